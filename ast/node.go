@@ -34,8 +34,13 @@ type Visitor interface {
 	VisitMustache(*MustacheStatement) interface{}
 	VisitBlock(*BlockStatement) interface{}
 	VisitPartial(*PartialStatement) interface{}
+	VisitPartialBlock(*PartialBlockStatement) interface{}
+	VisitParent(*ParentStatement) interface{}
+	VisitNamedBlock(*NamedBlockStatement) interface{}
 	VisitContent(*ContentStatement) interface{}
 	VisitComment(*CommentStatement) interface{}
+	VisitDecorator(*Decorator) interface{}
+	VisitDecoratorBlock(*DecoratorBlock) interface{}
 
 	// expressions
 	VisitExpression(*Expression) interface{}
@@ -73,12 +78,27 @@ const (
 	// NodePartial is the partial statement node
 	NodePartial
 
+	// NodePartialBlock is the partial block statement node
+	NodePartialBlock
+
+	// NodeParent is the mustache parent statement node
+	NodeParent
+
+	// NodeNamedBlock is the mustache block statement node
+	NodeNamedBlock
+
 	// NodeContent is the content statement node
 	NodeContent
 
 	// NodeComment is the comment statement node
 	NodeComment
 
+	// NodeDecorator is the decorator node
+	NodeDecorator
+
+	// NodeDecoratorBlock is the decorator block node
+	NodeDecoratorBlock
+
 	// NodeExpression is the expression node
 	NodeExpression
 
@@ -235,6 +255,14 @@ type BlockStatement struct {
 	Program *Program
 	Inverse *Program
 
+	// raw block, ie. {{{{raw-helper}}}}...{{{{/raw-helper}}}}
+	Raw bool
+
+	// verbatim source of program, and delimiters it was scanned with
+	Source         string
+	OpenDelimiter  string
+	CloseDelimiter string
+
 	// whitespace management
 	OpenStrip    *Strip
 	InverseStrip *Strip
@@ -272,6 +300,9 @@ type PartialStatement struct {
 	Params []Node // [ Expression ... ]
 	Hash   *Hash
 
+	// mustache dynamic name, ie. {{>*name}}: the partial name is the value of Name path
+	Dynamic bool
+
 	// whitespace management
 	Strip  *Strip
 	Indent string
@@ -295,6 +326,138 @@ func (node *PartialStatement) Accept(visitor Visitor) interface{} {
 	return visitor.VisitPartial(node)
 }
 
+//
+// Partial Block Statement
+//
+
+// PartialBlockStatement represents a partial block node, eg: {{#> layout}}...{{/layout}}
+type PartialBlockStatement struct {
+	NodeType
+	Loc
+
+	Name   Node   // PathExpression | SubExpression
+	Params []Node // [ Expression ... ]
+	Hash   *Hash
+
+	Program *Program
+
+	// whitespace management
+	OpenStrip  *Strip
+	CloseStrip *Strip
+}
+
+// NewPartialBlockStatement instanciates a new partial block node.
+func NewPartialBlockStatement(pos int, line int) *PartialBlockStatement {
+	return &PartialBlockStatement{
+		NodeType: NodePartialBlock,
+		Loc:      Loc{pos, line},
+	}
+}
+
+// String returns a string representation of receiver that can be used for debugging.
+func (node *PartialBlockStatement) String() string {
+	return fmt.Sprintf("PartialBlock{Name:%s, Pos:%d}", node.Name, node.Loc.Pos)
+}
+
+// Accept is the receiver entry point for visitors.
+func (node *PartialBlockStatement) Accept(visitor Visitor) interface{} {
+	return visitor.VisitPartialBlock(node)
+}
+
+//
+// Parent Statement
+//
+
+// ParentStatement represents a mustache parent node, eg: {{<layout}}{{$title}}...{{/title}}{{/layout}}
+type ParentStatement struct {
+	NodeType
+	Loc
+
+	Name Node // PathExpression
+
+	// mustache dynamic name, ie. {{<*name}}: the parent name is the value of Name path
+	Dynamic bool
+
+	// blocks overriding the ones of parent, other statements are ignored
+	Program *Program
+
+	// whitespace management
+	OpenStrip  *Strip
+	CloseStrip *Strip
+	Indent     string
+}
+
+// NewParentStatement instanciates a new parent node.
+func NewParentStatement(pos int, line int) *ParentStatement {
+	return &ParentStatement{
+		NodeType: NodeParent,
+		Loc:      Loc{pos, line},
+	}
+}
+
+// String returns a string representation of receiver that can be used for debugging.
+func (node *ParentStatement) String() string {
+	return fmt.Sprintf("Parent{Name:%s, Pos:%d}", node.Name, node.Loc.Pos)
+}
+
+// Accept is the receiver entry point for visitors.
+func (node *ParentStatement) Accept(visitor Visitor) interface{} {
+	return visitor.VisitParent(node)
+}
+
+// Blocks returns the blocks defined in parent statement.
+func (node *ParentStatement) Blocks() []*NamedBlockStatement {
+	var result []*NamedBlockStatement
+
+	for _, n := range node.Program.Body {
+		if block, ok := n.(*NamedBlockStatement); ok {
+			result = append(result, block)
+		}
+	}
+
+	return result
+}
+
+//
+// Named Block Statement
+//
+
+// NamedBlockStatement represents a mustache block node, eg: {{$title}}default title{{/title}}
+type NamedBlockStatement struct {
+	NodeType
+	Loc
+
+	Name string
+
+	// default content
+	Program *Program
+
+	// whitespace management
+	OpenStrip  *Strip
+	CloseStrip *Strip
+
+	// indentation of a standalone block content, replaced by the indentation of the overridden block
+	Indent string
+}
+
+// NewNamedBlockStatement instanciates a new named block node.
+func NewNamedBlockStatement(pos int, line int) *NamedBlockStatement {
+	return &NamedBlockStatement{
+		NodeType: NodeNamedBlock,
+		Loc:      Loc{pos, line},
+	}
+}
+
+// String returns a string representation of receiver that can be used for debugging.
+func (node *NamedBlockStatement) String() string {
+	return fmt.Sprintf("NamedBlock{Name:%s, Pos:%d}", node.Name, node.Loc.Pos)
+}
+
+// Accept is the receiver entry point for visitors.
+func (node *NamedBlockStatement) Accept(visitor Visitor) interface{} {
+	return visitor.VisitNamedBlock(node)
+}
+
 //
 // Content Statement
 //
@@ -368,6 +531,75 @@ func (node *CommentStatement) Accept(visitor Visitor) interface{} {
 	return visitor.VisitComment(node)
 }
 
+//
+// Decorator
+//
+
+// Decorator represents a decorator node, eg: {{* decorator arg}}
+type Decorator struct {
+	NodeType
+	Loc
+
+	Expression *Expression
+
+	// whitespace management
+	Strip *Strip
+}
+
+// NewDecorator instanciates a new decorator node.
+func NewDecorator(pos int, line int) *Decorator {
+	return &Decorator{
+		NodeType: NodeDecorator,
+		Loc:      Loc{pos, line},
+	}
+}
+
+// String returns a string representation of receiver that can be used for debugging.
+func (node *Decorator) String() string {
+	return fmt.Sprintf("Decorator{Pos: %d}", node.Loc.Pos)
+}
+
+// Accept is the receiver entry point for visitors.
+func (node *Decorator) Accept(visitor Visitor) interface{} {
+	return visitor.VisitDecorator(node)
+}
+
+//
+// Decorator Block
+//
+
+// DecoratorBlock represents a decorator block node, eg: {{#*inline "name"}}...{{/inline}}
+type DecoratorBlock struct {
+	NodeType
+	Loc
+
+	Expression *Expression
+
+	Program *Program
+
+	// whitespace management
+	OpenStrip  *Strip
+	CloseStrip *Strip
+}
+
+// NewDecoratorBlock instanciates a new decorator block node.
+func NewDecoratorBlock(pos int, line int) *DecoratorBlock {
+	return &DecoratorBlock{
+		NodeType: NodeDecoratorBlock,
+		Loc:      Loc{pos, line},
+	}
+}
+
+// String returns a string representation of receiver that can be used for debugging.
+func (node *DecoratorBlock) String() string {
+	return fmt.Sprintf("DecoratorBlock{Pos: %d}", node.Loc.Pos)
+}
+
+// Accept is the receiver entry point for visitors.
+func (node *DecoratorBlock) Accept(visitor Visitor) interface{} {
+	return visitor.VisitDecoratorBlock(node)
+}
+
 //
 // Expression
 //
@@ -118,11 +118,77 @@ func (v *printVisitor) VisitBlock(node *BlockStatement) interface{} {
 	return nil
 }
 
+// VisitDecorator implements corresponding Visitor interface method
+func (v *printVisitor) VisitDecorator(node *Decorator) interface{} {
+	v.indent()
+	v.str("{{ DIRECTIVE ")
+
+	node.Expression.Accept(v)
+
+	v.str(" }}")
+	v.nl()
+
+	return nil
+}
+
+// VisitDecoratorBlock implements corresponding Visitor interface method
+func (v *printVisitor) VisitDecoratorBlock(node *DecoratorBlock) interface{} {
+	v.inBlock = true
+
+	v.line("DIRECTIVE BLOCK:")
+	v.depth++
+
+	node.Expression.Accept(v)
+
+	v.line("PROGRAM:")
+	v.depth++
+	node.Program.Accept(v)
+	v.depth--
+
+	v.depth--
+
+	v.inBlock = false
+
+	return nil
+}
+
 // VisitPartial implements corresponding Visitor interface method
 func (v *printVisitor) VisitPartial(node *PartialStatement) interface{} {
 	v.indent()
 	v.str("{{> PARTIAL:")
 
+	if node.Dynamic {
+		v.str("*")
+	}
+
+	v.original = true
+	node.Name.Accept(v)
+	v.original = false
+
+	if len(node.Params) > 0 {
+		v.str(" ")
+		node.Params[0].Accept(v)
+	}
+
+	// hash
+	if node.Hash != nil {
+		v.str(" ")
+		node.Hash.Accept(v)
+	}
+
+	v.str(" }}")
+	v.nl()
+
+	return nil
+}
+
+// VisitPartialBlock implements corresponding Visitor interface method
+func (v *printVisitor) VisitPartialBlock(node *PartialBlockStatement) interface{} {
+	v.inBlock = true
+
+	v.indent()
+	v.str("{{> PARTIAL BLOCK:")
+
 	v.original = true
 	node.Name.Accept(v)
 	v.original = false
@@ -141,6 +207,63 @@ func (v *printVisitor) VisitPartial(node *PartialStatement) interface{} {
 	v.str(" }}")
 	v.nl()
 
+	v.depth++
+	v.line("PROGRAM:")
+	v.depth++
+	node.Program.Accept(v)
+	v.depth--
+	v.depth--
+
+	v.inBlock = false
+
+	return nil
+}
+
+// VisitParent implements corresponding Visitor interface method
+func (v *printVisitor) VisitParent(node *ParentStatement) interface{} {
+	v.inBlock = true
+
+	v.indent()
+	v.str("{{< PARENT:")
+
+	if node.Dynamic {
+		v.str("*")
+	}
+
+	v.original = true
+	node.Name.Accept(v)
+	v.original = false
+
+	v.str(" }}")
+	v.nl()
+
+	v.depth++
+	v.line("PROGRAM:")
+	v.depth++
+	node.Program.Accept(v)
+	v.depth--
+	v.depth--
+
+	v.inBlock = false
+
+	return nil
+}
+
+// VisitNamedBlock implements corresponding Visitor interface method
+func (v *printVisitor) VisitNamedBlock(node *NamedBlockStatement) interface{} {
+	v.inBlock = true
+
+	v.line("{{$ BLOCK:" + node.Name + " }}")
+
+	v.depth++
+	v.line("PROGRAM:")
+	v.depth++
+	node.Program.Accept(v)
+	v.depth--
+	v.depth--
+
+	v.inBlock = false
+
 	return nil
 }
 
@@ -0,0 +1,1250 @@
+package raymond
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+//
+// Contextual escaping, inspired by https://golang.org/pkg/html/template/
+//
+// The HTML state is tracked across content statements at parse time, so that the value of each mustache is escaped
+// according to the context it appears in: HTML text, HTML attribute, URL, JavaScript or CSS.
+//
+
+// filteredValue replaces a value that is unsafe in its context
+const filteredValue = "ZgotmplZ"
+
+// htmlState represents the HTML parser state
+type htmlState uint8
+
+const (
+	// stateText is outside tags, or in the content of an element
+	stateText htmlState = iota
+
+	// stateTag is inside a tag, before an attribute name or the end of tag
+	stateTag
+
+	// stateAttrName is in an attribute name
+	stateAttrName
+
+	// stateAfterName is after an attribute name, before the equal sign
+	stateAfterName
+
+	// stateBeforeValue is after the equal sign, before an attribute value
+	stateBeforeValue
+
+	// stateAttr is in an attribute value
+	stateAttr
+
+	// stateComment is in an HTML comment
+	stateComment
+)
+
+// htmlElement represents an element with a special content
+type htmlElement uint8
+
+const (
+	elementNone htmlElement = iota
+	elementScript
+	elementStyle
+	elementTextarea
+	elementTitle
+)
+
+// elementNames maps an element with special content to its tag name
+var elementNames = map[htmlElement]string{
+	elementScript:   "script",
+	elementStyle:    "style",
+	elementTextarea: "textarea",
+	elementTitle:    "title",
+}
+
+// attrType represents the content type of an attribute value
+type attrType uint8
+
+const (
+	attrNone attrType = iota
+	attrURL
+	attrJS
+	attrCSS
+)
+
+// urlAttrs lists attributes that contain an URL
+var urlAttrs = map[string]bool{
+	"action":     true,
+	"archive":    true,
+	"background": true,
+	"cite":       true,
+	"classid":    true,
+	"codebase":   true,
+	"data":       true,
+	"formaction": true,
+	"href":       true,
+	"icon":       true,
+	"longdesc":   true,
+	"manifest":   true,
+	"poster":     true,
+	"profile":    true,
+	"src":        true,
+	"usemap":     true,
+	"xmlns":      true,
+}
+
+// attrDelim represents the delimiter of an attribute value
+type attrDelim uint8
+
+const (
+	delimNone attrDelim = iota
+	delimDoubleQuote
+	delimSingleQuote
+)
+
+// jsState represents the JavaScript parser state
+type jsState uint8
+
+const (
+	jsExpr jsState = iota
+	jsDoubleQuoteStr
+	jsSingleQuoteStr
+	jsTemplateStr
+	jsLineComment
+	jsBlockComment
+	jsRegexp
+	jsRegexpClass
+)
+
+// jsCtx tells how a slash is interpreted in a JavaScript expression
+type jsCtx uint8
+
+const (
+	// jsCtxRegexp is where a slash starts a regular expression literal
+	jsCtxRegexp jsCtx = iota
+
+	// jsCtxDivOp is where a slash is a division operator
+	jsCtxDivOp
+)
+
+// regexpPrecederKeywords lists keywords that precede a regular expression literal rather than a division operator
+var regexpPrecederKeywords = map[string]bool{
+	"break":      true,
+	"case":       true,
+	"continue":   true,
+	"delete":     true,
+	"do":         true,
+	"else":       true,
+	"finally":    true,
+	"in":         true,
+	"instanceof": true,
+	"return":     true,
+	"throw":      true,
+	"try":        true,
+	"typeof":     true,
+	"void":       true,
+}
+
+// cssState represents the CSS parser state
+type cssState uint8
+
+const (
+	cssValue cssState = iota
+	cssDoubleQuoteStr
+	cssSingleQuoteStr
+	cssComment
+)
+
+// urlPart represents the part of an URL
+type urlPart uint8
+
+const (
+	urlStart urlPart = iota
+	urlPath
+	urlQuery
+)
+
+// escapeContext represents the context of a mustache, used to escape its value
+type escapeContext struct {
+	state   htmlState
+	element htmlElement
+	attr    attrType
+	delim   attrDelim
+	js      jsState
+	jsCtx   jsCtx
+	css     cssState
+	url     urlPart
+}
+
+// String returns a string representation of receiver that can be used for debugging.
+func (c escapeContext) String() string {
+	return fmt.Sprintf("{state:%d element:%d attr:%d delim:%d js:%d jsCtx:%d css:%d url:%d}", c.state, c.element, c.attr, c.delim, c.js, c.jsCtx, c.css, c.url)
+}
+
+//
+// Context transitions
+//
+
+// transition returns the context after given content
+func (c escapeContext) transition(s string) escapeContext {
+	for s != "" {
+		c, s = c.step(s)
+	}
+
+	return c
+}
+
+// step consumes the beginning of given content, and returns the resulting context and the remaining content
+func (c escapeContext) step(s string) (escapeContext, string) {
+	switch c.state {
+	case stateText:
+		if c.element == elementNone {
+			return c.stepText(s)
+		}
+
+		return c.stepElementContent(s)
+	case stateTag:
+		return c.stepTag(s)
+	case stateAttrName:
+		return c.stepAttrName(s)
+	case stateAfterName:
+		return c.stepAfterName(s)
+	case stateBeforeValue:
+		return c.stepBeforeValue(s)
+	case stateAttr:
+		return c.stepAttr(s)
+	case stateComment:
+		return c.stepComment(s)
+	}
+
+	return c, ""
+}
+
+// stepText handles content outside tags
+func (c escapeContext) stepText(s string) (escapeContext, string) {
+	i := strings.IndexByte(s, '<')
+	if i == -1 {
+		return c, ""
+	}
+
+	s = s[i:]
+
+	if strings.HasPrefix(s, "<!--") {
+		return escapeContext{state: stateComment}, s[4:]
+	}
+
+	if strings.HasPrefix(s, "</") {
+		if name, rest := tagName(s[2:]); name != "" {
+			return escapeContext{state: stateTag}, rest
+		}
+	} else if name, rest := tagName(s[1:]); name != "" {
+		return escapeContext{state: stateTag, element: elementOf(name)}, rest
+	}
+
+	// not a tag
+	return c, s[1:]
+}
+
+// stepElementContent handles the content of an element with special content, until its end tag
+func (c escapeContext) stepElementContent(s string) (escapeContext, string) {
+	content, rest := s, ""
+
+	i := indexEndTag(s, elementNames[c.element])
+	if i != -1 {
+		content, rest = s[:i], s[i:]
+	}
+
+	switch c.element {
+	case elementScript:
+		c.js, c.jsCtx = jsTransition(c.js, c.jsCtx, content)
+	case elementStyle:
+		c.css = cssTransition(c.css, content)
+	}
+
+	if i == -1 {
+		return c, ""
+	}
+
+	// end tag
+	return escapeContext{state: stateText}, rest
+}
+
+// stepTag handles content inside a tag
+func (c escapeContext) stepTag(s string) (escapeContext, string) {
+	s = strings.TrimLeft(s, htmlSpaces)
+	if s == "" {
+		return c, ""
+	}
+
+	switch s[0] {
+	case '>':
+		return escapeContext{state: stateText, element: c.element}, s[1:]
+	case '/':
+		return c, s[1:]
+	}
+
+	// attribute name
+	i := strings.IndexAny(s, htmlSpaces+"=>/")
+	if i == -1 {
+		i = len(s)
+	}
+
+	result := escapeContext{state: stateAttrName, element: c.element, attr: attrTypeOf(s[:i])}
+	if i < len(s) {
+		result.state = stateAfterName
+	}
+
+	return result, s[i:]
+}
+
+// stepAttrName handles the end of an attribute name
+func (c escapeContext) stepAttrName(s string) (escapeContext, string) {
+	i := strings.IndexAny(s, htmlSpaces+"=>/")
+	if i == -1 {
+		return c, ""
+	}
+
+	c.state = stateAfterName
+
+	return c, s[i:]
+}
+
+// stepAfterName handles content after an attribute name
+func (c escapeContext) stepAfterName(s string) (escapeContext, string) {
+	s = strings.TrimLeft(s, htmlSpaces)
+	if s == "" {
+		return c, ""
+	}
+
+	if s[0] == '=' {
+		c.state = stateBeforeValue
+		return c, s[1:]
+	}
+
+	// attribute without value
+	return escapeContext{state: stateTag, element: c.element}, s
+}
+
+// stepBeforeValue handles content after the equal sign of an attribute
+func (c escapeContext) stepBeforeValue(s string) (escapeContext, string) {
+	s = strings.TrimLeft(s, htmlSpaces)
+	if s == "" {
+		return c, ""
+	}
+
+	c.state = stateAttr
+
+	switch s[0] {
+	case '"':
+		c.delim = delimDoubleQuote
+		return c, s[1:]
+	case '\'':
+		c.delim = delimSingleQuote
+		return c, s[1:]
+	case '>':
+		return escapeContext{state: stateTag, element: c.element}, s
+	}
+
+	// unquoted value
+	c.delim = delimNone
+
+	return c, s
+}
+
+// stepAttr handles content of an attribute value
+func (c escapeContext) stepAttr(s string) (escapeContext, string) {
+	var i int
+
+	switch c.delim {
+	case delimDoubleQuote:
+		i = strings.IndexByte(s, '"')
+	case delimSingleQuote:
+		i = strings.IndexByte(s, '\'')
+	default:
+		i = strings.IndexAny(s, htmlSpaces+">")
+	}
+
+	value := s
+	if i != -1 {
+		value = s[:i]
+	}
+
+	switch c.attr {
+	case attrURL:
+		c.url = urlTransition(c.url, value)
+	case attrJS:
+		c.js, c.jsCtx = jsTransition(c.js, c.jsCtx, value)
+	case attrCSS:
+		c.css = cssTransition(c.css, value)
+	}
+
+	if i == -1 {
+		return c, ""
+	}
+
+	if c.delim == delimNone {
+		// the delimiter is part of tag
+		return escapeContext{state: stateTag, element: c.element}, s[i:]
+	}
+
+	return escapeContext{state: stateTag, element: c.element}, s[i+1:]
+}
+
+// stepComment handles content of an HTML comment
+func (c escapeContext) stepComment(s string) (escapeContext, string) {
+	i := strings.Index(s, "-->")
+	if i == -1 {
+		return c, ""
+	}
+
+	return escapeContext{state: stateText}, s[i+3:]
+}
+
+// mustache returns the context of a mustache appearing in receiver context
+func (c escapeContext) mustache() escapeContext {
+	if c.state == stateBeforeValue {
+		// mustache starts an unquoted attribute value
+		c.state = stateAttr
+		c.delim = delimNone
+	}
+
+	return c
+}
+
+// afterMustache returns the context after a mustache appearing in receiver context
+func (c escapeContext) afterMustache() escapeContext {
+	c = c.afterPartial()
+
+	if (c.state == stateAttr) && (c.attr == attrURL) && (c.url == urlStart) {
+		c.url = urlPath
+	}
+
+	return c
+}
+
+// afterPartial returns the context after a partial called in receiver context
+//
+// The output of a partial is not known when the calling template is parsed, so the start of an URL is kept and values
+// following the partial are still filtered. In a JavaScript expression, the partial is expected to output a value.
+func (c escapeContext) afterPartial() escapeContext {
+	c = c.mustache()
+
+	if c.isJS() && (c.js == jsExpr) {
+		c.jsCtx = jsCtxDivOp
+	}
+
+	return c
+}
+
+// isJS returns true if receiver context is in JavaScript content
+func (c escapeContext) isJS() bool {
+	return ((c.state == stateText) && (c.element == elementScript)) || ((c.state == stateAttr) && (c.attr == attrJS))
+}
+
+// acceptsPartialEnd returns true if a partial called in receiver context can end in given context
+func (c escapeContext) acceptsPartialEnd(end escapeContext) bool {
+	if (c.url == urlStart) && (end.url == urlPath) {
+		end.url = urlStart
+	}
+
+	return end == c.afterPartial()
+}
+
+// htmlSpaces lists HTML whitespace characters
+const htmlSpaces = " \t\n\f\r"
+
+// tagName returns the lowercased tag name at the beginning of given string, and the remaining string
+func tagName(s string) (string, string) {
+	i := 0
+	for i < len(s) {
+		ch := s[i]
+		if ((ch >= 'a') && (ch <= 'z')) || ((ch >= 'A') && (ch <= 'Z')) || ((i > 0) && (((ch >= '0') && (ch <= '9')) || (ch == '-') || (ch == ':'))) {
+			i++
+		} else {
+			break
+		}
+	}
+
+	return strings.ToLower(s[:i]), s[i:]
+}
+
+// elementOf returns the element with given tag name
+func elementOf(name string) htmlElement {
+	for element, elementName := range elementNames {
+		if name == elementName {
+			return element
+		}
+	}
+
+	return elementNone
+}
+
+// indexEndTag returns the index of the end tag with given name in s, or -1 if not found
+func indexEndTag(s string, name string) int {
+	lower := strings.ToLower(s)
+	tag := "</" + name
+
+	offset := 0
+	for {
+		i := strings.Index(lower[offset:], tag)
+		if i == -1 {
+			return -1
+		}
+
+		i += offset
+		end := i + len(tag)
+
+		if (end == len(s)) || strings.IndexByte(htmlSpaces+"/>", s[end]) != -1 {
+			return i
+		}
+
+		offset = end
+	}
+}
+
+// attrTypeOf returns the content type of attribute with given name
+func attrTypeOf(name string) attrType {
+	name = strings.ToLower(name)
+
+	if strings.HasPrefix(name, "data-") {
+		name = name[len("data-"):]
+	} else if i := strings.IndexByte(name, ':'); i != -1 {
+		// namespaced attribute
+		name = name[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(name, "on"):
+		return attrJS
+	case name == "style":
+		return attrCSS
+	case name == "srcdoc":
+		// holds an HTML document
+		return attrNone
+	case urlAttrs[name] || strings.Contains(name, "src") || strings.Contains(name, "uri") || strings.Contains(name, "url"):
+		return attrURL
+	}
+
+	return attrNone
+}
+
+// urlTransition returns the URL part after given URL content
+func urlTransition(part urlPart, s string) urlPart {
+	if part == urlStart {
+		// browsers ignore leading whitespace before the scheme
+		s = strings.TrimLeft(s, htmlSpaces)
+	}
+
+	switch {
+	case s == "":
+		return part
+	case (part == urlQuery) || strings.ContainsAny(s, "?#"):
+		return urlQuery
+	}
+
+	return urlPath
+}
+
+// jsTransition returns the JavaScript state after given JavaScript content
+func jsTransition(state jsState, ctx jsCtx, s string) (jsState, jsCtx) {
+	for s != "" {
+		switch state {
+		case jsExpr:
+			i := strings.IndexAny(s, "\"'`/")
+			if i == -1 {
+				return state, nextJSCtx(s, ctx)
+			}
+
+			ctx = nextJSCtx(s[:i], ctx)
+
+			switch s[i] {
+			case '"':
+				state = jsDoubleQuoteStr
+			case '\'':
+				state = jsSingleQuoteStr
+			case '`':
+				state = jsTemplateStr
+			case '/':
+				switch {
+				case strings.HasPrefix(s[i:], "//"):
+					state = jsLineComment
+					i++
+				case strings.HasPrefix(s[i:], "/*"):
+					state = jsBlockComment
+					i++
+				case ctx == jsCtxRegexp:
+					state = jsRegexp
+				default:
+					// division operator
+					ctx = jsCtxRegexp
+				}
+			}
+
+			s = s[i+1:]
+		case jsDoubleQuoteStr, jsSingleQuoteStr, jsTemplateStr:
+			s = skipString(s, jsQuotes[state])
+			if s != "" {
+				// end of string
+				state, ctx = jsExpr, jsCtxDivOp
+				s = s[1:]
+			}
+		case jsRegexp, jsRegexpClass:
+			state, s = skipRegexp(state, s)
+			if s != "" {
+				// end of regular expression
+				state, ctx = jsExpr, jsCtxDivOp
+				s = s[1:]
+			}
+		case jsLineComment:
+			i := strings.IndexAny(s, "\n\r")
+			if i == -1 {
+				return state, ctx
+			}
+
+			state = jsExpr
+			s = s[i+1:]
+		case jsBlockComment:
+			i := strings.Index(s, "*/")
+			if i == -1 {
+				return state, ctx
+			}
+
+			state = jsExpr
+			s = s[i+2:]
+		}
+	}
+
+	return state, ctx
+}
+
+// skipRegexp skips the content of a regular expression literal, and returns the resulting state and the content
+// starting with the closing slash, or an empty string if the regular expression does not end
+func skipRegexp(state jsState, s string) (jsState, string) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			state = jsRegexpClass
+		case ']':
+			state = jsRegexp
+		case '/':
+			if state == jsRegexp {
+				return state, s[i:]
+			}
+		}
+	}
+
+	return state, ""
+}
+
+// nextJSCtx returns the context of a slash following given JavaScript expression content
+//
+// This is the heuristic used by the html/template package.
+func nextJSCtx(s string, ctx jsCtx) jsCtx {
+	s = strings.TrimRight(s, htmlSpaces+"\u2028\u2029")
+	if s == "" {
+		return ctx
+	}
+
+	n := len(s)
+
+	switch c := s[n-1]; c {
+	case '+', '-':
+		// an odd number of signs is an operator, otherwise it is an increment or a decrement
+		start := n - 1
+		for (start > 0) && (s[start-1] == c) {
+			start--
+		}
+
+		if (n-start)%2 == 1 {
+			return jsCtxRegexp
+		}
+
+		return jsCtxDivOp
+	case '.':
+		// a decimal point
+		if (n > 1) && ('0' <= s[n-2]) && (s[n-2] <= '9') {
+			return jsCtxDivOp
+		}
+
+		return jsCtxRegexp
+	case ',', '<', '>', '=', '*', '%', '&', '|', '^', '?', '!', '~', '(', '[', ':', ';', '{', '}':
+		return jsCtxRegexp
+	}
+
+	i := n
+	for (i > 0) && isJSIdentChar(s[i-1]) {
+		i--
+	}
+
+	if regexpPrecederKeywords[s[i:]] {
+		return jsCtxRegexp
+	}
+
+	return jsCtxDivOp
+}
+
+// isJSIdentChar returns true if given character can be part of a JavaScript identifier
+func isJSIdentChar(ch byte) bool {
+	return (ch == '$') || (ch == '_') || ('0' <= ch && ch <= '9') || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
+}
+
+// jsQuotes maps a JavaScript string state to its quote character
+var jsQuotes = map[jsState]byte{
+	jsDoubleQuoteStr: '"',
+	jsSingleQuoteStr: '\'',
+	jsTemplateStr:    '`',
+}
+
+// cssTransition returns the CSS state after given CSS content
+func cssTransition(state cssState, s string) cssState {
+	for s != "" {
+		switch state {
+		case cssValue:
+			i := strings.IndexAny(s, "\"'/")
+			if i == -1 {
+				return state
+			}
+
+			switch s[i] {
+			case '"':
+				state = cssDoubleQuoteStr
+			case '\'':
+				state = cssSingleQuoteStr
+			case '/':
+				if strings.HasPrefix(s[i:], "/*") {
+					state = cssComment
+					i++
+				}
+			}
+
+			s = s[i+1:]
+		case cssDoubleQuoteStr, cssSingleQuoteStr:
+			quote := byte('"')
+			if state == cssSingleQuoteStr {
+				quote = '\''
+			}
+
+			s = skipString(s, quote)
+			if s != "" {
+				// end of string
+				state = cssValue
+				s = s[1:]
+			}
+		case cssComment:
+			i := strings.Index(s, "*/")
+			if i == -1 {
+				return state
+			}
+
+			state = cssValue
+			s = s[i+2:]
+		}
+	}
+
+	return state
+}
+
+// skipString skips string content until given quote character, taking backslash escapes into account, and returns
+// remaining content starting with that quote, or an empty string if not found
+func skipString(s string, quote byte) string {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return s[i:]
+		}
+	}
+
+	return ""
+}
+
+//
+// Escaping
+//
+
+// escape returns given mustache value escaped for receiver context
+func (c escapeContext) escape(value interface{}) string {
+	str := Str(value)
+
+	switch c.state {
+	case stateText:
+		switch c.element {
+		case elementScript:
+			return escapeJS(c.js, value, str)
+		case elementStyle:
+			return escapeCSS(c.css, str)
+		}
+	case stateTag, stateAttrName, stateAfterName:
+		return filterAttrName(str)
+	case stateAttr:
+		var result string
+
+		switch c.attr {
+		case attrURL:
+			result = escapeURL(c.url, str)
+		case attrJS:
+			result = escapeJS(c.js, value, str)
+		case attrCSS:
+			result = escapeCSS(c.css, str)
+		default:
+			result = str
+		}
+
+		if c.delim == delimNone {
+			return escapeUnquotedAttr(result)
+		}
+
+		return Escape(result)
+	}
+
+	return Escape(str)
+}
+
+// filterAttrName returns given string if it is a safe attribute name, or a filtered value otherwise
+func filterAttrName(s string) string {
+	if s == "" {
+		return s
+	}
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if !isAlphaNum(ch) && (ch != '-') && (ch != '_') {
+			return filteredValue
+		}
+	}
+
+	if attrTypeOf(s) != attrNone {
+		return filteredValue
+	}
+
+	return s
+}
+
+// escapeUnquotedAttr escapes given string for an unquoted attribute value
+func escapeUnquotedAttr(s string) string {
+	var buf bytes.Buffer
+
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '\'':
+			buf.WriteString("&apos;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '"':
+			buf.WriteString("&quot;")
+		case ' ', '\t', '\n', '\f', '\r', '=', '`':
+			fmt.Fprintf(&buf, "&#%d;", r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+
+	return buf.String()
+}
+
+// escapeURL escapes given string for given URL part
+func escapeURL(part urlPart, s string) string {
+	switch part {
+	case urlStart:
+		if !isSafeURL(s) {
+			return "#" + filteredValue
+		}
+
+		return normalizeURL(s)
+	case urlPath:
+		return normalizeURL(s)
+	}
+
+	return queryEscape(s)
+}
+
+// isSafeURL returns true if given URL has no scheme, or a safe one
+func isSafeURL(s string) bool {
+	s = strings.TrimLeft(s, htmlSpaces)
+
+	i := strings.IndexAny(s, ":/?#")
+	if (i == -1) || (s[i] != ':') {
+		// no scheme
+		return true
+	}
+
+	switch strings.ToLower(s[:i]) {
+	case "http", "https", "mailto":
+		return true
+	}
+
+	return false
+}
+
+// normalizeURL percent encodes characters that are not allowed in an URL
+func normalizeURL(s string) string {
+	var buf bytes.Buffer
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if isUnreservedURLChar(ch) || (strings.IndexByte("!#$%&*+,/:;=?@[]", ch) != -1) {
+			buf.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", ch)
+		}
+	}
+
+	return buf.String()
+}
+
+// queryEscape percent encodes all characters that are not unreserved
+func queryEscape(s string) string {
+	var buf bytes.Buffer
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if isUnreservedURLChar(ch) {
+			buf.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", ch)
+		}
+	}
+
+	return buf.String()
+}
+
+// isUnreservedURLChar returns true if given character is an URL unreserved character
+func isUnreservedURLChar(ch byte) bool {
+	return isAlphaNum(ch) || (strings.IndexByte("-._~", ch) != -1)
+}
+
+// isAlphaNum returns true if given character is an ASCII letter or digit
+func isAlphaNum(ch byte) bool {
+	return ((ch >= 'a') && (ch <= 'z')) || ((ch >= 'A') && (ch <= 'Z')) || ((ch >= '0') && (ch <= '9'))
+}
+
+// escapeJS escapes given value for given JavaScript state
+func escapeJS(state jsState, value interface{}, str string) string {
+	switch state {
+	case jsExpr:
+		return jsValue(value)
+	case jsDoubleQuoteStr, jsSingleQuoteStr, jsTemplateStr:
+		return jsStringEscape(str)
+	case jsRegexp, jsRegexpClass:
+		return jsRegexpEscape(str)
+	}
+
+	// elided in comments
+	return ""
+}
+
+// jsValue returns given value as a JavaScript value
+func jsValue(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "null"
+	}
+
+	// json package already escapes <, >, & and line terminators
+	return string(b)
+}
+
+// jsRegexpEscape escapes given string for a JavaScript regular expression literal
+func jsRegexpEscape(s string) string {
+	if s == "" {
+		// an empty regular expression literal would be a comment
+		return "(?:)"
+	}
+
+	var buf bytes.Buffer
+
+	for _, r := range s {
+		if strings.ContainsRune(`.*+?^$|()[]{}-`, r) {
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		} else {
+			buf.WriteString(jsStringEscape(string(r)))
+		}
+	}
+
+	return buf.String()
+}
+
+// jsStringEscape escapes given string for a JavaScript string
+func jsStringEscape(s string) string {
+	var buf bytes.Buffer
+
+	for _, r := range s {
+		switch r {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '"', '\'', '`', '<', '>', '&', '=', '$', '/', '\u2028', '\u2029':
+			fmt.Fprintf(&buf, `\u%04x`, r)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// escapeCSS escapes given string for given CSS state
+func escapeCSS(state cssState, s string) string {
+	switch state {
+	case cssValue:
+		return filterCSSValue(s)
+	case cssDoubleQuoteStr, cssSingleQuoteStr:
+		return cssStringEscape(s)
+	}
+
+	// elided in comments
+	return ""
+}
+
+// filterCSSValue returns given string if it is a safe CSS value, or a filtered value otherwise
+func filterCSSValue(s string) string {
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if !isAlphaNum(ch) && (strings.IndexByte(" #%.,-_!", ch) == -1) {
+			return filteredValue
+		}
+	}
+
+	return s
+}
+
+// cssStringEscape escapes given string for a CSS string
+func cssStringEscape(s string) string {
+	var buf bytes.Buffer
+
+	for i, r := range s {
+		if (r < utf8.RuneSelf) && !isAlphaNum(byte(r)) && (strings.IndexRune(" #%.,-_", r) == -1) {
+			fmt.Fprintf(&buf, `\%x`, r)
+
+			// a space terminates the hexadecimal escape sequence if it could be followed by an hexadecimal digit
+			if next := i + 1; (next < len(s)) && (isAlphaNum(s[next]) || (s[next] == ' ')) {
+				buf.WriteByte(' ')
+			}
+		} else {
+			buf.WriteRune(r)
+		}
+	}
+
+	return buf.String()
+}
+
+//
+// Parse time analysis
+//
+
+// nonRepeatingHelpers are the builtin block helpers that render their content at most once
+var nonRepeatingHelpers = map[string]bool{
+	"if":     true,
+	"unless": true,
+	"with":   true,
+}
+
+// escapeContextVisitor walks through the AST to compute the escaping context of mustaches
+//
+// The context of partial statements, and the start context of programs rendered as partials, are also recorded, so that
+// partials are analyzed at evaluation time in the context they are called in.
+type escapeContextVisitor struct {
+	ctx      escapeContext
+	contexts map[ast.Node]escapeContext
+}
+
+// computeEscapeContexts returns the escaping context of all mustaches in given program
+func computeEscapeContexts(program *ast.Program) (map[ast.Node]escapeContext, error) {
+	result, _, err := analyzeEscapeContexts(program, escapeContext{})
+
+	return result, err
+}
+
+// computePartialEscapeContexts returns the escaping context of all mustaches in given partial program, called in
+// given context
+//
+// It returns an error if partial does not end in the context expected after the partial call, as the content following
+// that call was analyzed in that context.
+func computePartialEscapeContexts(program *ast.Program, start escapeContext) (map[ast.Node]escapeContext, error) {
+	result, end, err := analyzeEscapeContexts(program, start)
+	if (err == nil) && !start.acceptsPartialEnd(end) {
+		err = fmt.Errorf("Contextual escaping error: partial must end in the context it is called in, called in %s and ended in %s", start, end)
+	}
+
+	return result, err
+}
+
+// analyzeEscapeContexts returns the escaping context of all nodes in given program starting in given context, and the
+// context at the end of program
+func analyzeEscapeContexts(program *ast.Program, start escapeContext) (result map[ast.Node]escapeContext, end escapeContext, err error) {
+	defer errRecover(&err)
+
+	v := &escapeContextVisitor{
+		ctx:      start,
+		contexts: make(map[ast.Node]escapeContext),
+	}
+
+	program.Accept(v)
+
+	return v.contexts, v.ctx, nil
+}
+
+// errorf panics with a custom message
+func (v *escapeContextVisitor) errorf(node ast.Node, format string, args ...interface{}) {
+	panic(fmt.Errorf("Contextual escaping error: %s\nNode:\n\t%s", fmt.Sprintf(format, args...), node))
+}
+
+// acceptBranch visits given program from given context, and returns the resulting context
+func (v *escapeContextVisitor) acceptBranch(program *ast.Program, ctx escapeContext) escapeContext {
+	v.ctx = ctx
+
+	if program != nil {
+		program.Accept(v)
+	}
+
+	return v.ctx
+}
+
+// acceptPartial visits given program, rendered as a partial called by given node, and sets the context after that call
+func (v *escapeContextVisitor) acceptPartial(node ast.Node, program *ast.Program) {
+	start := v.ctx
+
+	// the partial is analyzed when called
+	v.contexts[node] = start
+
+	if program != nil {
+		v.contexts[program] = start
+
+		if !start.acceptsPartialEnd(v.acceptBranch(program, start)) {
+			v.errorf(node, "partial content must end in the context it is called in")
+		}
+	}
+
+	v.ctx = start.afterPartial()
+}
+
+//
+// Visitor interface
+//
+
+// VisitProgram implements corresponding Visitor interface method
+func (v *escapeContextVisitor) VisitProgram(node *ast.Program) interface{} {
+	for _, n := range node.Body {
+		n.Accept(v)
+	}
+
+	return nil
+}
+
+// VisitMustache implements corresponding Visitor interface method
+func (v *escapeContextVisitor) VisitMustache(node *ast.MustacheStatement) interface{} {
+	v.contexts[node] = v.ctx.mustache()
+	v.ctx = v.ctx.afterMustache()
+
+	return nil
+}
+
+// VisitBlock implements corresponding Visitor interface method
+func (v *escapeContextVisitor) VisitBlock(node *ast.BlockStatement) interface{} {
+	start := v.ctx
+
+	programEnd := v.acceptBranch(node.Program, start)
+	inverseEnd := v.acceptBranch(node.Inverse, start)
+
+	if programEnd != inverseEnd {
+		v.errorf(node, "block branches end in different contexts")
+	}
+
+	if !nonRepeatingHelpers[node.Expression.HelperName()] && (programEnd != start) {
+		// the next iteration would be rendered in another context than the analyzed one
+		v.errorf(node, "block content may be repeated, so it must end in the context it starts in")
+	}
+
+	return nil
+}
+
+// VisitPartialBlock implements corresponding Visitor interface method
+func (v *escapeContextVisitor) VisitPartialBlock(node *ast.PartialBlockStatement) interface{} {
+	v.acceptPartial(node, node.Program)
+
+	return nil
+}
+
+// VisitParent implements corresponding Visitor interface method
+func (v *escapeContextVisitor) VisitParent(node *ast.ParentStatement) interface{} {
+	// blocks of a parent are rendered in parent template, that is analyzed when called
+	ctx := v.ctx
+
+	v.acceptBranch(node.Program, escapeContext{})
+
+	v.ctx = ctx
+
+	v.acceptPartial(node, nil)
+
+	return nil
+}
+
+// VisitNamedBlock implements corresponding Visitor interface method
+func (v *escapeContextVisitor) VisitNamedBlock(node *ast.NamedBlockStatement) interface{} {
+	// a block overridden by a parent is rendered there, and a parent block is rendered here instead
+	v.acceptPartial(node, node.Program)
+
+	return nil
+}
+
+// VisitDecoratorBlock implements corresponding Visitor interface method
+func (v *escapeContextVisitor) VisitDecoratorBlock(node *ast.DecoratorBlock) interface{} {
+	// an inline partial is a separate template, analyzed when called
+	ctx := v.ctx
+
+	v.acceptBranch(node.Program, escapeContext{})
+
+	v.ctx = ctx
+
+	return nil
+}
+
+// VisitContent implements corresponding Visitor interface method
+func (v *escapeContextVisitor) VisitContent(node *ast.ContentStatement) interface{} {
+	v.ctx = v.ctx.transition(node.Value)
+
+	return nil
+}
+
+// VisitPartial implements corresponding Visitor interface method
+func (v *escapeContextVisitor) VisitPartial(node *ast.PartialStatement) interface{} {
+	v.acceptPartial(node, nil)
+
+	return nil
+}
+
+// NOOP
+func (v *escapeContextVisitor) VisitComment(node *ast.CommentStatement) interface{}    { return nil }
+func (v *escapeContextVisitor) VisitDecorator(node *ast.Decorator) interface{}         { return nil }
+func (v *escapeContextVisitor) VisitExpression(node *ast.Expression) interface{}       { return nil }
+func (v *escapeContextVisitor) VisitSubExpression(node *ast.SubExpression) interface{} { return nil }
+func (v *escapeContextVisitor) VisitPath(node *ast.PathExpression) interface{}         { return nil }
+func (v *escapeContextVisitor) VisitString(node *ast.StringLiteral) interface{}        { return nil }
+func (v *escapeContextVisitor) VisitBoolean(node *ast.BooleanLiteral) interface{}      { return nil }
+func (v *escapeContextVisitor) VisitNumber(node *ast.NumberLiteral) interface{}        { return nil }
+func (v *escapeContextVisitor) VisitHash(node *ast.Hash) interface{}                   { return nil }
+func (v *escapeContextVisitor) VisitHashPair(node *ast.HashPair) interface{}           { return nil }
@@ -0,0 +1,299 @@
+package raymond
+
+import (
+	"strings"
+	"testing"
+)
+
+type autoescapeTest struct {
+	name   string
+	input  string
+	data   interface{}
+	output string
+}
+
+var autoescapeTests = []autoescapeTest{
+	{
+		"text",
+		`<p>{{foo}}</p>`,
+		map[string]string{"foo": `<b>"bar"</b>`},
+		`<p>&lt;b&gt;&quot;bar&quot;&lt;/b&gt;</p>`,
+	},
+	{
+		"quoted attribute",
+		`<p title="{{foo}}" class='{{foo}}'>`,
+		map[string]string{"foo": `a "b" 'c'`},
+		`<p title="a &quot;b&quot; &apos;c&apos;" class='a &quot;b&quot; &apos;c&apos;'>`,
+	},
+	{
+		"unquoted attribute",
+		`<p title={{foo}}>`,
+		map[string]string{"foo": `a b=c`},
+		`<p title=a&#32;b&#61;c>`,
+	},
+	{
+		"attribute name",
+		`<p {{foo}}="bar" {{baz}}="qux">`,
+		map[string]string{"foo": "title", "baz": "onclick"},
+		`<p title="bar" ZgotmplZ="qux">`,
+	},
+	{
+		"safe URL",
+		`<a href="{{url}}">`,
+		map[string]string{"url": "https://example.com/a b?c=d"},
+		`<a href="https://example.com/a%20b?c=d">`,
+	},
+	{
+		"unsafe URL scheme",
+		`<a href="{{url}}">`,
+		map[string]string{"url": "javascript:alert(1)"},
+		`<a href="#ZgotmplZ">`,
+	},
+	{
+		"unsafe URL scheme in unquoted attribute",
+		`<img src={{url}}>`,
+		map[string]string{"url": "JavaScript:alert(1)"},
+		`<img src=#ZgotmplZ>`,
+	},
+	{
+		"unsafe URL scheme after leading whitespace",
+		`<a href=" {{url}}">`,
+		map[string]string{"url": "javascript:alert(1)"},
+		`<a href=" #ZgotmplZ">`,
+	},
+	{
+		"unsafe URL scheme with leading whitespace",
+		`<a href="{{url}}">`,
+		map[string]string{"url": "\tjavascript:alert(1)"},
+		`<a href="#ZgotmplZ">`,
+	},
+	{
+		"srcdoc attribute",
+		`<iframe srcdoc="{{doc}}">`,
+		map[string]string{"doc": `<script>alert(1)</script>`},
+		`<iframe srcdoc="&lt;script&gt;alert(1)&lt;/script&gt;">`,
+	},
+	{
+		"URL path",
+		`<a href="/search/{{q}}">`,
+		map[string]string{"q": "javascript:alert(1)"},
+		`<a href="/search/javascript:alert%281%29">`,
+	},
+	{
+		"URL query",
+		`<a href="/search?q={{q}}&amp;lang={{lang}}">`,
+		map[string]string{"q": "a&b=c d", "lang": "fr"},
+		`<a href="/search?q=a%26b%3Dc%20d&amp;lang=fr">`,
+	},
+	{
+		"JavaScript string in event handler",
+		`<button onclick="alert('{{msg}}')">`,
+		map[string]string{"msg": `it's </script>`},
+		`<button onclick="alert('it\u0027s \u003c\u002fscript\u003e')">`,
+	},
+	{
+		"JavaScript value in script",
+		`<script>var x = {{x}}, s = "{{s}}";</script>`,
+		map[string]interface{}{"x": map[string]interface{}{"a": []int{1, 2}}, "s": `a"b`},
+		`<script>var x = {"a":[1,2]}, s = "a\u0022b";</script>`,
+	},
+	{
+		"JavaScript string in script",
+		`<script>var s = '{{s}}';</script>`,
+		map[string]string{"s": "</script><script>alert(1)//"},
+		`<script>var s = '\u003c\u002fscript\u003e\u003cscript\u003ealert(1)\u002f\u002f';</script>`,
+	},
+	{
+		"JavaScript comment",
+		`<script>//{{foo}}
+var s = 1; /* {{foo}} */</script>`,
+		map[string]string{"foo": "bar"},
+		`<script>//
+var s = 1; /*  */</script>`,
+	},
+	{
+		"CSS value",
+		`<p style="color: {{color}}; background: {{bg}}">`,
+		map[string]string{"color": "#fff", "bg": "url(javascript:alert(1))"},
+		`<p style="color: #fff; background: ZgotmplZ">`,
+	},
+	{
+		"CSS in style element",
+		`<style>p { font-family: "{{font}}"; color: {{color}} }</style>`,
+		map[string]string{"font": `a"}b`, "color": "red"},
+		`<style>p { font-family: "a\22\7d b"; color: red }</style>`,
+	},
+	{
+		"JavaScript regular expression literal",
+		`<script>var r = /'[/]/; var s = {{x}};</script>`,
+		map[string]string{"x": "a"},
+		`<script>var r = /'[/]/; var s = "a";</script>`,
+	},
+	{
+		"JavaScript division",
+		`<script>var r = a / 2, b = (c) / 3; var s = '{{x}}';</script>`,
+		map[string]string{"x": "'"},
+		`<script>var r = a / 2, b = (c) / 3; var s = '\u0027';</script>`,
+	},
+	{
+		"JavaScript value in regular expression",
+		`<script>var r = /^{{x}}$/, e = /{{y}}/;</script>`,
+		map[string]string{"x": "a.b/c", "y": ""},
+		`<script>var r = /^a\.b\u002fc$/, e = /(?:)/;</script>`,
+	},
+	{
+		"JavaScript division after value",
+		`<script>var r = {{x}} / 2 / {{x}};</script>`,
+		map[string]int{"x": 4},
+		`<script>var r = 4 / 2 / 4;</script>`,
+	},
+	{
+		"text after script element",
+		`<script>var x = 1;</script>{{foo}}`,
+		map[string]string{"foo": "<br>"},
+		`<script>var x = 1;</script>&lt;br&gt;`,
+	},
+	{
+		"HTML comment",
+		`<!-- <a href="{{foo}}"> -->{{foo}}`,
+		map[string]string{"foo": "<br>"},
+		`<!-- <a href="&lt;br&gt;"> -->&lt;br&gt;`,
+	},
+	{
+		"context tracked across blocks",
+		`<a {{#if foo}}class="foo" {{/if}}href="{{url}}">`,
+		map[string]interface{}{"foo": true, "url": "javascript:alert(1)"},
+		`<a class="foo" href="#ZgotmplZ">`,
+	},
+	{
+		"block in attribute value",
+		`<a href="{{#if foo}}{{url}}{{else}}/{{url}}{{/if}}">`,
+		map[string]interface{}{"foo": true, "url": "javascript:alert(1)"},
+		`<a href="#ZgotmplZ">`,
+	},
+	{
+		"each block in query",
+		`<a href="/?{{#each list}}q={{.}}&amp;{{/each}}">`,
+		map[string]interface{}{"list": []string{"a b", "c"}},
+		`<a href="/?q=a%20b&amp;q=c&amp;">`,
+	},
+	{
+		"safe string is not escaped",
+		`<a href="{{safe}}" title="{{{raw}}}">`,
+		map[string]interface{}{"safe": SafeString("javascript:alert(1)"), "raw": "<b>"},
+		`<a href="javascript:alert(1)" title="<b>">`,
+	},
+}
+
+func TestContextualEscaping(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range autoescapeTests {
+		tpl, err := ParseWithOptions(test.input, ParseOptions{ContextualEscaping: true})
+		if err != nil {
+			t.Errorf("Test '%s' failed - Unexpected parse error: %s", test.name, err)
+			continue
+		}
+
+		output, err := tpl.Exec(test.data)
+		if err != nil {
+			t.Errorf("Test '%s' failed - Unexpected error: %s", test.name, err)
+		} else if output != test.output {
+			t.Errorf("Test '%s' failed - Expected %q but got %q", test.name, test.output, output)
+		}
+	}
+}
+
+func TestContextualEscapingDisabled(t *testing.T) {
+	t.Parallel()
+
+	output := MustRender(`<a href="{{url}}">`, map[string]string{"url": "javascript:alert(1)"})
+	if output != `<a href="javascript:alert(1)">` {
+		t.Errorf("Unexpected output: %q", output)
+	}
+}
+
+func TestContextualEscapingBranchesMismatch(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseWithOptions(`<a {{#if foo}}href="{{/if}}">`, ParseOptions{ContextualEscaping: true})
+	if err == nil || !strings.Contains(err.Error(), "block branches end in different contexts") {
+		t.Errorf("Expected a context mismatch error, got: %v", err)
+	}
+}
+
+func TestContextualEscapingRepeatedBlock(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseWithOptions(`{{#each xs}}<a title="{{this}}{{else}}<a title="{{/each}}">`, ParseOptions{ContextualEscaping: true})
+	if err == nil || !strings.Contains(err.Error(), "block content may be repeated") {
+		t.Errorf("Expected a repeated block context error, got: %v", err)
+	}
+
+	_, err = ParseWithOptions(`<a title="{{#list}}{{.}}{{/list}}">`, ParseOptions{ContextualEscaping: true})
+	if err != nil {
+		t.Errorf("Unexpected error with a repeated block ending in its start context: %s", err)
+	}
+}
+
+func TestContextualEscapingInlinePartial(t *testing.T) {
+	t.Parallel()
+
+	tpl, err := ParseWithOptions(`{{#*inline "link"}}{{url}}{{/inline}}<a href="{{> link}}">{{> link}}`, ParseOptions{ContextualEscaping: true})
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	output := tpl.MustExec(map[string]string{"url": "javascript:alert(1)"})
+	if output != `<a href="#ZgotmplZ">javascript:alert(1)` {
+		t.Errorf("Unexpected output: %q", output)
+	}
+}
+
+func TestContextualEscapingPartials(t *testing.T) {
+	t.Parallel()
+
+	ctx := map[string]string{"url": "javascript:alert(1)", "x": "</script>"}
+
+	tests := []struct {
+		source   string
+		partial  string
+		expected string
+	}{
+		{`{{> p}}`, `<a href="{{url}}">`, `<a href="#ZgotmplZ">`},
+		{`<a href="{{> p}}">`, `{{url}}`, `<a href="#ZgotmplZ">`},
+		{`<script>var a = {{> p}};</script>`, `{{x}}`, `<script>var a = "\u003c/script\u003e";</script>`},
+		{`<script>var a = "{{> p}}";</script>`, `{{x}}`, `<script>var a = "\u003c\u002fscript\u003e";</script>`},
+	}
+
+	for _, test := range tests {
+		tpl, err := ParseWithOptions(test.source, ParseOptions{ContextualEscaping: true})
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+
+		tpl.RegisterPartial("p", test.partial)
+
+		// twice, to check cached analysis
+		for i := 0; i < 2; i++ {
+			if output := tpl.MustExec(ctx); output != test.expected {
+				t.Errorf("Unexpected output for partial %q called in %q: %q, expected: %q", test.partial, test.source, output, test.expected)
+			}
+		}
+	}
+}
+
+func TestContextualEscapingPartialMismatch(t *testing.T) {
+	t.Parallel()
+
+	tpl, err := ParseWithOptions(`<p title="{{> p}}">`, ParseOptions{ContextualEscaping: true})
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	tpl.RegisterPartial("p", `<a href="{{url}}">`)
+
+	if _, err := tpl.Exec(nil); err == nil || !strings.Contains(err.Error(), "partial must end in the context it is called in") {
+		t.Errorf("Expected a context mismatch error, got: %v", err)
+	}
+}
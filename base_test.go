@@ -17,6 +17,11 @@ type Test struct {
 }
 
 func launchTests(t *testing.T, tests []Test) {
+	launchTestsWithSetup(t, tests, nil)
+}
+
+// launchTestsWithSetup launches tests, calling given setup function on each parsed template if not nil
+func launchTestsWithSetup(t *testing.T, tests []Test, setup func(tpl *Template)) {
 	// NOTE: TestMustache() makes Parallel testing fail
 	// t.Parallel()
 
@@ -29,6 +34,10 @@ func launchTests(t *testing.T, tests []Test) {
 		if err != nil {
 			t.Errorf("Test '%s' failed - Failed to parse template\ninput:\n\t'%s'\nerror:\n\t%s", test.name, test.input, err)
 		} else {
+			if setup != nil {
+				setup(tpl)
+			}
+
 			if len(test.helpers) > 0 {
 				// register helpers
 				tpl.RegisterHelpers(test.helpers)
@@ -100,6 +100,51 @@ func BenchmarkComplex(b *testing.B) {
 	}
 }
 
+type benchItem struct {
+	Name    string
+	Current bool
+	Link    string `handlebars:"url"`
+}
+
+type benchPage struct {
+	Items []benchItem
+}
+
+func (p *benchPage) Header() string { return "Colors" }
+
+func BenchmarkComplexStruct(b *testing.B) {
+	source := `<h1>{{header}}</h1>
+{{#if items}}
+  <ul>
+    {{#each items}}
+      {{#if current}}
+        <li><strong>{{name}}</strong></li>
+      {{^}}
+        <li><a href="{{url}}">{{name}}</a></li>
+      {{/if}}
+    {{/each}}
+  </ul>
+{{^}}
+  <p>The list is empty.</p>
+{{/if}}
+`
+
+	ctx := &benchPage{
+		Items: []benchItem{
+			{"red", true, "#Red"},
+			{"green", false, "#Green"},
+			{"blue", false, "#Blue"},
+		},
+	}
+
+	tpl := MustParse(source)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
+
 func BenchmarkData(b *testing.B) {
 	source := `{{#each names}}{{@index}}{{name}}{{/each}}`
 
@@ -0,0 +1,132 @@
+// Command raymond-gen generates Go code that renders handlebars templates with a statically typed context.
+//
+// Usage:
+//
+//	raymond-gen -type <Type> [-o <file>] [-helpers <names>] [-strict] <template.hbs>...
+//
+// The context type must be declared in the package of the output file. For each template file, a function is
+// generated, named after the file name: user_profile.hbs is rendered by:
+//
+//	func RenderUserProfile(w io.Writer, ctx *<Type>) error
+//
+// It is meant to be used with go generate, eg:
+//
+//	//go:generate raymond-gen -type Page -helpers fullName,link templates/page.hbs templates/footer.hbs
+//
+// Only a subset of the language can be generated: templates that use partials, decorators, raw blocks, block helpers
+// other than #if, #unless, #with and #each, literal expressions, or lookups in values of interface type, fall back on
+// the interpreter. A warning is printed for each of them, or raymond-gen fails with the -strict flag.
+//
+// See the documentation of the github.com/aymerick/raymond/gen package for the full list of unsupported constructs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aymerick/raymond/gen"
+)
+
+var (
+	typeName = flag.String("type", "", "name of the context type (required)")
+	output   = flag.String("o", "templates_gen.go", "output file")
+	helpers  = flag.String("helpers", "", "comma-separated list of the global helpers that templates can call")
+	strict   = flag.Bool("strict", false, "fail if a template can't be generated, instead of falling back on the interpreter")
+)
+
+// unsupportedHelp describes the templates that can't be generated
+const unsupportedHelp = `Templates that use partials, decorators, raw blocks, block helpers other than #if, #unless,
+#with and #each, literal expressions, or lookups in values of interface type, can't be generated:
+they fall back on the interpreter with a warning, or raymond-gen fails with -strict.
+See the documentation of the github.com/aymerick/raymond/gen package for the full list.`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: raymond-gen -type <Type> [flags] <template.hbs>...\n\nFlags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\n%s\n", unsupportedHelp)
+	}
+
+	flag.Parse()
+
+	if (*typeName == "") || (flag.NArg() == 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "raymond-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the output file
+func run() error {
+	pkg, err := gen.LoadPackage(filepath.Dir(*output))
+	if err != nil {
+		return err
+	}
+
+	typ, err := gen.LookupType(pkg, *typeName)
+	if err != nil {
+		return err
+	}
+
+	config := gen.Config{
+		Package: pkg,
+		Strict:  *strict,
+		Fallback: func(name string, err error) {
+			fmt.Fprintf(os.Stderr, "raymond-gen: warning: template %s falls back on the interpreter: %s\n", name, err)
+		},
+	}
+
+	if *helpers != "" {
+		for _, name := range strings.Split(*helpers, ",") {
+			config.Helpers = append(config.Helpers, strings.TrimSpace(name))
+		}
+	}
+
+	for _, fileName := range flag.Args() {
+		source, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+
+		config.Templates = append(config.Templates, gen.Template{
+			Name:   templateName(fileName),
+			Source: string(source),
+			Type:   typ,
+		})
+	}
+
+	code, err := gen.Generate(config)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(*output, code, 0644)
+}
+
+// templateName returns the name of template in given file, eg: templates/user_profile.hbs => UserProfile
+func templateName(fileName string) string {
+	base := filepath.Base(fileName)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	words := strings.FieldsFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	result := ""
+	for _, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		result += string(unicode.ToUpper(r)) + word[size:]
+	}
+
+	return result
+}
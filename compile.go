@@ -0,0 +1,435 @@
+package raymond
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+//
+// A parsed template is compiled to a tree of closures, so that what does not depend on the evaluation context is computed
+// once: path parts are pre-split, helper lookups are memoized, parameters are pre-evaluated when they are literals, and so on.
+//
+// Statements that are not compiled, and programs that are not part of a compiled template (eg. the result of a mustache
+// lambda), are evaluated by visiting the AST.
+//
+
+// compiledPrograms maps programs of a template to their compiled form
+type compiledPrograms map[*ast.Program]*compiledProgram
+
+// compiledProgram represents a compiled program
+type compiledProgram struct {
+	node       *ast.Program
+	decorators bool // does program have decorators ?
+	body       []statementFunc
+}
+
+// statementFunc evaluates a compiled statement
+type statementFunc func(v *evalVisitor)
+
+// valueFunc evaluates a compiled parameter and returns its value
+type valueFunc func(v *evalVisitor) interface{}
+
+// compiledExpression represents a compiled expression
+type compiledExpression struct {
+	node        *ast.Expression
+	helperName  string
+	maybeHelper bool // false if helperName can't be a helper in knownHelpersOnly mode
+
+	// memoized helper lookup
+	binding atomic.Pointer[helperBinding]
+
+	literal   string
+	isLiteral bool
+
+	path      *ast.PathExpression
+	pathParts []pathPart
+
+	params []valueFunc
+	hash   []compiledHashPair
+
+	// params ids in trackIds mode
+	ids     []string
+	hashIds map[string]string
+}
+
+// compiledHashPair represents a compiled hash argument
+type compiledHashPair struct {
+	key string
+	val valueFunc
+}
+
+// helperBinding is the result of a helper lookup for an evaluated template, that is valid as long as no helper is
+// registered nor removed
+type helperBinding struct {
+	tpl       *Template
+	tplGen    uint64
+	globalGen uint64
+	helper    reflect.Value
+}
+
+// pathPart represents a path part, ie. a field name
+type pathPart struct {
+	name      string
+	titleName string        // name with first letter uppercased, empty if not precomputed
+	nameValue reflect.Value // name as a map key, invalid if not precomputed
+}
+
+// title returns path part name, with first letter uppercased
+func (p pathPart) title() string {
+	if p.titleName != "" {
+		return p.titleName
+	}
+
+	return strings.Title(p.name)
+}
+
+// key returns path part name as a map key
+func (p pathPart) key() reflect.Value {
+	if p.nameValue.IsValid() {
+		return p.nameValue
+	}
+
+	return reflect.ValueOf(p.name)
+}
+
+// newPathParts returns the path parts of given path expression parts
+func newPathParts(parts []string) []pathPart {
+	result := make([]pathPart, len(parts))
+
+	for i, part := range parts {
+		// "[foo bar]"" => "foo bar"
+		if (len(part) >= 2) && (part[0] == '[') && (part[len(part)-1] == ']') {
+			part = part[1 : len(part)-1]
+		}
+
+		result[i].name = part
+	}
+
+	return result
+}
+
+// compilePathParts returns the path parts of given path expression parts, with precomputed titles
+func compilePathParts(parts []string) []pathPart {
+	result := newPathParts(parts)
+
+	for i := range result {
+		result[i].titleName = strings.Title(result[i].name)
+		result[i].nameValue = reflect.ValueOf(result[i].name)
+	}
+
+	return result
+}
+
+// compiler compiles the programs of a template
+type compiler struct {
+	knownHelpers map[string]bool
+	programs     compiledPrograms
+}
+
+// compile compiles given template program, and all its sub programs
+func compile(program *ast.Program, knownHelpers map[string]bool) compiledPrograms {
+	c := &compiler{
+		knownHelpers: knownHelpers,
+		programs:     make(compiledPrograms),
+	}
+
+	c.compileProgram(program)
+
+	return c.programs
+}
+
+// compileProgram compiles given program
+func (c *compiler) compileProgram(node *ast.Program) *compiledProgram {
+	if node == nil {
+		return nil
+	}
+
+	result := &compiledProgram{node: node}
+	c.programs[node] = result
+
+	for _, n := range node.Body {
+		switch n.(type) {
+		case *ast.Decorator, *ast.DecoratorBlock:
+			result.decorators = true
+		}
+
+		if stmt := c.compileStatement(n); stmt != nil {
+			result.body = append(result.body, stmt)
+		}
+	}
+
+	return result
+}
+
+// compileStatement compiles given statement, or returns nil if it outputs nothing
+func (c *compiler) compileStatement(node ast.Node) statementFunc {
+	switch n := node.(type) {
+	case *ast.ContentStatement:
+		value := n.Value
+
+		return func(v *evalVisitor) {
+			v.write(value)
+		}
+
+	case *ast.CommentStatement, *ast.Decorator:
+		// decorators are run before program body
+		return nil
+
+	case *ast.DecoratorBlock:
+		c.compileProgram(n.Program)
+		return nil
+
+	case *ast.MustacheStatement:
+		expr := c.compileExpression(n.Expression)
+
+		return func(v *evalVisitor) {
+			v.at(n)
+
+			v.curMustache = n
+
+			v.writeMustache(n, expr.eval(v))
+		}
+
+	case *ast.BlockStatement:
+		expr := c.compileExpression(n.Expression)
+		c.compileProgram(n.Program)
+		c.compileProgram(n.Inverse)
+
+		return func(v *evalVisitor) {
+			v.at(n)
+
+			v.pushBlock(n)
+
+			value, helper := expr.evalHelper(v)
+			v.writeBlock(n, value, helper)
+
+			v.popBlock()
+		}
+
+	case *ast.PartialBlockStatement:
+		c.compileProgram(n.Program)
+	case *ast.ParentStatement:
+		c.compileProgram(n.Program)
+	case *ast.NamedBlockStatement:
+		c.compileProgram(n.Program)
+	}
+
+	// fallback on AST evaluation
+	return func(v *evalVisitor) {
+		node.Accept(v)
+	}
+}
+
+// compileExpression compiles given expression
+func (c *compiler) compileExpression(node *ast.Expression) *compiledExpression {
+	result := &compiledExpression{
+		node:       node,
+		helperName: node.HelperName(),
+		path:       node.FieldPath(),
+	}
+
+	// in knownHelpersOnly mode, an unknown helper is never looked up
+	result.maybeHelper = (result.helperName != "") && ((c.knownHelpers == nil) || c.knownHelpers[result.helperName])
+
+	result.literal, result.isLiteral = node.LiteralStr()
+
+	if result.path != nil {
+		result.pathParts = compilePathParts(result.path.Parts)
+	}
+
+	for _, param := range node.Params {
+		result.params = append(result.params, c.compileValue(param))
+	}
+
+	if node.Hash != nil {
+		for _, pair := range node.Hash.Pairs {
+			result.hash = append(result.hash, compiledHashPair{pair.Key, c.compileValue(pair.Val)})
+		}
+	}
+
+	result.ids, result.hashIds = paramIds(node)
+
+	return result
+}
+
+// compileValue compiles given parameter
+func (c *compiler) compileValue(node ast.Node) valueFunc {
+	switch n := node.(type) {
+	case *ast.StringLiteral:
+		value := n.Value
+
+		return func(v *evalVisitor) interface{} {
+			return value
+		}
+
+	case *ast.BooleanLiteral:
+		value := n.Value
+
+		return func(v *evalVisitor) interface{} {
+			return value
+		}
+
+	case *ast.NumberLiteral:
+		value := n.Number()
+
+		return func(v *evalVisitor) interface{} {
+			return value
+		}
+
+	case *ast.PathExpression:
+		parts := compilePathParts(n.Parts)
+
+		return func(v *evalVisitor) interface{} {
+			return v.evalPathExpression(n, parts, false)
+		}
+
+	case *ast.SubExpression:
+		expr := c.compileExpression(n.Expression)
+
+		return func(v *evalVisitor) interface{} {
+			v.at(n)
+
+			return expr.eval(v)
+		}
+	}
+
+	// fallback on AST evaluation
+	return func(v *evalVisitor) interface{} {
+		return node.Accept(v)
+	}
+}
+
+// exec evaluates compiled program
+func (p *compiledProgram) exec(v *evalVisitor) {
+	var scope *decoratorScope
+
+	// decorators are run before program body
+	if p.decorators {
+		if scope = v.evalDecorators(p.node); scope != nil {
+			v.enterScope(scope)
+		}
+	}
+
+	for _, stmt := range p.body {
+		// checked between each statement, so that includes each block iteration
+		v.checkDone()
+
+		stmt(v)
+	}
+
+	if scope != nil {
+		v.leaveScope(scope)
+	}
+}
+
+// eval evaluates compiled expression
+func (e *compiledExpression) eval(v *evalVisitor) interface{} {
+	result, _ := e.evalHelper(v)
+
+	return result
+}
+
+// evalHelper evaluates compiled expression, and returns true if it was a helper call
+func (e *compiledExpression) evalHelper(v *evalVisitor) (interface{}, bool) {
+	v.at(e.node)
+
+	var result interface{}
+	done, helper := false, false
+
+	v.pushExpr(e.node, e)
+
+	// helper call
+	if h := e.findHelper(v); h != zero {
+		result = v.callHelper(e.helperName, h, e.options(v))
+		done, helper = true, true
+	}
+
+	// literal
+	if !done && e.isLiteral {
+		if val := v.evalPathPart(v.curCtx(), pathPart{name: e.literal}, true); val.IsValid() {
+			result = val.Interface()
+			done = true
+		}
+	}
+
+	// field path
+	if !done && (e.path != nil) {
+		if val := v.evalPathExpression(e.path, e.pathParts, true); val != nil {
+			result = val
+			done = true
+		}
+	}
+
+	if !done && (e.helperName != "") && !v.wasFuncCall(e.node) && v.isHelperMissingCall(e.node) {
+		// missing helper
+		result = v.helperMissing(e.helperName, e.node, e.options(v))
+	}
+
+	v.popExpr()
+
+	return result, helper
+}
+
+// findHelper finds compiled expression helper, or returns zero if it is not a helper call
+func (e *compiledExpression) findHelper(v *evalVisitor) reflect.Value {
+	if !e.maybeHelper {
+		return zero
+	}
+
+	// generations are loaded before looking up helper, so that a concurrent registration invalidates the binding
+	tplGen := atomic.LoadUint64(&v.tpl.helpersGen)
+	globalGen := atomic.LoadUint64(&helpersGen)
+
+	if b := e.binding.Load(); (b != nil) && (b.tpl == v.tpl) && (b.tplGen == tplGen) && (b.globalGen == globalGen) {
+		return b.helper
+	}
+
+	helper := v.findHelper(e.helperName)
+
+	e.binding.Store(&helperBinding{
+		tpl:       v.tpl,
+		tplGen:    tplGen,
+		globalGen: globalGen,
+		helper:    helper,
+	})
+
+	return helper
+}
+
+// options computes helper options argument of compiled expression
+func (e *compiledExpression) options(v *evalVisitor) *Options {
+	if v.stringParams {
+		// parameters are not evaluated
+		return v.helperOptions(e.node)
+	}
+
+	var params []interface{}
+	var hash map[string]interface{}
+
+	if len(e.params) > 0 {
+		params = make([]interface{}, len(e.params))
+		for i, param := range e.params {
+			params[i] = param(v)
+		}
+	}
+
+	if e.node.Hash != nil {
+		hash = make(map[string]interface{}, len(e.hash))
+		for _, pair := range e.hash {
+			if value := pair.val(v); value != nil {
+				hash[pair.key] = value
+			}
+		}
+	}
+
+	result := newOptions(v, params, hash)
+
+	if v.trackIds {
+		result.ids, result.hashIds = e.ids, e.hashIds
+	}
+
+	return result
+}
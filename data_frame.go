@@ -2,48 +2,64 @@ package raymond
 
 import "reflect"
 
+// contextPathName is the name of the private variable set to the path of current context in trackIds mode
+const contextPathName = "contextPath"
+
 // DataFrame represents a private data frame.
 //
 // Cf. private variables documentation at: http://handlebarsjs.com/block_helpers.html
 type DataFrame struct {
 	parent *DataFrame
-	data   map[string]interface{}
+
+	// values set on that frame, allocated on first Set()
+	data map[string]interface{}
+
+	// values not found in that frame are looked up in parent frame, ie. frame is a copy of its parent
+	inherit bool
+
+	// iteration data (@index, @key, @first, @last), set on frames instanciated for each iteration
+	iter   bool
+	index  int
+	key    interface{}
+	length int
 }
 
 // NewDataFrame instanciates a new private data frame.
 func NewDataFrame() *DataFrame {
-	return &DataFrame{
-		data: make(map[string]interface{}),
-	}
+	return &DataFrame{}
 }
 
 // Copy instanciates a new private data frame with receiver as parent.
+//
+// Returned frame holds a snapshot of receiver values: values set on receiver afterwards are not seen by returned frame.
 func (p *DataFrame) Copy() *DataFrame {
-	result := NewDataFrame()
-
-	for k, v := range p.data {
-		result.data[k] = v
+	return &DataFrame{
+		parent: p,
+		data:   p.values(),
 	}
-
-	result.parent = p
-
-	return result
 }
 
 // newIterDataFrame instanciates a new private data frame with receiver as parent and with iteration data set (@index, @key, @first, @last)
+//
+// Values of parent are not copied: they are looked up in parent when they are not set on returned frame, as parent is
+// not modified while iterating.
 func (p *DataFrame) newIterDataFrame(length int, i int, key interface{}) *DataFrame {
-	result := p.Copy()
-
-	result.Set("index", i)
-	result.Set("key", key)
-	result.Set("first", i == 0)
-	result.Set("last", i == length-1)
-
-	return result
+	return &DataFrame{
+		parent:  p,
+		inherit: true,
+		iter:    true,
+		index:   i,
+		key:     key,
+		length:  length,
+	}
 }
 
 // Set sets a data value.
 func (p *DataFrame) Set(key string, val interface{}) {
+	if p.data == nil {
+		p.data = make(map[string]interface{})
+	}
+
 	p.data[key] = val
 }
 
@@ -52,23 +68,71 @@ func (p *DataFrame) Get(key string) interface{} {
 	return p.find([]string{key})
 }
 
+// lookup returns the data value with given key, and false if it is not found
+func (p *DataFrame) lookup(key string) (interface{}, bool) {
+	for frame := p; frame != nil; frame = frame.parent {
+		if val, ok := frame.data[key]; ok {
+			return val, true
+		}
+
+		if frame.iter {
+			switch key {
+			case "index":
+				return frame.index, true
+			case "key":
+				return frame.key, true
+			case "first":
+				return frame.index == 0, true
+			case "last":
+				return frame.index == frame.length-1, true
+			}
+		}
+
+		if !frame.inherit {
+			break
+		}
+	}
+
+	return nil, false
+}
+
+// values returns all data values of frame
+func (p *DataFrame) values() map[string]interface{} {
+	result := make(map[string]interface{})
+
+	if p.inherit && (p.parent != nil) {
+		result = p.parent.values()
+	}
+
+	if p.iter {
+		result["index"] = p.index
+		result["key"] = p.key
+		result["first"] = p.index == 0
+		result["last"] = p.index == p.length-1
+	}
+
+	for k, v := range p.data {
+		result[k] = v
+	}
+
+	return result
+}
+
 // find gets a deep data value
 //
 // @todo This is NOT consistent with the way we resolve data in template (cf. `evalDataPathExpression()`) ! FIX THAT !
 func (p *DataFrame) find(parts []string) interface{} {
-	data := p.data
+	if len(parts) == 0 {
+		return nil
+	}
+
+	val, _ := p.lookup(parts[0])
 
-	for i, part := range parts {
-		val := data[part]
+	for _, part := range parts[1:] {
 		if val == nil {
 			return nil
 		}
 
-		if i == len(parts)-1 {
-			// found
-			return val
-		}
-
 		valValue := reflect.ValueOf(val)
 		if valValue.Kind() != reflect.Map {
 			// not found
@@ -76,11 +140,10 @@ func (p *DataFrame) find(parts []string) interface{} {
 		}
 
 		// continue
-		data = mapStringInterface(valValue)
+		val = mapStringInterface(valValue)[part]
 	}
 
-	// not found
-	return nil
+	return val
 }
 
 // mapStringInterface converts any `map` to `map[string]interface{}`
@@ -0,0 +1,74 @@
+package raymond
+
+import "testing"
+
+func TestDataFrameCopy(t *testing.T) {
+	t.Parallel()
+
+	parent := NewDataFrame()
+	parent.Set("foo", "bar")
+	parent.Set("baz", "qux")
+
+	frame := parent.Copy()
+	frame.Set("baz", "overridden")
+
+	if frame.Get("foo") != "bar" || frame.Get("baz") != "overridden" {
+		t.Errorf("Unexpected values in copied frame: %v", frame.values())
+	}
+
+	if parent.Get("baz") != "qux" {
+		t.Errorf("Copied frame modified its parent: %v", parent.values())
+	}
+
+	parent.Set("foo", "later")
+	if frame.Get("foo") != "bar" {
+		t.Errorf("Copied frame sees values set on its parent afterwards: %v", frame.values())
+	}
+
+	if NewDataFrame().Get("foo") != nil {
+		t.Errorf("A new frame must not have any value")
+	}
+}
+
+func TestIterDataFrame(t *testing.T) {
+	t.Parallel()
+
+	parent := NewDataFrame()
+	parent.Set("foo", "bar")
+
+	frame := parent.newIterDataFrame(3, 2, "key")
+
+	expected := map[string]interface{}{"foo": "bar", "index": 2, "key": "key", "first": false, "last": true}
+	for name, value := range expected {
+		if frame.Get(name) != value {
+			t.Errorf("Unexpected @%s in iteration frame: %v", name, frame.Get(name))
+		}
+	}
+
+	frame.Set("index", 10)
+	if frame.Get("index") != 10 {
+		t.Errorf("Iteration data can't be overridden: %v", frame.Get("index"))
+	}
+
+	if frame.Copy().Get("last") != true {
+		t.Errorf("Iteration data not found in copied frame")
+	}
+}
+
+func TestEvalDataFrames(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{#each outer}}{{#each this}}{{@../index}}.{{@index}}{{#if @last}}{{@foo}};{{/if}} {{/each}}{{/each}}`)
+
+	privData := NewDataFrame()
+	privData.Set("foo", "bar")
+
+	output, err := tpl.ExecWith(map[string]interface{}{"outer": [][]int{{1, 2}, {3}}}, privData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "0.0 0.1bar; 1.0bar; "; output != expected {
+		t.Errorf("Unexpected output: %q, expected: %q", output, expected)
+	}
+}
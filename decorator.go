@@ -0,0 +1,263 @@
+package raymond
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+// DecoratorFunc represents a decorator function.
+//
+// A decorator is called before evaluating the program where it is declared, and can change the context, private data and partials used to evaluate that program.
+type DecoratorFunc func(options *DecoratorOptions)
+
+// DecoratorOptions represents the options argument provided to decorators.
+//
+// Like helper Options, they are only valid during the evaluation that called the decorator.
+type DecoratorOptions struct {
+	// evaluation visitor, and its generation when options were instanciated
+	eval       *evalVisitor
+	generation uint64
+
+	// decorator name
+	name string
+
+	// params
+	params []interface{}
+	hash   map[string]interface{}
+
+	// decorator block program, nil for a decorator statement
+	program *ast.Program
+
+	// decorated program scope
+	scope *decoratorScope
+}
+
+// decoratorScope holds the changes made by decorators to the scope of the decorated program
+type decoratorScope struct {
+	ctx      reflect.Value
+	data     *DataFrame
+	partials map[string]*partial
+}
+
+// decorators stores all globally registered decorators
+var decorators = make(map[string]DecoratorFunc)
+
+// protects global decorators
+var decoratorsMutex sync.RWMutex
+
+func init() {
+	// register builtin decorators
+	RegisterDecorator("inline", inlineDecorator)
+}
+
+// RegisterDecorator registers a global decorator. That decorator will be available to all templates.
+func RegisterDecorator(name string, decorator DecoratorFunc) {
+	decoratorsMutex.Lock()
+	defer decoratorsMutex.Unlock()
+
+	if decorators[name] != nil {
+		panic(fmt.Errorf("Decorator already registered: %s", name))
+	}
+
+	if decorator == nil {
+		panic(fmt.Errorf("Decorator must be a function: %s", name))
+	}
+
+	decorators[name] = decorator
+}
+
+// RemoveDecorator unregisters a global decorator
+func RemoveDecorator(name string) {
+	decoratorsMutex.Lock()
+	defer decoratorsMutex.Unlock()
+
+	delete(decorators, name)
+}
+
+// findDecorator finds a globally registered decorator
+func findDecorator(name string) DecoratorFunc {
+	decoratorsMutex.RLock()
+	defer decoratorsMutex.RUnlock()
+
+	return decorators[name]
+}
+
+// newDecoratorOptions instanciates a new DecoratorOptions
+func newDecoratorOptions(eval *evalVisitor, name string, options *Options, program *ast.Program, scope *decoratorScope) *DecoratorOptions {
+	return &DecoratorOptions{
+		eval:       eval,
+		generation: eval.generation,
+		name:       name,
+		params:     options.params,
+		hash:       options.hash,
+		program:    program,
+		scope:      scope,
+	}
+}
+
+// visitor returns the evaluation visitor, and panics if the evaluation that called the decorator is done
+func (options *DecoratorOptions) visitor() *evalVisitor {
+	if options.eval.generation != options.generation {
+		panic(errOptionsReleased)
+	}
+
+	return options.eval
+}
+
+// Name returns the name of the called decorator.
+func (options *DecoratorOptions) Name() string {
+	return options.name
+}
+
+//
+// Context
+//
+
+// Ctx returns the context the decorated program will be evaluated with.
+func (options *DecoratorOptions) Ctx() interface{} {
+	if options.scope.ctx.IsValid() {
+		return options.scope.ctx.Interface()
+	}
+
+	return options.visitor().curCtx().Interface()
+}
+
+// SetCtx sets the context the decorated program will be evaluated with. Parent context is still accessible with `../`.
+func (options *DecoratorOptions) SetCtx(ctx interface{}) {
+	options.scope.ctx = reflect.ValueOf(ctx)
+}
+
+// Context returns the execution context provided to Template.ExecContext(), or context.Background() if template was not executed with that function.
+func (options *DecoratorOptions) Context() context.Context {
+	return options.visitor().execCtx
+}
+
+//
+// Hash Arguments
+//
+
+// HashProp returns hash property.
+func (options *DecoratorOptions) HashProp(name string) interface{} {
+	return options.hash[name]
+}
+
+// HashStr returns string representation of hash property.
+func (options *DecoratorOptions) HashStr(name string) string {
+	return Str(options.hash[name])
+}
+
+// Hash returns entire hash.
+func (options *DecoratorOptions) Hash() map[string]interface{} {
+	return options.hash
+}
+
+//
+// Parameters
+//
+
+// Param returns parameter at given position.
+func (options *DecoratorOptions) Param(pos int) interface{} {
+	if len(options.params) > pos {
+		return options.params[pos]
+	}
+
+	return nil
+}
+
+// ParamStr returns string representation of parameter at given position.
+func (options *DecoratorOptions) ParamStr(pos int) string {
+	return Str(options.Param(pos))
+}
+
+// Params returns all parameters.
+func (options *DecoratorOptions) Params() []interface{} {
+	return options.params
+}
+
+//
+// Private data
+//
+
+// Data returns private data value.
+func (options *DecoratorOptions) Data(name string) interface{} {
+	return options.DataFrame().Get(name)
+}
+
+// DataStr returns string representation of private data value.
+func (options *DecoratorOptions) DataStr(name string) string {
+	return Str(options.Data(name))
+}
+
+// DataFrame returns the private data frame the decorated program will be evaluated with.
+//
+// That data frame is a copy of current evaluation data frame, so values set on it are only available in the decorated program.
+func (options *DecoratorOptions) DataFrame() *DataFrame {
+	if options.scope.data == nil {
+		options.scope.data = options.visitor().dataFrame.Copy()
+	}
+
+	return options.scope.data
+}
+
+//
+// Partials
+//
+
+// RegisterPartial registers a partial that is only available in the decorated program, and in partials called from that program.
+func (options *DecoratorOptions) RegisterPartial(name string, source string) {
+	options.addPartial(newPartial(name, source, nil))
+}
+
+// RegisterPartialTemplate registers an already parsed partial that is only available in the decorated program, and in partials called from that program.
+func (options *DecoratorOptions) RegisterPartialTemplate(name string, tpl *Template) {
+	options.addPartial(newPartial(name, "", tpl))
+}
+
+// addPartial adds given partial to decorated program scope
+func (options *DecoratorOptions) addPartial(p *partial) {
+	if options.scope.partials == nil {
+		options.scope.partials = make(map[string]*partial)
+	}
+
+	options.scope.partials[p.name] = p
+}
+
+//
+// Evaluation
+//
+
+// Fn evaluates the decorator block with current evaluation context. It returns an empty string for a decorator that is not a block.
+func (options *DecoratorOptions) Fn() string {
+	if options.program == nil {
+		return ""
+	}
+
+	return options.visitor().evalProgram(options.program, nil, nil, nil)
+}
+
+//
+// Builtin decorators
+//
+
+// #*inline decorator
+func inlineDecorator(options *DecoratorOptions) {
+	if options.program == nil {
+		options.visitor().errorf("Inline partial must be a decorator block")
+	}
+
+	if len(options.params) != 1 {
+		options.visitor().errorf("Inline partial must have one name parameter")
+	}
+
+	name := options.ParamStr(0)
+	if name == "" {
+		options.visitor().errorf("Unexpected inline partial name: %q", options.params[0])
+	}
+
+	// an inline partial is part of current template
+	options.RegisterPartialTemplate(name, options.visitor().subTemplate(options.program))
+}
@@ -0,0 +1,169 @@
+package raymond
+
+import (
+	"strings"
+	"testing"
+)
+
+type decoratorTest struct {
+	name       string
+	input      string
+	data       interface{}
+	decorators map[string]DecoratorFunc
+	output     string
+}
+
+func setDataDecorator(options *DecoratorOptions) {
+	options.DataFrame().Set(options.ParamStr(0), options.Param(1))
+}
+
+func setCtxDecorator(options *DecoratorOptions) {
+	options.SetCtx(options.Hash())
+}
+
+func ctxDataDecorator(options *DecoratorOptions) {
+	options.DataFrame().Set("ctx", options.Ctx())
+}
+
+func partialDecorator(options *DecoratorOptions) {
+	options.RegisterPartial(options.ParamStr(0), options.ParamStr(1))
+}
+
+func blockDecorator(options *DecoratorOptions) {
+	options.DataFrame().Set(options.Name(), options.Fn())
+}
+
+var decoratorTests = []decoratorTest{
+	{
+		"decorator sets private data",
+		`{{* setData "foo" bar}}{{@foo}}`,
+		map[string]string{"bar": "baz"},
+		map[string]DecoratorFunc{"setData": setDataDecorator},
+		"baz",
+	},
+	{
+		"private data is scoped to decorated program",
+		`{{#if true}}{{* setData "foo" "bar"}}{{@foo}}{{/if}}-{{@foo}}`,
+		nil,
+		map[string]DecoratorFunc{"setData": setDataDecorator},
+		"bar-",
+	},
+	{
+		"decorator sets context",
+		`{{* setCtx foo="bar"}}{{foo}} {{../foo}}`,
+		map[string]string{"foo": "baz"},
+		map[string]DecoratorFunc{"setCtx": setCtxDecorator},
+		"bar baz",
+	},
+	{
+		"context is scoped to decorated program",
+		`{{#each list}}{{* setCtx foo=.}}{{foo}}{{/each}}-{{foo}}`,
+		map[string]interface{}{"list": []string{"a", "b"}, "foo": "c"},
+		map[string]DecoratorFunc{"setCtx": setCtxDecorator},
+		"ab-c",
+	},
+	{
+		"decorator registers partial",
+		`{{* partial "foo" "hello {{name}}"}}{{> foo}}`,
+		map[string]string{"name": "world"},
+		map[string]DecoratorFunc{"partial": partialDecorator},
+		"hello world",
+	},
+	{
+		"decorators are run before program body",
+		`{{@foo}}{{* setData "foo" "bar"}}`,
+		nil,
+		map[string]DecoratorFunc{"setData": setDataDecorator},
+		"bar",
+	},
+	{
+		"decorators are run in order",
+		`{{* setCtx foo="bar"}}{{* ctxData}}{{@ctx.foo}}`,
+		map[string]string{"foo": "baz"},
+		map[string]DecoratorFunc{"setCtx": setCtxDecorator, "ctxData": ctxDataDecorator},
+		"bar",
+	},
+	{
+		"decorator block",
+		"{{#*block}}\n  {{foo}}\n{{/block}}\n{{@block}}",
+		map[string]string{"foo": "bar"},
+		map[string]DecoratorFunc{"block": blockDecorator},
+		"  bar\n",
+	},
+	{
+		"decorator is not a block",
+		`{{* block}}{{@block}}`,
+		nil,
+		map[string]DecoratorFunc{"block": blockDecorator},
+		"",
+	},
+	{
+		"standalone decorator",
+		"{{* setData \"foo\" \"bar\"}}\n{{@foo}}",
+		nil,
+		map[string]DecoratorFunc{"setData": setDataDecorator},
+		"\nbar",
+	},
+	{
+		"decorator in partial",
+		`{{> myPartial}}`,
+		nil,
+		map[string]DecoratorFunc{"setData": setDataDecorator},
+		"bar",
+	},
+}
+
+func TestDecorators(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range decoratorTests {
+		tpl := MustParse(test.input)
+		tpl.RegisterPartial("myPartial", `{{* setData "foo" "bar"}}{{@foo}}`)
+
+		for name, decorator := range test.decorators {
+			tpl.RegisterDecorator(name, decorator)
+		}
+
+		output, err := tpl.Exec(test.data)
+		if err != nil {
+			t.Errorf("Test '%s' failed - Unexpected error: %s", test.name, err)
+		} else if output != test.output {
+			t.Errorf("Test '%s' failed - Expected %q but got %q", test.name, test.output, output)
+		}
+	}
+}
+
+func TestGlobalDecorator(t *testing.T) {
+	RegisterDecorator("testGlobalDecorator", setDataDecorator)
+	defer RemoveDecorator("testGlobalDecorator")
+
+	tpl := MustParse(`{{* testGlobalDecorator "foo" "bar"}}{{@foo}}`)
+
+	if output := tpl.MustExec(nil); output != "bar" {
+		t.Errorf("Expected %q but got %q", "bar", output)
+	}
+
+	// template decorator overrides global one
+	tpl = tpl.Clone()
+	tpl.RegisterDecorator("testGlobalDecorator", func(options *DecoratorOptions) {
+		options.DataFrame().Set("foo", "baz")
+	})
+
+	if output := tpl.MustExec(nil); output != "baz" {
+		t.Errorf("Expected %q but got %q", "baz", output)
+	}
+
+	// template decorators are cloned
+	if output := tpl.Clone().MustExec(nil); output != "baz" {
+		t.Errorf("Expected %q but got %q", "baz", output)
+	}
+}
+
+func TestDecoratorNotFound(t *testing.T) {
+	t.Parallel()
+
+	_, err := MustParse(`{{* unknown}}foo`).Exec(nil)
+	if err == nil || !strings.Contains(err.Error(), "Decorator not found: unknown") {
+		t.Errorf("Expected a decorator not found error, got: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package raymond
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 )
 
@@ -63,3 +64,76 @@ func Escape(s string) string {
 	escape(&buf, s)
 	return buf.String()
 }
+
+// Escaper represents a function that escapes the result of mustache expressions.
+//
+// The result of triple mustaches expressions and SafeString values are never escaped.
+type Escaper func(s string) string
+
+// NoEscape returns given string as is. Use it as an Escaper to disable escaping.
+func NoEscape(s string) string {
+	return s
+}
+
+// EscapeJSONString escapes given string to be embedded in a JSON string.
+func EscapeJSONString(s string) string {
+	var buf bytes.Buffer
+
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\u2028', '\u2029':
+			fmt.Fprintf(&buf, `\u%04x`, r)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// latexReplacer escapes LaTeX special characters
+var latexReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+// EscapeLaTeX escapes LaTeX special characters.
+func EscapeLaTeX(s string) string {
+	return latexReplacer.Replace(s)
+}
+
+// EscapeShell returns given string single-quoted, to be used as a single shell word.
+func EscapeShell(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// EscapeCSV returns given string as a CSV field, double-quoted if it contains a comma, a double quote, a line break, or leading or trailing spaces.
+func EscapeCSV(s string) string {
+	if (s == "") || (!strings.ContainsAny(s, ",\"\r\n") && (strings.TrimSpace(s) == s)) {
+		return s
+	}
+
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
@@ -1,6 +1,12 @@
 package raymond
 
-import "fmt"
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
 
 func ExampleEscape() {
 	tpl := MustParse("{{link url text}}")
@@ -18,3 +24,116 @@ func ExampleEscape() {
 	fmt.Print(result)
 	// Output: <a href='http://www.aymerick.com/'>This is a &lt;em&gt;cool&lt;/em&gt; website</a>
 }
+
+func ExampleTemplate_SetEscaper() {
+	tpl := MustParse(`{"name": "{{name}}"}`)
+	tpl.SetEscaper(EscapeJSONString)
+
+	result := tpl.MustExec(map[string]string{"name": `John "Johnny" <Doe>`})
+	fmt.Print(result)
+	// Output: {"name": "John \"Johnny\" <Doe>"}
+}
+
+var escaperTests = []struct {
+	name    string
+	escaper Escaper
+	input   string
+	output  string
+}{
+	{"no escape", NoEscape, `<a href="x">'&'</a>`, `<a href="x">'&'</a>`},
+	{"html", Escape, `<a href="x">'&'</a>`, `&lt;a href=&quot;x&quot;&gt;&apos;&amp;&apos;&lt;/a&gt;`},
+	{"json string", EscapeJSONString, "a\"b\\c\nd\te\x01\u2028", `a\"b\\c\nd\te\u0001\u2028`},
+	{"latex", EscapeLaTeX, `50% of $10 & #1_a {b} ~c^ \d`, `50\% of \$10 \& \#1\_a \{b\} \textasciitilde{}c\textasciicircum{} \textbackslash{}d`},
+	{"shell", EscapeShell, `it's $HOME`, `'it'\''s $HOME'`},
+	{"shell empty", EscapeShell, ``, `''`},
+	{"csv", EscapeCSV, `foo`, `foo`},
+	{"csv comma", EscapeCSV, `foo, bar`, `"foo, bar"`},
+	{"csv quote", EscapeCSV, `say "hi"`, `"say ""hi"""`},
+	{"csv line break", EscapeCSV, "a\nb", "\"a\nb\""},
+	{"csv spaces", EscapeCSV, ` a `, `" a "`},
+}
+
+func TestEscapers(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range escaperTests {
+		if output := test.escaper(test.input); output != test.output {
+			t.Errorf("Test '%s' failed - Expected %q but got %q", test.name, test.output, output)
+		}
+	}
+}
+
+func TestTemplateEscaper(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{foo}} {{{foo}}} {{safe}} {{> part}}`)
+	tpl.RegisterPartial("part", `{{foo}}`)
+	tpl.SetEscaper(EscapeShell)
+
+	ctx := map[string]interface{}{"foo": "it's", "safe": SafeString("it's")}
+
+	// triple mustaches and safe strings are not escaped
+	expected := `'it'\''s' it's it's 'it'\''s'`
+	if output := tpl.MustExec(ctx); output != expected {
+		t.Errorf("Expected %q but got %q", expected, output)
+	}
+
+	// escaper is cloned
+	if output := tpl.Clone().MustExec(ctx); output != expected {
+		t.Errorf("Expected %q but got %q", expected, output)
+	}
+
+	// exec escaper overrides template escaper
+	if output, err := tpl.ExecWithOptions(ctx, ExecOptions{Escaper: EscapeLaTeX}); err != nil || output != `it's it's it's it's` {
+		t.Errorf("Unexpected result: %q, %v", output, err)
+	}
+
+	// other exec methods use template escaper
+	buf := new(bytes.Buffer)
+	if err := tpl.ExecTo(buf, ctx); err != nil || buf.String() != expected {
+		t.Errorf("Unexpected result: %q, %v", buf.String(), err)
+	}
+
+	if output, err := tpl.ExecContext(context.Background(), ctx, nil); err != nil || output != expected {
+		t.Errorf("Unexpected result: %q, %v", output, err)
+	}
+
+	// default is HTML escaping
+	tpl.SetEscaper(nil)
+	expected = `it&apos;s it's it's it&apos;s`
+	if output := tpl.MustExec(ctx); output != expected {
+		t.Errorf("Expected %q but got %q", expected, output)
+	}
+}
+
+func TestContextualEscapingWithEscaper(t *testing.T) {
+	t.Parallel()
+
+	tpl, err := ParseWithOptions(`<a href="{{url}}">`, ParseOptions{ContextualEscaping: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tpl.ExecWithOptions(nil, ExecOptions{Escaper: NoEscape}); err != errContextualEscaper {
+		t.Errorf("Expected an error with a custom escaper and contextual escaping, got: %v", err)
+	}
+
+	tpl.SetEscaper(EscapeShell)
+
+	if _, err := tpl.Exec(nil); err != errContextualEscaper {
+		t.Errorf("Expected an error with a custom escaper and contextual escaping, got: %v", err)
+	}
+
+	// a contextually escaped partial
+	tpl = MustParse(`{{> p}}`)
+	partial, err := ParseWithOptions(`<a href="{{url}}">`, ParseOptions{ContextualEscaping: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl.RegisterPartialTemplate("p", partial)
+
+	if _, err := tpl.ExecWithOptions(nil, ExecOptions{Escaper: NoEscape}); err == nil || !strings.Contains(err.Error(), errContextualEscaper.Error()) {
+		t.Errorf("Expected an error with a custom escaper and a contextually escaped partial, got: %v", err)
+	}
+}
@@ -2,12 +2,16 @@ package raymond
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/aymerick/raymond/ast"
+	"github.com/aymerick/raymond/parser"
 )
 
 var (
@@ -22,6 +26,13 @@ var (
 type evalVisitor struct {
 	tpl *Template
 
+	// output
+	out writer
+
+	// execution context, and its done channel
+	execCtx  context.Context
+	execDone <-chan struct{}
+
 	// contexts stack
 	ctx []reflect.Value
 
@@ -34,31 +45,136 @@ type evalVisitor struct {
 	// block statements stack
 	blocks []*ast.BlockStatement
 
+	// inline partials stack
+	partials []map[string]*partial
+
+	// partial blocks stack, ie. templates rendered by {{> @partial-block}}
+	partialBlocks []*Template
+
+	// mustache blocks overridden by parents being evaluated, ie. {{$name}}...{{/name}}
+	namedBlocks map[string]namedBlock
+
+	// known helpers in knownHelpersOnly mode, nil otherwise
+	knownHelpers map[string]bool
+
+	// mustaches and partials escaping contexts in contextual escaping mode, nil otherwise
+	escapeContexts map[ast.Node]escapeContext
+
+	// custom escaper, nil for HTML escaping
+	escaper Escaper
+
+	// logger used by the `log` helper
+	logger Logger
+
+	// missing fields handling
+	strict   StrictMode
+	compat   bool
+	warnings Warnings
+
+	// helpers parameters handling
+	trackIds     bool
+	stringParams bool
+
+	// mustache lambdas handling
+	lambdas     bool
+	curMustache *ast.MustacheStatement
+
 	// expressions stack
-	exprs []*ast.Expression
+	exprs []exprFrame
 
-	// memoize expressions that were function calls
+	// memoize expressions that were function calls, allocated on first function call
 	exprFunc map[*ast.Expression]bool
 
+	// compiled programs of currently evaluated template, nil if template is not compiled
+	compiled compiledPrograms
+
 	// used for info on panic
 	curNode ast.Node
+
+	// incremented each time visitor is released, so that helper options can't use a visitor reused by another evaluation
+	generation uint64
 }
 
-// NewEvalVisitor instanciate a new evaluation visitor with given context and initial private data frame
+// NewEvalVisitor instanciate a new evaluation visitor with given execution context, context and initial private data frame, that writes its result to given writer
 //
-// If privData is nil, then a default data frame is created
-func newEvalVisitor(tpl *Template, ctx interface{}, privData *DataFrame) *evalVisitor {
+// If privData is nil, then a default data frame is created. If escaper is nil, then template escaper is used.
+func newEvalVisitor(execCtx context.Context, tpl *Template, ctx interface{}, privData *DataFrame, escaper Escaper, w io.Writer) *evalVisitor {
 	frame := privData
 	if frame == nil {
 		frame = NewDataFrame()
 	}
 
-	return &evalVisitor{
-		tpl:       tpl,
-		ctx:       []reflect.Value{reflect.ValueOf(ctx)},
-		dataFrame: frame,
-		exprFunc:  make(map[*ast.Expression]bool),
+	if escaper == nil {
+		escaper = tpl.getEscaper()
+	}
+
+	v := visitorPool.Get().(*evalVisitor)
+
+	*v = evalVisitor{
+		tpl:            tpl,
+		out:            newOutput(w),
+		execCtx:        execCtx,
+		execDone:       execCtx.Done(),
+		strict:         tpl.strictMode(),
+		compat:         tpl.compatMode(),
+		trackIds:       tpl.trackIdsMode(),
+		stringParams:   tpl.stringParamsMode(),
+		lambdas:        tpl.mustacheLambdasMode(),
+		knownHelpers:   tpl.knownHelpers,
+		escapeContexts: tpl.escapeContexts,
+		escaper:        escaper,
+		logger:         tpl.getLogger(),
+		ctx:            append(v.ctx[:0], reflect.ValueOf(ctx)),
+		dataFrame:      frame,
+		blocks:         v.blocks[:0],
+		exprs:          v.exprs[:0],
+		exprFunc:       v.exprFunc,
+		compiled:       tpl.compiled,
+		generation:     v.generation,
 	}
+
+	return v
+}
+
+// visitorPool holds evaluation visitors, reused across template executions with their stacks
+var visitorPool = sync.Pool{
+	New: func() interface{} {
+		return new(evalVisitor)
+	},
+}
+
+// release puts back visitor in pool, once evaluation is done
+//
+// Stacks are emptied so that they don't retain any value, but are kept allocated for next evaluation.
+func (v *evalVisitor) release() {
+	ctx := v.ctx[:cap(v.ctx)]
+	for i := range ctx {
+		ctx[i] = zero
+	}
+
+	blocks := v.blocks[:cap(v.blocks)]
+	for i := range blocks {
+		blocks[i] = nil
+	}
+
+	exprs := v.exprs[:cap(v.exprs)]
+	for i := range exprs {
+		exprs[i] = exprFrame{}
+	}
+
+	for node := range v.exprFunc {
+		delete(v.exprFunc, node)
+	}
+
+	*v = evalVisitor{
+		ctx:        ctx[:0],
+		blocks:     blocks[:0],
+		exprs:      exprs[:0],
+		exprFunc:   v.exprFunc,
+		generation: v.generation + 1,
+	}
+
+	visitorPool.Put(v)
 }
 
 // at sets current node
@@ -66,6 +182,115 @@ func (v *evalVisitor) at(node ast.Node) {
 	v.curNode = node
 }
 
+//
+// Output
+//
+
+// stringWriter adapts an io.Writer to the writer interface
+type stringWriter struct {
+	w io.Writer
+}
+
+// WriteString writes given string to underlying writer
+func (sw stringWriter) WriteString(s string) (int, error) {
+	return sw.w.Write([]byte(s))
+}
+
+// newOutput returns a writer that writes to given io.Writer
+func newOutput(w io.Writer) writer {
+	if sw, ok := w.(writer); ok {
+		return sw
+	}
+
+	return stringWriter{w}
+}
+
+// write writes given string to output
+func (v *evalVisitor) write(str string) {
+	if str == "" {
+		return
+	}
+
+	if _, err := v.out.WriteString(str); err != nil {
+		// abort evaluation, and report that write error as is
+		panic(err)
+	}
+}
+
+// writeEscaped writes given string to output, escaped with current escaper
+func (v *evalVisitor) writeEscaped(str string) {
+	if v.escaper != nil {
+		v.write(v.escaper(str))
+		return
+	}
+
+	if err := escape(v.out, str); err != nil {
+		// abort evaluation, and report that write error as is
+		panic(err)
+	}
+}
+
+// capture calls given function with a temporary output, and returns everything that was written to it
+func (v *evalVisitor) capture(fn func()) string {
+	out := v.out
+
+	buf := getBuffer()
+	v.out = buf
+
+	defer func() {
+		v.out = out
+		putBuffer(buf)
+	}()
+
+	fn()
+
+	return buf.String()
+}
+
+// maxPooledBufferSize is the maximum capacity of a buffer put back in pool, so that a huge output does not stay in memory
+const maxPooledBufferSize = 64 * 1024
+
+// bufferPool holds output buffers, reused across captures and template executions
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns an empty buffer from pool
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer puts back given buffer in pool
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+//
+// Execution context
+//
+
+// checkDone aborts evaluation if execution context is done
+func (v *evalVisitor) checkDone() {
+	if v.execDone == nil {
+		// context can never be canceled
+		return
+	}
+
+	select {
+	case <-v.execDone:
+		// abort evaluation, and report context error as is
+		panic(v.execCtx.Err())
+	default:
+	}
+}
+
 //
 // Contexts stack
 //
@@ -185,13 +410,72 @@ func (v *evalVisitor) curBlock() *ast.BlockStatement {
 	return v.blocks[len(v.blocks)-1]
 }
 
+//
+// Inline partials stack
+//
+
+// pushPartials pushes new inline partials to stack
+func (v *evalVisitor) pushPartials(partials map[string]*partial) {
+	v.partials = append(v.partials, partials)
+}
+
+// popPartials pops last inline partials from stack
+func (v *evalVisitor) popPartials() map[string]*partial {
+	if len(v.partials) == 0 {
+		return nil
+	}
+
+	var result map[string]*partial
+	result, v.partials = v.partials[len(v.partials)-1], v.partials[:len(v.partials)-1]
+
+	return result
+}
+
+// inlinePartial iterates on stack to find given inline partial, and returns nil if not found
+func (v *evalVisitor) inlinePartial(name string) *partial {
+	for i := len(v.partials) - 1; i >= 0; i-- {
+		if p := v.partials[i][name]; p != nil {
+			return p
+		}
+	}
+
+	return nil
+}
+
+//
+// Partial blocks stack
+//
+
+// pushPartialBlock pushes new partial block to stack
+func (v *evalVisitor) pushPartialBlock(block *Template) {
+	v.partialBlocks = append(v.partialBlocks, block)
+}
+
+// popPartialBlock pops last partial block from stack
+func (v *evalVisitor) popPartialBlock() *Template {
+	if len(v.partialBlocks) == 0 {
+		return nil
+	}
+
+	var result *Template
+	result, v.partialBlocks = v.partialBlocks[len(v.partialBlocks)-1], v.partialBlocks[:len(v.partialBlocks)-1]
+
+	return result
+}
+
 //
 // Expressions stack
 //
 
-// pushExpr pushes new expression to stack
-func (v *evalVisitor) pushExpr(expression *ast.Expression) {
-	v.exprs = append(v.exprs, expression)
+// exprFrame represents an expression being evaluated
+type exprFrame struct {
+	node     *ast.Expression
+	compiled *compiledExpression // nil if expression is not compiled
+}
+
+// pushExpr pushes new expression to stack, with its compiled form if any
+func (v *evalVisitor) pushExpr(expression *ast.Expression, compiled *compiledExpression) {
+	v.exprs = append(v.exprs, exprFrame{expression, compiled})
 }
 
 // popExpr pops last expression from stack
@@ -200,10 +484,10 @@ func (v *evalVisitor) popExpr() *ast.Expression {
 		return nil
 	}
 
-	var result *ast.Expression
+	var result exprFrame
 	result, v.exprs = v.exprs[len(v.exprs)-1], v.exprs[:len(v.exprs)-1]
 
-	return result
+	return result.node
 }
 
 // curExpr returns current expression
@@ -212,7 +496,17 @@ func (v *evalVisitor) curExpr() *ast.Expression {
 		return nil
 	}
 
-	return v.exprs[len(v.exprs)-1]
+	return v.exprs[len(v.exprs)-1].node
+}
+
+// curExprOptions computes helper options argument from current expression
+func (v *evalVisitor) curExprOptions() *Options {
+	frame := v.exprs[len(v.exprs)-1]
+	if frame.compiled != nil {
+		return frame.compiled.options(v)
+	}
+
+	return v.helperOptions(frame.node)
 }
 
 //
@@ -229,21 +523,66 @@ func (v *evalVisitor) errorf(format string, args ...interface{}) {
 	v.errPanic(fmt.Errorf(format, args...))
 }
 
+//
+// Missing fields
+//
+
+// missingField is called when given path expression could not be entirely resolved, and handles it depending on strict mode
+func (v *evalVisitor) missingField(node *ast.PathExpression, resolved int, exprRoot bool) {
+	// is it missing an object that was traversed by path ?
+	missingObject := resolved < len(node.Parts)-1
+
+	required := false
+
+	switch v.strict {
+	case StrictOn, StrictWarn:
+		// a helper parameter can be missing, so that {{#if foo}} works, and a missing helper is handled by helperMissing
+		required = (exprRoot && !v.isHelperCallCandidate(v.curExpr())) || missingObject
+	case StrictAssumeObjects:
+		required = missingObject
+	}
+
+	if !required {
+		return
+	}
+
+	err := &MissingFieldError{
+		Path: node.Original,
+		Line: node.Line,
+		Pos:  node.Pos,
+	}
+
+	if v.strict == StrictWarn {
+		v.warnings = append(v.warnings, err)
+		return
+	}
+
+	// abort evaluation
+	panic(err)
+}
+
 //
 // Evaluation
 //
 
-// evalProgram eEvaluates program with given context and returns string result
+// evalProgram evaluates program with given context and returns string result
 func (v *evalVisitor) evalProgram(program *ast.Program, ctx interface{}, data *DataFrame, key interface{}) string {
-	blockParams := make(map[string]interface{})
+	return v.capture(func() {
+		v.writeProgram(program, ctx, data, key)
+	})
+}
+
+// writeProgram evaluates program with given context and writes result to output
+func (v *evalVisitor) writeProgram(program *ast.Program, ctx interface{}, data *DataFrame, key interface{}) {
+	var blockParams map[string]interface{}
 
 	// compute block params
 	if len(program.BlockParams) > 0 {
-		blockParams[program.BlockParams[0]] = ctx
-	}
+		blockParams = map[string]interface{}{program.BlockParams[0]: ctx}
 
-	if (len(program.BlockParams) > 1) && (key != nil) {
-		blockParams[program.BlockParams[1]] = key
+		if (len(program.BlockParams) > 1) && (key != nil) {
+			blockParams[program.BlockParams[1]] = key
+		}
 	}
 
 	// push contexts
@@ -261,7 +600,7 @@ func (v *evalVisitor) evalProgram(program *ast.Program, ctx interface{}, data *D
 	}
 
 	// evaluate program
-	result, _ := program.Accept(v).(string)
+	program.Accept(v)
 
 	// pop contexts
 	if data != nil {
@@ -275,36 +614,33 @@ func (v *evalVisitor) evalProgram(program *ast.Program, ctx interface{}, data *D
 	if len(blockParams) > 0 {
 		v.popBlockParams()
 	}
-
-	return result
 }
 
-// evalPath evaluates all path parts with given context
-func (v *evalVisitor) evalPath(ctx reflect.Value, parts []string, exprRoot bool) (reflect.Value, bool) {
-	partResolved := false
+// evalPath evaluates all path parts with given context, and returns the number of resolved parts
+func (v *evalVisitor) evalPath(ctx reflect.Value, parts []pathPart, exprRoot bool) (reflect.Value, int) {
+	resolved := 0
 
 	for i := 0; i < len(parts); i++ {
-		part := parts[i]
-
-		// "[foo bar]"" => "foo bar"
-		if (len(part) >= 2) && (part[0] == '[') && (part[len(part)-1] == ']') {
-			part = part[1 : len(part)-1]
-		}
-
-		ctx = v.evalField(ctx, part, exprRoot)
+		ctx = v.evalPathPart(ctx, parts[i], exprRoot)
 		if !ctx.IsValid() {
 			break
 		}
 
-		// we resolved at least one part of path
-		partResolved = true
+		// we resolved one more part of path
+		resolved++
 	}
 
-	return ctx, partResolved
+	return ctx, resolved
 }
 
 // evalField evaluates field with given context
 func (v *evalVisitor) evalField(ctx reflect.Value, fieldName string, exprRoot bool) reflect.Value {
+	return v.evalPathPart(ctx, pathPart{name: fieldName}, exprRoot)
+}
+
+// evalPathPart evaluates path part with given context
+func (v *evalVisitor) evalPathPart(ctx reflect.Value, part pathPart, exprRoot bool) reflect.Value {
+	fieldName := part.name
 	result := zero
 
 	ctx, _ = indirect(ctx)
@@ -312,38 +648,20 @@ func (v *evalVisitor) evalField(ctx reflect.Value, fieldName string, exprRoot bo
 		return result
 	}
 
-	// check if this is a method call
-	result, isMeth := v.evalMethod(ctx, fieldName, exprRoot)
-	if !isMeth {
-		switch ctx.Kind() {
-		case reflect.Struct:
-			// example: firstName => FirstName
-			expFieldName := strings.Title(fieldName)
-
-			// check if struct have this field and that it is exported
-			if tField, ok := ctx.Type().FieldByName(expFieldName); ok && (tField.PkgPath == "") {
-				// struct field
-				result = ctx.FieldByIndex(tField.Index)
-				break
-			}
-
-			// attempts to find template variable name as a struct tag
-			result = v.evalStructTag(ctx, fieldName)
-		case reflect.Map:
-			nameVal := reflect.ValueOf(fieldName)
-			if nameVal.Type().AssignableTo(ctx.Type().Key()) {
-				// map key
-				result = ctx.MapIndex(nameVal)
-			}
-		case reflect.Array, reflect.Slice:
-			if i, err := strconv.Atoi(fieldName); (err == nil) && (i < ctx.Len()) {
-				result = ctx.Index(i)
-			}
-		}
+	if (ctx.Type() == mapType) && ctx.CanInterface() {
+		// map key, without reflection
+		val, ok := ctx.Interface().(map[string]interface{})[fieldName]
+		result = mapValue(val, ok)
+	} else {
+		result = v.evalMember(ctx, part, exprRoot)
 	}
 
-	// check if result is a function
-	result, _ = indirect(result)
+	return v.evalFieldValue(fieldName, result, exprRoot)
+}
+
+// evalFieldValue returns given field value, or the result of its call if it is a function
+func (v *evalVisitor) evalFieldValue(fieldName string, value reflect.Value, exprRoot bool) reflect.Value {
+	result, _ := indirect(value)
 	if result.Kind() == reflect.Func {
 		result = v.evalFieldFunc(fieldName, result, exprRoot)
 	}
@@ -351,61 +669,153 @@ func (v *evalVisitor) evalField(ctx reflect.Value, fieldName string, exprRoot bo
 	return result
 }
 
-// evalFieldFunc tries to evaluate given method name, and a boolean to indicate if this was a method call
-func (v *evalVisitor) evalMethod(ctx reflect.Value, name string, exprRoot bool) (reflect.Value, bool) {
-	if ctx.Kind() != reflect.Interface && ctx.CanAddr() {
-		ctx = ctx.Addr()
+// mapValue returns the reflect.Value of given value found in a map[string]interface{}, or zero if it was not found
+func mapValue(val interface{}, found bool) reflect.Value {
+	switch {
+	case !found:
+		return zero
+	case val == nil:
+		// a nil value is found, so that parent contexts are not checked
+		return reflect.Zero(mapType.Elem())
+	default:
+		return reflect.ValueOf(val)
 	}
+}
+
+// evalMember evaluates the method, struct field, map key or array index of given context, with given path part
+func (v *evalVisitor) evalMember(ctx reflect.Value, part pathPart, exprRoot bool) reflect.Value {
+	kind := ctx.Kind()
+
+	var m *member
+	if (kind == reflect.Struct) || (kind == reflect.Interface) || (ctx.Type().Name() != "") {
+		// only those types can have methods
+		m = typeMembersOf(ctx.Type()).get(part)
 
-	method := ctx.MethodByName(name)
-	if !method.IsValid() {
-		// example: subject() => Subject()
-		method = ctx.MethodByName(strings.Title(name))
+		// check if this is a method call
+		if (kind != reflect.Interface) && ctx.CanAddr() {
+			if m.ptrMethod >= 0 {
+				return v.evalFieldFunc(part.name, ctx.Addr().Method(m.ptrMethod), exprRoot)
+			}
+		} else if m.method >= 0 {
+			return v.evalFieldFunc(part.name, ctx.Method(m.method), exprRoot)
+		}
 	}
 
-	if !method.IsValid() {
-		return zero, false
+	switch kind {
+	case reflect.Struct:
+		switch {
+		case m.field != nil:
+			// example: firstName => FirstName
+			return ctx.FieldByIndex(m.field)
+		case m.tag < 0:
+			return zero
+		case !ctx.CanAddr():
+			return ctx.Field(m.tag)
+		case !m.tagCopy:
+			// the value found with a struct tag is not addressable
+			return reflect.ValueOf(ctx.Field(m.tag).Interface())
+		default:
+			return reflect.ValueOf(ctx.Interface()).Field(m.tag)
+		}
+	case reflect.Map:
+		nameVal := part.key()
+		if nameVal.Type().AssignableTo(ctx.Type().Key()) {
+			// map key
+			return ctx.MapIndex(nameVal)
+		}
+	case reflect.Array, reflect.Slice:
+		if i, err := strconv.Atoi(part.name); (err == nil) && (i < ctx.Len()) {
+			return ctx.Index(i)
+		}
 	}
 
-	return v.evalFieldFunc(name, method, exprRoot), true
+	return zero
 }
 
 // evalFieldFunc evaluates given function
 func (v *evalVisitor) evalFieldFunc(name string, funcVal reflect.Value, exprRoot bool) reflect.Value {
 	ensureValidHelper(name, funcVal)
 
+	if exprRoot && v.lambdas {
+		if result, ok := v.evalMustacheLambda(funcVal); ok {
+			return result
+		}
+	}
+
 	var options *Options
 	if exprRoot {
 		// create function arg with all params/hash
-		expr := v.curExpr()
-		options = v.helperOptions(expr)
+		options = v.curExprOptions()
 
 		// ok, that expression was a function call
-		v.exprFunc[expr] = true
+		v.setFuncCall(v.curExpr())
 	} else {
 		// we are not at root of expression, so we are a parameter... and we don't like
 		// infinite loops caused by trying to parse ourself forever
 		options = newEmptyOptions(v)
 	}
 
+	options.name = name
+
 	return v.callFunc(name, funcVal, options)
 }
 
-// evalStructTag checks for the existence of a struct tag containing the
-// name of the variable in the template. This allows for a template variable to
-// be separated from the field in the struct.
-func (v *evalVisitor) evalStructTag(ctx reflect.Value, name string) reflect.Value {
-	val := reflect.ValueOf(ctx.Interface())
+// evalMustacheLambda evaluates given function as a mustache lambda, and a boolean to indicate if function is a mustache lambda
+func (v *evalVisitor) evalMustacheLambda(funcVal reflect.Value) (reflect.Value, bool) {
+	expr := v.curExpr()
+	if (len(expr.Params) > 0) || (expr.Hash != nil) {
+		return zero, false
+	}
+
+	funcType := funcVal.Type()
+
+	if block := v.curBlock(); (block != nil) && (block.Expression == expr) {
+		// section lambda: {{#lambda}}...{{/lambda}}
+		if (funcType.NumIn() != 1) || (funcType.In(0).Kind() != reflect.String) {
+			return zero, false
+		}
+
+		v.setFuncCall(expr)
 
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Type().Field(i)
-		tag := field.Tag.Get("handlebars")
-		if tag == name {
-			return val.Field(i)
+		if block.Program == nil {
+			// lambdas are truthy, so inverted section is not rendered
+			return reflect.ValueOf(""), true
 		}
+
+		arg := reflect.ValueOf(block.Source).Convert(funcType.In(0))
+		source := Str(funcVal.Call([]reflect.Value{arg})[0].Interface())
+
+		return reflect.ValueOf(v.renderLambda(source, block.OpenDelimiter, block.CloseDelimiter)), true
 	}
 
-	return zero
+	if (v.curMustache != nil) && (v.curMustache.Expression == expr) && (funcType.NumIn() == 0) {
+		// interpolation lambda: {{lambda}}
+		v.setFuncCall(expr)
+
+		source := Str(funcVal.Call(nil)[0].Interface())
+
+		return reflect.ValueOf(v.renderLambda(source, "", "")), true
+	}
+
+	return zero, false
+}
+
+// renderLambda renders given template source returned by a mustache lambda in current context, with given delimiters,
+// or with template delimiters if they are empty
+func (v *evalVisitor) renderLambda(source string, openDelim string, closeDelim string) string {
+	options := v.tpl.parserOptions()
+	if openDelim != "" {
+		options.OpenDelimiter, options.CloseDelimiter = openDelim, closeDelim
+	}
+
+	program, err := parser.ParseWithOptions(source, options)
+	if err != nil {
+		v.errorf("Failed to parse mustache lambda result: %s", err)
+	}
+
+	return v.capture(func() {
+		v.writePartial(&Template{program: program}, zero, "", nil)
+	})
 }
 
 // findBlockParam returns node's block parameter
@@ -420,9 +830,10 @@ func (v *evalVisitor) findBlockParam(node *ast.PathExpression) (string, interfac
 	return "", nil
 }
 
-// evalPathExpression evaluates a path expression
-func (v *evalVisitor) evalPathExpression(node *ast.PathExpression, exprRoot bool) interface{} {
+// evalPathExpression evaluates a path expression, whose parts are given
+func (v *evalVisitor) evalPathExpression(node *ast.PathExpression, parts []pathPart, exprRoot bool) interface{} {
 	var result interface{}
+	resolved := 0
 
 	if name, value := v.findBlockParam(node); value != nil {
 		// block parameter value
@@ -439,14 +850,14 @@ func (v *evalVisitor) evalPathExpression(node *ast.PathExpression, exprRoot bool
 		newCtx := map[string]interface{}{name: value}
 
 		v.pushCtx(reflect.ValueOf(newCtx))
-		result = v.evalCtxPathExpression(node, exprRoot)
+		result, resolved = v.evalCtxPathExpression(node, parts, exprRoot)
 		v.popCtx()
 	} else {
 		ctxTried := false
 
 		if node.IsDataRoot() {
 			// context path
-			result = v.evalCtxPathExpression(node, exprRoot)
+			result, resolved = v.evalCtxPathExpression(node, parts, exprRoot)
 
 			ctxTried = true
 		}
@@ -456,77 +867,143 @@ func (v *evalVisitor) evalPathExpression(node *ast.PathExpression, exprRoot bool
 			// so let's try with private data
 
 			// private data
-			result = v.evalDataPathExpression(node, exprRoot)
+			var n int
+			if result, n = v.evalDataPathExpression(node, parts, exprRoot); n > resolved {
+				resolved = n
+			}
 		}
 
 		if (result == nil) && !ctxTried {
 			// context path
-			result = v.evalCtxPathExpression(node, exprRoot)
+			var n int
+			if result, n = v.evalCtxPathExpression(node, parts, exprRoot); n > resolved {
+				resolved = n
+			}
 		}
 	}
 
+	if (result == nil) && (resolved < len(node.Parts)) {
+		v.missingField(node, resolved, exprRoot)
+	}
+
 	return result
 }
 
-// evalDataPathExpression evaluates a private data path expression
-func (v *evalVisitor) evalDataPathExpression(node *ast.PathExpression, exprRoot bool) interface{} {
+// evalDataPathExpression evaluates a private data path expression, and returns the number of resolved parts
+func (v *evalVisitor) evalDataPathExpression(node *ast.PathExpression, parts []pathPart, exprRoot bool) (interface{}, int) {
 	// find data frame
 	frame := v.dataFrame
 	for i := node.Depth; i > 0; i-- {
 		if frame.parent == nil {
-			return nil
+			return nil, 0
 		}
 		frame = frame.parent
 	}
 
-	// resolve data
-	// @note Can be changed to v.evalCtx() as context can't be an array
-	result, _ := v.evalCtxPath(reflect.ValueOf(frame.data), node.Parts, exprRoot)
-	return result
+	if len(parts) == 0 {
+		return frame.values(), 0
+	}
+
+	// resolve data, then remaining parts with found value
+	val, found := frame.lookup(parts[0].name)
+
+	ctx := v.evalFieldValue(parts[0].name, mapValue(val, found), exprRoot)
+	if !ctx.IsValid() {
+		return nil, 0
+	}
+
+	// @note Data can't be an array context
+	ctx, resolved := v.evalPath(ctx, parts[1:], exprRoot)
+	if !ctx.IsValid() {
+		return nil, resolved + 1
+	}
+
+	return ctx.Interface(), resolved + 1
 }
 
-// evalCtxPathExpression evaluates a context path expression
-func (v *evalVisitor) evalCtxPathExpression(node *ast.PathExpression, exprRoot bool) interface{} {
+// evalCtxPathExpression evaluates a context path expression, and returns the number of resolved parts
+func (v *evalVisitor) evalCtxPathExpression(node *ast.PathExpression, parts []pathPart, exprRoot bool) (interface{}, int) {
 	v.at(node)
 
 	if node.IsDataRoot() {
 		// `@root` - remove the first part
-		parts := node.Parts[1:len(node.Parts)]
-
-		result, _ := v.evalCtxPath(v.rootCtx(), parts, exprRoot)
-		return result
+		result, resolved := v.evalCtxPath(v.rootCtx(), parts[1:], exprRoot)
+		return result, resolved + 1
 	}
 
-	return v.evalDepthPath(node.Depth, node.Parts, exprRoot)
+	return v.evalDepthPath(node.Depth, parts, exprRoot)
 }
 
 // evalDepthPath iterates on contexts, starting at given depth, until there is one that resolve given path parts
-func (v *evalVisitor) evalDepthPath(depth int, parts []string, exprRoot bool) interface{} {
+func (v *evalVisitor) evalDepthPath(depth int, parts []pathPart, exprRoot bool) (interface{}, int) {
+	if v.compat && (len(parts) > 0) {
+		return v.evalCompatPath(depth, parts, exprRoot)
+	}
+
 	var result interface{}
-	partResolved := false
+	resolved := 0
 
 	ctx := v.ancestorCtx(depth)
 
-	for (result == nil) && ctx.IsValid() && (depth <= len(v.ctx) && !partResolved) {
+	for (result == nil) && ctx.IsValid() && (depth <= len(v.ctx)) {
 		// try with context
-		result, partResolved = v.evalCtxPath(ctx, parts, exprRoot)
+		res, n := v.evalCtxPath(ctx, parts, exprRoot)
+		result = res
+		if n > resolved {
+			resolved = n
+		}
 
 		// As soon as we find the first part of a path, we must not try to resolve with parent context if result is finally `nil`
 		// Reference: "Dotted Names - Context Precedence" mustache test
-		if !partResolved && (result == nil) {
+		if (result == nil) && (n == 0) {
 			// try with previous context
 			depth++
 			ctx = v.ancestorCtx(depth)
+		} else {
+			break
 		}
 	}
 
-	return result
+	return result, resolved
+}
+
+// evalCompatPath iterates on contexts, starting at given depth, until there is one where the first path part is not
+// nil, and resolves the remaining parts in that context only
+//
+// This is the depthed lookup of the handlebars.js compat mode: unlike the default lookup, a first part with a nil
+// value is also looked up in parent contexts.
+func (v *evalVisitor) evalCompatPath(depth int, parts []pathPart, exprRoot bool) (interface{}, int) {
+	for ; depth < len(v.ctx); depth++ {
+		ctx := v.ancestorCtx(depth)
+
+		if kind := ctx.Kind(); (kind == reflect.Array) || (kind == reflect.Slice) {
+			// array context
+			if result, n := v.evalCtxPath(ctx, parts, exprRoot); (result != nil) || (n > 0) {
+				return result, n
+			}
+			continue
+		}
+
+		head := v.evalPathPart(ctx, parts[0], exprRoot)
+		if _, isNil := indirect(head); !head.IsValid() || isNil {
+			continue
+		}
+
+		value, n := v.evalPath(head, parts[1:], exprRoot)
+		if !value.IsValid() {
+			return nil, n + 1
+		}
+
+		return value.Interface(), n + 1
+	}
+
+	return nil, 0
 }
 
-// evalCtxPath evaluates path with given context
-func (v *evalVisitor) evalCtxPath(ctx reflect.Value, parts []string, exprRoot bool) (interface{}, bool) {
+// evalCtxPath evaluates path with given context, and returns the number of resolved parts
+func (v *evalVisitor) evalCtxPath(ctx reflect.Value, parts []pathPart, exprRoot bool) (interface{}, int) {
 	var result interface{}
-	partResolved := false
+	resolved := 0
 
 	switch ctx.Kind() {
 	case reflect.Array, reflect.Slice:
@@ -545,13 +1022,13 @@ func (v *evalVisitor) evalCtxPath(ctx reflect.Value, parts []string, exprRoot bo
 		// NOT array context
 		var value reflect.Value
 
-		value, partResolved = v.evalPath(ctx, parts, exprRoot)
+		value, resolved = v.evalPath(ctx, parts, exprRoot)
 		if value.IsValid() {
 			result = value.Interface()
 		}
 	}
 
-	return result, partResolved
+	return result, resolved
 }
 
 //
@@ -568,6 +1045,11 @@ func (v *evalVisitor) isHelperCall(node *ast.Expression) bool {
 
 // findHelper finds given helper
 func (v *evalVisitor) findHelper(name string) reflect.Value {
+	if (v.knownHelpers != nil) && !v.knownHelpers[name] {
+		// knownHelpersOnly mode: no need to look for an unknown helper
+		return zero
+	}
+
 	// check template helpers
 	if h := v.tpl.findHelper(name); h != zero {
 		return h
@@ -583,6 +1065,11 @@ func (v *evalVisitor) callFunc(name string, funcVal reflect.Value, options *Opti
 
 	funcType := funcVal.Type()
 
+	if variadicHelpers[name] && (funcType.NumIn() == 1) && (funcType.In(0) == reflect.TypeOf(options)) {
+		// helper only takes an options argument, so any parameters are available with Options.Params()
+		return funcVal.Call([]reflect.Value{reflect.ValueOf(options)})[0]
+	}
+
 	// @todo Is there a better way to do that ?
 	strType := reflect.TypeOf("")
 	boolType := reflect.TypeOf(true)
@@ -644,9 +1131,15 @@ func (v *evalVisitor) callFunc(name string, funcVal reflect.Value, options *Opti
 	return result[0]
 }
 
-// callHelper invoqs helper function for given expression node
-func (v *evalVisitor) callHelper(name string, helper reflect.Value, node *ast.Expression) interface{} {
-	result := v.callFunc(name, helper, v.helperOptions(node))
+// callHelper invoqs helper function with given options
+func (v *evalVisitor) callHelper(name string, helper reflect.Value, options *Options) interface{} {
+	options.name = name
+
+	if result, ok := callHelperDirect(helper, options); ok {
+		return result
+	}
+
+	result := v.callFunc(name, helper, options)
 	if !result.IsValid() {
 		return nil
 	}
@@ -655,87 +1148,431 @@ func (v *evalVisitor) callHelper(name string, helper reflect.Value, node *ast.Ex
 	return result.Interface()
 }
 
-// helperOptions computes helper options argument from an expression
-func (v *evalVisitor) helperOptions(node *ast.Expression) *Options {
-	var params []interface{}
-	var hash map[string]interface{}
-
-	for _, paramNode := range node.Params {
-		param := paramNode.Accept(v)
-		params = append(params, param)
+// callHelperDirect calls helpers with the most common signatures, like builtin helpers, without reflection
+//
+// It returns false if helper signature is not handled, or if it does not match the number of parameters.
+func callHelperDirect(helper reflect.Value, options *Options) (interface{}, bool) {
+	params := options.params
+
+	switch fn := helper.Interface().(type) {
+	case func(*Options) interface{}:
+		if (len(params) == 0) || variadicHelpers[options.name] {
+			return fn(options), true
+		}
+	case func(*Options) string:
+		if (len(params) == 0) || variadicHelpers[options.name] {
+			return fn(options), true
+		}
+	case func(interface{}, *Options) interface{}:
+		if len(params) == 1 {
+			return fn(params[0], options), true
+		}
+	case func(interface{}, interface{}, *Options) interface{}:
+		if len(params) == 2 {
+			return fn(params[0], params[1], options), true
+		}
 	}
 
-	if node.Hash != nil {
-		hash, _ = node.Hash.Accept(v).(map[string]interface{})
-	}
+	return nil, false
+}
 
-	return newOptions(v, params, hash)
+// isHelperCallCandidate returns true if given expression has parameters or hash, so it must be a helper call
+func (v *evalVisitor) isHelperCallCandidate(node *ast.Expression) bool {
+	return (node != nil) && (node.HelperName() != "") && ((len(node.Params) > 0) || (node.Hash != nil))
 }
 
-//
-// Partials
-//
+// isHelperMissingCall returns true if given expression, whose value could not be resolved, must be handled by helperMissing
+func (v *evalVisitor) isHelperMissingCall(node *ast.Expression) bool {
+	if v.isHelperCallCandidate(node) {
+		// eg: {{foo bar}}
+		return true
+	}
 
-// findPartial finds given partial
-func (v *evalVisitor) findPartial(name string) *partial {
-	// check template partials
-	if p := v.tpl.findPartial(name); p != nil {
-		return p
+	if v.knownHelpers != nil {
+		// knownHelpersOnly mode: an ambiguous expression is a field lookup
+		return false
 	}
 
-	// check global partials
-	return findPartial(name)
+	// an ambiguous block expression is handled by blockHelperMissing
+	// eg: {{#foo}}{{/foo}}
+	block := v.curBlock()
+
+	return (block == nil) || (block.Expression != node)
+}
+
+// helperMissing evaluates an expression that looks like a call to given helper, that could not be resolved, with given options
+func (v *evalVisitor) helperMissing(name string, node *ast.Expression, options *Options) interface{} {
+	options.name = name
+
+	// that expression is now a function call
+	v.setFuncCall(node)
+
+	if helper := v.findHelper(helperMissingName); helper != zero {
+		return v.callMissingHelper(helperMissingName, helper, options)
+	}
+
+	// default behaviour
+	if len(options.params) > 0 {
+		v.errorf("Missing helper: %q", name)
+	}
+
+	return nil
+}
+
+// callMissingHelper calls a custom helperMissing or blockHelperMissing helper
+//
+// If that helper only takes an options argument, then parameters are only available with Options.Params().
+func (v *evalVisitor) callMissingHelper(name string, helper reflect.Value, options *Options) interface{} {
+	result := v.callFunc(name, helper, options)
+	if !result.IsValid() {
+		return nil
+	}
+
+	return result.Interface()
+}
+
+// helperOptions computes helper options argument from an expression
+func (v *evalVisitor) helperOptions(node *ast.Expression) *Options {
+	var result *Options
+
+	if v.stringParams {
+		result = v.stringParamsOptions(node)
+	} else {
+		var params []interface{}
+		var hash map[string]interface{}
+
+		for _, paramNode := range node.Params {
+			param := paramNode.Accept(v)
+			params = append(params, param)
+		}
+
+		if node.Hash != nil {
+			hash, _ = node.Hash.Accept(v).(map[string]interface{})
+		}
+
+		result = newOptions(v, params, hash)
+	}
+
+	if v.trackIds {
+		result.ids, result.hashIds = paramIds(node)
+	}
+
+	return result
+}
+
+// stringParamsOptions computes helper options argument from an expression in stringParams mode
+func (v *evalVisitor) stringParamsOptions(node *ast.Expression) *Options {
+	result := newOptions(v, make([]interface{}, len(node.Params)), make(map[string]interface{}))
+
+	result.types = make([]ParamType, len(node.Params))
+	result.contexts = make([]interface{}, len(node.Params))
+
+	for i, param := range node.Params {
+		result.params[i], result.types[i], result.contexts[i] = v.stringParam(param)
+	}
+
+	result.hashTypes = make(map[string]ParamType)
+	result.hashContexts = make(map[string]interface{})
+
+	if node.Hash != nil {
+		for _, pair := range node.Hash.Pairs {
+			result.hash[pair.Key], result.hashTypes[pair.Key], result.hashContexts[pair.Key] = v.stringParam(pair.Val)
+		}
+	}
+
+	return result
+}
+
+// stringParam returns the value, the type and the context of given param in stringParams mode
+//
+// A path is not evaluated, its value is the path itself, and its context is the context it would be resolved in.
+func (v *evalVisitor) stringParam(node ast.Node) (interface{}, ParamType, interface{}) {
+	var value interface{}
+	var paramType ParamType
+
+	ctx := v.curCtx()
+
+	switch n := node.(type) {
+	case *ast.PathExpression:
+		value = stringParamPath(n.Original)
+		paramType = ParamTypeID
+		ctx = v.ancestorCtx(n.Depth)
+	case *ast.StringLiteral:
+		value, paramType = node.Accept(v), ParamTypeString
+	case *ast.NumberLiteral:
+		value, paramType = node.Accept(v), ParamTypeNumber
+	case *ast.BooleanLiteral:
+		value, paramType = node.Accept(v), ParamTypeBoolean
+	case *ast.SubExpression:
+		value, paramType = node.Accept(v), ParamTypeSubExpression
+	default:
+		v.errorf("Unexpected param node: %s", node)
+	}
+
+	if !ctx.IsValid() {
+		return value, paramType, nil
+	}
+
+	return value, paramType, ctx.Interface()
+}
+
+// stringParamPath returns given path original without leading `../` and `./`, and with `.` separators
+func stringParamPath(original string) string {
+	for {
+		if strings.HasPrefix(original, "../") {
+			original = original[len("../"):]
+		} else if strings.HasPrefix(original, "./") {
+			original = original[len("./"):]
+		} else {
+			break
+		}
+	}
+
+	return strings.Replace(original, "/", ".", -1)
+}
+
+// paramIds returns the ids of given expression params and hash values
+func paramIds(node *ast.Expression) ([]string, map[string]string) {
+	ids := make([]string, len(node.Params))
+	for i, param := range node.Params {
+		ids[i] = nodeID(param)
+	}
+
+	hashIds := make(map[string]string)
+	if node.Hash != nil {
+		for _, pair := range node.Hash.Pairs {
+			hashIds[pair.Key] = nodeID(pair.Val)
+		}
+	}
+
+	return ids, hashIds
+}
+
+// nodeID returns the path used to resolve given node relatively to current context in trackIds mode, or an empty string if
+// that node is not a path
+func nodeID(node ast.Node) string {
+	path, ok := node.(*ast.PathExpression)
+	if !ok {
+		return ""
+	}
+
+	id := path.Original
+
+	switch {
+	case id == "this", id == ".":
+		return ""
+	case strings.HasPrefix(id, "this."):
+		return id[len("this."):]
+	case strings.HasPrefix(id, "./"):
+		return id[len("./"):]
+	}
+
+	return id
+}
+
+// appendContextPath returns given context path with given id appended
+func appendContextPath(contextPath string, id string) string {
+	if contextPath == "" {
+		return id
+	}
+
+	if id == "" {
+		return contextPath
+	}
+
+	return contextPath + "." + id
+}
+
+// childContextPath returns the context path of a child context with given id, in trackIds mode
+func (v *evalVisitor) childContextPath(id string) string {
+	return appendContextPath(Str(v.dataFrame.Get(contextPathName)), id)
+}
+
+// contextPathFrame returns a new data frame with @contextPath set to the path of a child context with given id in
+// trackIds mode, or nil if trackIds mode is disabled
+func (v *evalVisitor) contextPathFrame(id string) *DataFrame {
+	if !v.trackIds {
+		return nil
+	}
+
+	result := v.dataFrame.Copy()
+	result.Set(contextPathName, v.childContextPath(id))
+
+	return result
+}
+
+//
+// Partials
+//
+
+// findPartial finds given partial
+func (v *evalVisitor) findPartial(name string) *partial {
+	// check inline partials
+	if p := v.inlinePartial(name); p != nil {
+		return p
+	}
+
+	// check template partials
+	if p := v.tpl.findPartial(name); p != nil {
+		return p
+	}
+
+	// check global partials
+	return findPartial(name)
+}
+
+// partialName evaluates given partial name node
+func (v *evalVisitor) partialName(node ast.Node) string {
+	// partialName: helperName | sexpr
+	name, ok := ast.HelperNameStr(node)
+	if !ok {
+		if subExpr, ok := node.(*ast.SubExpression); ok {
+			name, _ = subExpr.Accept(v).(string)
+		}
+	}
+
+	if name == "" {
+		v.errorf("Unexpected partial name: %q", node)
+	}
+
+	return name
+}
+
+// dynamicName evaluates given mustache dynamic name node
+func (v *evalVisitor) dynamicName(node ast.Node) string {
+	return Str(node.Accept(v))
 }
 
 // partialContext computes partial context
-func (v *evalVisitor) partialContext(node *ast.PartialStatement) reflect.Value {
-	if nb := len(node.Params); nb > 1 {
+func (v *evalVisitor) partialContext(params []ast.Node, hash *ast.Hash) reflect.Value {
+	if nb := len(params); nb > 1 {
 		v.errorf("Unsupported number of partial arguments: %d", nb)
 	}
 
-	if (len(node.Params) > 0) && (node.Hash != nil) {
+	if (len(params) > 0) && (hash != nil) {
 		v.errorf("Passing both context and named parameters to a partial is not allowed")
 	}
 
-	if len(node.Params) == 1 {
-		return reflect.ValueOf(node.Params[0].Accept(v))
+	if len(params) == 1 {
+		return reflect.ValueOf(params[0].Accept(v))
 	}
 
-	if node.Hash != nil {
-		hash, _ := node.Hash.Accept(v).(map[string]interface{})
-		return reflect.ValueOf(hash)
+	if hash != nil {
+		hashVal, _ := hash.Accept(v).(map[string]interface{})
+		return reflect.ValueOf(hashVal)
 	}
 
 	return zero
 }
 
-// evalPartial evaluates a partial
-func (v *evalVisitor) evalPartial(p *partial, node *ast.PartialStatement) string {
-	// get partial template
-	partialTpl, err := p.template()
+// partialTemplate returns parsed template of given partial
+func (v *evalVisitor) partialTemplate(p *partial) *Template {
+	result, err := p.template(v.tpl)
 	if err != nil {
 		v.errPanic(err)
 	}
 
+	return result
+}
+
+// evalPartial evaluates a partial and writes result to output
+func (v *evalVisitor) evalPartial(p *partial, node *ast.PartialStatement) {
+	partialTpl := v.partialTemplate(p)
+
+	v.writePartial(partialTpl, v.partialContext(node.Params, node.Hash), node.Indent, node)
+}
+
+// subTemplate returns a template for given program, that is part of currently evaluated template
+func (v *evalVisitor) subTemplate(program *ast.Program) *Template {
+	return &Template{
+		program:        program,
+		knownHelpers:   v.knownHelpers,
+		escapeContexts: v.escapeContexts,
+		compiled:       v.compiled,
+	}
+}
+
+// writePartial evaluates given partial template, called by given node, with given context, and writes result to output indented with given indent
+func (v *evalVisitor) writePartial(tpl *Template, ctx reflect.Value, indent string, node ast.Node) {
+	// partial template has its own known helpers, escaping contexts and compiled programs
+	knownHelpers, escapeContexts, compiled := v.knownHelpers, v.escapeContexts, v.compiled
+	v.knownHelpers, v.escapeContexts, v.compiled = tpl.knownHelpers, v.partialEscapeContexts(tpl, node), tpl.compiled
+
 	// push partial context
-	ctx := v.partialContext(node)
 	if ctx.IsValid() {
 		v.pushCtx(ctx)
 	}
 
 	// evaluate partial template
-	result, _ := partialTpl.program.Accept(v).(string)
+	if indent == "" {
+		tpl.program.Accept(v)
+	} else {
+		// ident partial
+		v.write(indentLines(v.capture(func() {
+			tpl.program.Accept(v)
+		}), indent))
+	}
 
-	// ident partial
-	result = indentLines(result, node.Indent)
+	v.knownHelpers, v.escapeContexts, v.compiled = knownHelpers, escapeContexts, compiled
 
 	if ctx.IsValid() {
 		v.popCtx()
 	}
+}
+
+// partialEscapeContexts returns the escaping contexts of given partial template, called by given node
+//
+// In contextual escaping mode, the partial is analyzed in the context it is called in.
+func (v *evalVisitor) partialEscapeContexts(tpl *Template, node ast.Node) map[ast.Node]escapeContext {
+	if v.escapeContexts == nil {
+		// contextual escaping is disabled in calling template
+		if (tpl.escapeContexts != nil) && (v.escaper != nil) {
+			v.errPanic(errContextualEscaper)
+		}
+
+		return tpl.escapeContexts
+	}
+
+	start := v.escapeContexts[node]
+
+	var result map[ast.Node]escapeContext
+	var err error
+
+	if analyzed, ok := tpl.escapeContexts[tpl.program]; ok {
+		// the partial is part of an analyzed template, ie. an inline partial, a partial block or a mustache block
+		if analyzed == start {
+			return tpl.escapeContexts
+		}
+
+		result, err = computePartialEscapeContexts(tpl.program, start)
+	} else {
+		result, err = tpl.partialEscapeContexts(start)
+	}
+
+	if err != nil {
+		v.errPanic(err)
+	}
 
 	return result
 }
 
+// evalPartialBlock evaluates current partial block, ie. {{> @partial-block}}, and writes result to output
+func (v *evalVisitor) evalPartialBlock(node *ast.PartialStatement) {
+	if len(v.partialBlocks) == 0 {
+		v.errorf("Partial not found: %s", partialBlockName)
+	}
+
+	blocks := v.partialBlocks
+	block := blocks[len(blocks)-1]
+
+	// a @partial-block inside that block refers to the enclosing partial block
+	v.partialBlocks = blocks[: len(blocks)-1 : len(blocks)-1]
+
+	v.writePartial(block, v.partialContext(node.Params, node.Hash), node.Indent, node)
+
+	v.partialBlocks = blocks
+}
+
 // indentLines indents all lines of given string
 func indentLines(str string, indent string) string {
 	if indent == "" {
@@ -757,6 +1594,101 @@ func indentLines(str string, indent string) string {
 	return strings.Join(indented, "\n")
 }
 
+// reindentLines replaces given indentation at the start of every lines of given string by another one
+func reindentLines(str string, from string, to string) string {
+	if from != "" {
+		lines := strings.SplitAfter(str, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(line, from)
+		}
+
+		str = strings.Join(lines, "")
+	}
+
+	return indentLines(str, to)
+}
+
+//
+// Decorators
+//
+
+// findDecorator finds given decorator
+func (v *evalVisitor) findDecorator(name string) DecoratorFunc {
+	// check template decorators
+	if d := v.tpl.findDecorator(name); d != nil {
+		return d
+	}
+
+	// check global decorators
+	return findDecorator(name)
+}
+
+// evalDecorators runs decorators of given program, and returns the resulting program scope, or nil if there is no decorator
+func (v *evalVisitor) evalDecorators(program *ast.Program) *decoratorScope {
+	var scope *decoratorScope
+
+	for _, n := range program.Body {
+		var expr *ast.Expression
+		var block *ast.Program
+
+		switch node := n.(type) {
+		case *ast.Decorator:
+			expr = node.Expression
+		case *ast.DecoratorBlock:
+			expr, block = node.Expression, node.Program
+		default:
+			continue
+		}
+
+		v.at(n)
+
+		name := expr.Canonical()
+
+		decorator := v.findDecorator(name)
+		if decorator == nil {
+			v.errorf("Decorator not found: %s", name)
+		}
+
+		if scope == nil {
+			scope = &decoratorScope{}
+		}
+
+		decorator(newDecoratorOptions(v, name, v.helperOptions(expr), block, scope))
+	}
+
+	return scope
+}
+
+// enterScope sets up context, private data and partials of given decorated program scope
+func (v *evalVisitor) enterScope(scope *decoratorScope) {
+	if scope.partials != nil {
+		v.pushPartials(scope.partials)
+	}
+
+	if scope.ctx.IsValid() {
+		v.pushCtx(scope.ctx)
+	}
+
+	if scope.data != nil {
+		v.setDataFrame(scope.data)
+	}
+}
+
+// leaveScope restores context, private data and partials that were set up by enterScope()
+func (v *evalVisitor) leaveScope(scope *decoratorScope) {
+	if scope.data != nil {
+		v.popDataFrame()
+	}
+
+	if scope.ctx.IsValid() {
+		v.popCtx()
+	}
+
+	if scope.partials != nil {
+		v.popPartials()
+	}
+}
+
 //
 // Functions
 //
@@ -767,6 +1699,15 @@ func (v *evalVisitor) wasFuncCall(node *ast.Expression) bool {
 	return v.exprFunc[node]
 }
 
+// setFuncCall tags given expression as a function call
+func (v *evalVisitor) setFuncCall(node *ast.Expression) {
+	if v.exprFunc == nil {
+		v.exprFunc = make(map[*ast.Expression]bool)
+	}
+
+	v.exprFunc[node] = true
+}
+
 //
 // Visitor interface
 //
@@ -777,37 +1718,61 @@ func (v *evalVisitor) wasFuncCall(node *ast.Expression) bool {
 func (v *evalVisitor) VisitProgram(node *ast.Program) interface{} {
 	v.at(node)
 
-	buf := new(bytes.Buffer)
+	if program := v.compiled[node]; program != nil {
+		program.exec(v)
+		return nil
+	}
+
+	// decorators are run before program body
+	scope := v.evalDecorators(node)
+	if scope != nil {
+		v.enterScope(scope)
+	}
 
 	for _, n := range node.Body {
-		if str := Str(n.Accept(v)); str != "" {
-			if _, err := buf.Write([]byte(str)); err != nil {
-				v.errPanic(err)
-			}
-		}
+		// checked between each statement, so that includes each block iteration
+		v.checkDone()
+
+		n.Accept(v)
 	}
 
-	return buf.String()
+	if scope != nil {
+		v.leaveScope(scope)
+	}
+
+	return nil
 }
 
 // VisitMustache implements corresponding Visitor interface method
 func (v *evalVisitor) VisitMustache(node *ast.MustacheStatement) interface{} {
 	v.at(node)
 
+	v.curMustache = node
+
 	// evaluate expression
-	expr := node.Expression.Accept(v)
+	v.writeMustache(node, node.Expression.Accept(v))
 
+	return nil
+}
+
+// writeMustache writes given mustache expression value to output
+func (v *evalVisitor) writeMustache(node *ast.MustacheStatement, expr interface{}) {
 	// check if this is a safe string
 	isSafe := isSafeString(expr)
 
 	// get string value
 	str := Str(expr)
 	if !isSafe && !node.Unescaped {
-		// escape html
-		str = Escape(str)
+		if ctx, ok := v.escapeContexts[node]; ok {
+			// escape according to context
+			v.write(ctx.escape(expr))
+		} else {
+			// escape html
+			v.writeEscaped(str)
+		}
+	} else {
+		v.write(str)
 	}
-
-	return str
 }
 
 // VisitBlock implements corresponding Visitor interface method
@@ -816,71 +1781,218 @@ func (v *evalVisitor) VisitBlock(node *ast.BlockStatement) interface{} {
 
 	v.pushBlock(node)
 
-	var result interface{}
-
 	// evaluate expression
 	expr := node.Expression.Accept(v)
 
-	if v.isHelperCall(node.Expression) || v.wasFuncCall(node.Expression) {
+	v.writeBlock(node, expr, v.isHelperCall(node.Expression))
+
+	v.popBlock()
+
+	return nil
+}
+
+// writeBlock writes block to output, given its expression value, and if that value is a helper result
+func (v *evalVisitor) writeBlock(node *ast.BlockStatement, expr interface{}, helper bool) {
+	if v.isBlockOutput(node.Expression, expr, helper) {
 		// it is the responsibility of the helper/function to evaluate block
-		result = expr
+		v.write(Str(expr))
+	} else if helper := v.findHelper(blockHelperMissingName); helper != zero {
+		// custom blockHelperMissing helper
+		options := newOptions(v, []interface{}{expr}, nil)
+		options.name = node.Expression.Canonical()
+
+		v.write(Str(v.callMissingHelper(blockHelperMissingName, helper, options)))
 	} else {
-		val := reflect.ValueOf(expr)
-
-		truth, _ := isTrueValue(val)
-		if truth {
-			if node.Program != nil {
-				switch val.Kind() {
-				case reflect.Array, reflect.Slice:
-					concat := ""
-
-					// Array context
-					for i := 0; i < val.Len(); i++ {
-						// Computes new private data frame
-						frame := v.dataFrame.newIterDataFrame(val.Len(), i, nil)
-
-						// Evaluate program
-						concat += v.evalProgram(node.Program, val.Index(i).Interface(), frame, i)
+		v.writeBlockValue(node, expr)
+	}
+}
+
+// isBlockOutput returns true if given block expression value is the output of a helper or function that evaluated the block itself
+func (v *evalVisitor) isBlockOutput(node *ast.Expression, expr interface{}, helper bool) bool {
+	if helper {
+		return true
+	}
+
+	if !v.wasFuncCall(node) {
+		return false
+	}
+
+	if v.isHelperCallCandidate(node) {
+		// function or helperMissing called with parameters
+		return true
+	}
+
+	// any other function result is handled by blockHelperMissing
+	switch expr.(type) {
+	case string, SafeString:
+		return true
+	}
+
+	return false
+}
+
+// writeBlockValue evaluates block with given expression value, and writes result to output
+//
+// This is the default blockHelperMissing behaviour.
+func (v *evalVisitor) writeBlockValue(node *ast.BlockStatement, expr interface{}) {
+	val := reflect.ValueOf(expr)
+
+	truth, _ := isTrueValue(val)
+	if truth {
+		if node.Program != nil {
+			switch val.Kind() {
+			case reflect.Array, reflect.Slice:
+				// Array context
+				for i := 0; i < val.Len(); i++ {
+					// Computes new private data frame
+					frame := v.dataFrame.newIterDataFrame(val.Len(), i, nil)
+					if v.trackIds {
+						frame.Set(contextPathName, appendContextPath(v.childContextPath(nodeID(node.Expression.Path)), strconv.Itoa(i)))
 					}
 
-					result = concat
-				default:
-					// NOT array
-					result = v.evalProgram(node.Program, expr, nil, nil)
+					// Evaluate program
+					v.writeProgram(node.Program, val.Index(i).Interface(), frame, i)
 				}
+			default:
+				// NOT array
+				v.writeProgram(node.Program, expr, v.contextPathFrame(nodeID(node.Expression.Path)), nil)
 			}
-		} else if node.Inverse != nil {
-			result, _ = node.Inverse.Accept(v).(string)
 		}
+	} else if node.Inverse != nil {
+		node.Inverse.Accept(v)
 	}
-
-	v.popBlock()
-
-	return result
 }
 
 // VisitPartial implements corresponding Visitor interface method
 func (v *evalVisitor) VisitPartial(node *ast.PartialStatement) interface{} {
 	v.at(node)
 
-	// partialName: helperName | sexpr
-	name, ok := ast.HelperNameStr(node.Name)
-	if !ok {
-		if subExpr, ok := node.Name.(*ast.SubExpression); ok {
-			name, _ = subExpr.Accept(v).(string)
+	var name string
+	if node.Dynamic {
+		// mustache dynamic name: {{>*name}}
+		if name = v.dynamicName(node.Name); name == "" {
+			return nil
 		}
+	} else {
+		name = v.partialName(node.Name)
 	}
 
-	if name == "" {
-		v.errorf("Unexpected partial name: %q", node.Name)
+	v.checkDone()
+
+	if name == partialBlockName {
+		v.evalPartialBlock(node)
+		return nil
+	}
+
+	partial := v.findPartial(name)
+	if partial == nil {
+		v.errorf("Partial not found: %s", name)
+	}
+
+	v.evalPartial(partial, node)
+
+	return nil
+}
+
+// VisitPartialBlock implements corresponding Visitor interface method
+func (v *evalVisitor) VisitPartialBlock(node *ast.PartialBlockStatement) interface{} {
+	v.at(node)
+
+	name := v.partialName(node.Name)
+
+	v.checkDone()
+
+	ctx := v.partialContext(node.Params, node.Hash)
+
+	partial := v.findPartial(name)
+	if partial == nil {
+		// render failover content
+		v.writePartial(v.subTemplate(node.Program), ctx, "", node)
+		return nil
+	}
+
+	// inline partials defined in block are available to partial
+	scope := v.evalDecorators(node.Program)
+	if (scope != nil) && (scope.partials != nil) {
+		v.pushPartials(scope.partials)
+		defer v.popPartials()
+	}
+
+	v.pushPartialBlock(v.subTemplate(node.Program))
+
+	v.writePartial(v.partialTemplate(partial), ctx, "", node)
+
+	v.popPartialBlock()
+
+	return nil
+}
+
+// VisitParent implements corresponding Visitor interface method
+func (v *evalVisitor) VisitParent(node *ast.ParentStatement) interface{} {
+	v.at(node)
+
+	var name string
+	if node.Dynamic {
+		name = v.dynamicName(node.Name)
+	} else {
+		name = v.partialName(node.Name)
 	}
 
+	v.checkDone()
+
 	partial := v.findPartial(name)
 	if partial == nil {
 		v.errorf("Partial not found: %s", name)
 	}
 
-	return v.evalPartial(partial, node)
+	// blocks overridden by outer parents take precedence
+	outer := v.namedBlocks
+
+	blocks := make(map[string]namedBlock)
+	for _, block := range node.Blocks() {
+		blocks[block.Name] = namedBlock{tpl: v.subTemplate(block.Program), indent: block.Indent}
+	}
+
+	for name, block := range outer {
+		blocks[name] = block
+	}
+
+	v.namedBlocks = blocks
+
+	v.writePartial(v.partialTemplate(partial), zero, node.Indent, node)
+
+	v.namedBlocks = outer
+
+	return nil
+}
+
+// namedBlock represents a mustache block overridden by a parent
+type namedBlock struct {
+	tpl *Template
+
+	// indentation of the block content
+	indent string
+}
+
+// VisitNamedBlock implements corresponding Visitor interface method
+func (v *evalVisitor) VisitNamedBlock(node *ast.NamedBlockStatement) interface{} {
+	v.at(node)
+
+	if block, ok := v.namedBlocks[node.Name]; ok {
+		// overridden by a parent, and reindented
+		if block.indent == node.Indent {
+			v.writePartial(block.tpl, zero, "", node)
+		} else {
+			v.write(reindentLines(v.capture(func() {
+				v.writePartial(block.tpl, zero, "", node)
+			}), block.indent, node.Indent))
+		}
+	} else {
+		// default content
+		node.Program.Accept(v)
+	}
+
+	return nil
 }
 
 // VisitContent implements corresponding Visitor interface method
@@ -888,7 +2000,9 @@ func (v *evalVisitor) VisitContent(node *ast.ContentStatement) interface{} {
 	v.at(node)
 
 	// write content as is
-	return node.Value
+	v.write(node.Value)
+
+	return nil
 }
 
 // VisitComment implements corresponding Visitor interface method
@@ -896,7 +2010,23 @@ func (v *evalVisitor) VisitComment(node *ast.CommentStatement) interface{} {
 	v.at(node)
 
 	// ignore comments
-	return ""
+	return nil
+}
+
+// VisitDecorator implements corresponding Visitor interface method
+func (v *evalVisitor) VisitDecorator(node *ast.Decorator) interface{} {
+	v.at(node)
+
+	// decorators were already run by VisitProgram()
+	return nil
+}
+
+// VisitDecoratorBlock implements corresponding Visitor interface method
+func (v *evalVisitor) VisitDecoratorBlock(node *ast.DecoratorBlock) interface{} {
+	v.at(node)
+
+	// decorators were already run by VisitProgram()
+	return nil
 }
 
 // Expressions
@@ -908,12 +2038,13 @@ func (v *evalVisitor) VisitExpression(node *ast.Expression) interface{} {
 	var result interface{}
 	done := false
 
-	v.pushExpr(node)
+	v.pushExpr(node, nil)
 
 	// helper call
-	if helperName := node.HelperName(); helperName != "" {
+	helperName := node.HelperName()
+	if helperName != "" {
 		if helper := v.findHelper(helperName); helper != zero {
-			result = v.callHelper(helperName, helper, node)
+			result = v.callHelper(helperName, helper, v.helperOptions(node))
 			done = true
 		}
 	}
@@ -934,12 +2065,18 @@ func (v *evalVisitor) VisitExpression(node *ast.Expression) interface{} {
 			// @todo Find a cleaner way ! Don't break the pattern !
 			// this is an exception to visitor pattern, because we need to pass the info
 			// that this path is at root of current expression
-			if val := v.evalPathExpression(path, true); val != nil {
+			if val := v.evalPathExpression(path, newPathParts(path.Parts), true); val != nil {
 				result = val
+				done = true
 			}
 		}
 	}
 
+	if !done && (helperName != "") && !v.wasFuncCall(node) && v.isHelperMissingCall(node) {
+		// missing helper
+		result = v.helperMissing(helperName, node, v.helperOptions(node))
+	}
+
 	v.popExpr()
 
 	return result
@@ -954,7 +2091,7 @@ func (v *evalVisitor) VisitSubExpression(node *ast.SubExpression) interface{} {
 
 // VisitPath implements corresponding Visitor interface method
 func (v *evalVisitor) VisitPath(node *ast.PathExpression) interface{} {
-	return v.evalPathExpression(node, false)
+	return v.evalPathExpression(node, newPathParts(node.Parts), false)
 }
 
 // Literals
@@ -270,3 +270,52 @@ func TestEvalMethodReturningFunc(t *testing.T) {
 		t.Errorf("Failed to evaluate struct method: %s", output)
 	}
 }
+
+func TestEvalCompat(t *testing.T) {
+	t.Parallel()
+
+	source := `{{#a}}{{b.c}}{{/a}}`
+	ctx := map[string]interface{}{
+		"a": map[string]interface{}{"b": nil},
+		"b": map[string]interface{}{"c": "X"},
+	}
+
+	tpl := MustParse(source)
+
+	if output := tpl.MustExec(ctx); output != "" {
+		t.Errorf("Failed to evaluate without compat mode: %q", output)
+	}
+
+	tpl.SetCompat(true)
+
+	if output := tpl.MustExec(ctx); output != "X" {
+		t.Errorf("Failed to evaluate with compat mode: %q", output)
+	}
+
+	if output := tpl.Clone().MustExec(ctx); output != "X" {
+		t.Errorf("Failed to evaluate cloned template with compat mode: %q", output)
+	}
+
+	// only the first part of path is looked up in parent contexts
+	ctx["a"] = map[string]interface{}{"b": map[string]interface{}{}}
+
+	if output := tpl.MustExec(ctx); output != "" {
+		t.Errorf("Failed to evaluate with compat mode when first part is found: %q", output)
+	}
+}
+
+func TestEvalVisitorReuse(t *testing.T) {
+	tpl := MustParse(`{{#each items}}{{#if fail}}{{missing.foo}}{{/if}}{{name}}{{/each}}`)
+	tpl.SetStrict(StrictOn)
+
+	// an aborted evaluation must not leave any state in pooled visitors
+	if _, err := tpl.Exec(map[string]interface{}{"items": []map[string]interface{}{{"name": "a"}, {"fail": true}}}); err == nil {
+		t.Fatalf("Expected a missing field error")
+	}
+
+	for i := 0; i < 10; i++ {
+		if output := tpl.MustExec(map[string]interface{}{"items": []map[string]interface{}{{"name": "b"}, {"name": func() string { return "c" }}}}); output != "bc" {
+			t.Errorf("Unexpected output with reused visitor: %q", output)
+		}
+	}
+}
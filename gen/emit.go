@@ -0,0 +1,737 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+// builtin helpers, that are only generated as blocks for if, unless, with and each
+var builtinHelpers = map[string]bool{
+	"if":     true,
+	"unless": true,
+	"with":   true,
+	"each":   true,
+	"log":    true,
+	"lookup": true,
+	"equal":  true,
+}
+
+// unsupportedError is returned when a template uses a construct that can't be generated
+type unsupportedError struct {
+	node   ast.Node
+	reason string
+}
+
+// Error implements the error interface
+func (err *unsupportedError) Error() string {
+	return fmt.Sprintf("Unsupported construct at line %d: %s", err.node.Location().Line, err.reason)
+}
+
+// generator generates the body of a template render function
+type generator struct {
+	file    *file
+	helpers map[string]bool
+
+	buf    *bytes.Buffer
+	nbVars int
+
+	// current node, used for errors
+	curNode ast.Node
+
+	// contexts stack
+	scopes []value
+
+	// private data frames stack, nil for the root frame
+	frames []*frame
+
+	// block parameters stack
+	params []map[string]value
+}
+
+// newGenerator instanciates a new generator, for a template with given context type
+func newGenerator(f *file, helpers map[string]bool, ctxType types.Type) *generator {
+	return &generator{
+		file:    f,
+		helpers: helpers,
+		buf:     new(bytes.Buffer),
+		scopes:  []value{{expr: "ctx", typ: types.NewPointer(ctxType), indirect: true, root: true}},
+		frames:  []*frame{nil},
+	}
+}
+
+// generate generates the body of the render function of given program
+func (g *generator) generate(program *ast.Program) (result string, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			unsupported, ok := e.(*unsupportedError)
+			if !ok {
+				panic(e)
+			}
+
+			err = unsupported
+		}
+	}()
+
+	g.curNode = program
+
+	g.printf("out := rt.NewWriter(w)")
+	g.program(program)
+	g.printf("return out.Err()")
+
+	result = g.buf.String()
+
+	// named return values
+	return
+}
+
+//
+// Output
+//
+
+// printf writes a line of generated code
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+// close closes given number of generated blocks
+func (g *generator) close(nb int) {
+	for i := 0; i < nb; i++ {
+		g.printf("}")
+	}
+}
+
+// capture calls given function with a temporary output, and returns everything that was generated
+func (g *generator) capture(fn func()) string {
+	buf := g.buf
+	g.buf = new(bytes.Buffer)
+
+	defer func() {
+		g.buf = buf
+	}()
+
+	fn()
+
+	return g.buf.String()
+}
+
+// newVar returns a new variable name, with given prefix
+func (g *generator) newVar(prefix string) string {
+	g.nbVars++
+
+	return fmt.Sprintf("%s%d", prefix, g.nbVars)
+}
+
+// typeString returns the string representation of given type
+func (g *generator) typeString(typ types.Type) string {
+	return types.TypeString(typ, g.file.qualifier)
+}
+
+// unsupported aborts generation
+func (g *generator) unsupported(format string, args ...interface{}) {
+	panic(&unsupportedError{
+		node:   g.curNode,
+		reason: fmt.Sprintf(format, args...),
+	})
+}
+
+//
+// Contexts
+//
+
+// context returns current context, as seen by helpers
+func (g *generator) context() string {
+	return g.scopes[len(g.scopes)-1].val()
+}
+
+// withContext generates given program, with given context, private data frame and block parameters key
+func (g *generator) withContext(program *ast.Program, ctx value, data *frame, key *value) {
+	// the interpreter pushes a copy of that value
+	ctx = value{expr: ctx.expr, typ: ctx.typ, ptr: ctx.ptr}
+
+	g.scopes = append(g.scopes, ctx)
+	if data != nil {
+		g.frames = append(g.frames, data)
+	}
+
+	params := make(map[string]value)
+	if len(program.BlockParams) > 0 {
+		params[program.BlockParams[0]] = ctx
+	}
+
+	if (len(program.BlockParams) > 1) && (key != nil) {
+		params[program.BlockParams[1]] = *key
+	}
+
+	g.params = append(g.params, params)
+
+	g.program(program)
+
+	g.params = g.params[:len(g.params)-1]
+	if data != nil {
+		g.frames = g.frames[:len(g.frames)-1]
+	}
+	g.scopes = g.scopes[:len(g.scopes)-1]
+}
+
+// iterate generates the iteration of given program on given slice, array or map value
+func (g *generator) iterate(program *ast.Program, val value) {
+	index, length := g.newVar("i"), g.newVar("n")
+	data := &frame{
+		index: index,
+		first: index + " == 0",
+		last:  fmt.Sprintf("%s == %s-1", index, length),
+	}
+
+	switch u := val.typ.Underlying().(type) {
+	case *types.Slice, *types.Array:
+		var elem types.Type
+		if slice, ok := u.(*types.Slice); ok {
+			elem = slice.Elem()
+		} else {
+			elem = u.(*types.Array).Elem()
+		}
+
+		ctx := g.checkValue(value{expr: fmt.Sprintf("%s[%s]", val.ref(), index), typ: elem})
+		key := value{expr: index, typ: types.Typ[types.Int]}
+
+		body := g.capture(func() {
+			g.withContext(program, ctx, data, &key)
+		})
+
+		if usesVar(body, length) {
+			g.printf("%s := len(%s)", length, val.ref())
+		}
+
+		if usesVar(body, index) {
+			g.printf("for %s := range %s {", index, val.ref())
+		} else {
+			g.printf("for range %s {", val.ref())
+		}
+
+		g.buf.WriteString(body)
+		g.printf("}")
+
+	case *types.Map:
+		k, e := g.newVar("k"), g.newVar("e")
+
+		ctx := g.checkValue(value{expr: e, typ: u.Elem()})
+		key := value{expr: k, typ: u.Key()}
+		data.key = &key
+
+		body := g.capture(func() {
+			g.withContext(program, ctx, data, &key)
+		})
+
+		if usesVar(body, length) {
+			g.printf("%s := len(%s)", length, val.ref())
+		}
+
+		countIndex := usesVar(body, index)
+		if countIndex {
+			g.printf("%s := 0", index)
+		}
+
+		switch {
+		case usesVar(body, k) && usesVar(body, e):
+			g.printf("for %s, %s := range %s {", k, e, val.ref())
+		case usesVar(body, k):
+			g.printf("for %s := range %s {", k, val.ref())
+		case usesVar(body, e):
+			g.printf("for _, %s := range %s {", e, val.ref())
+		default:
+			g.printf("for range %s {", val.ref())
+		}
+
+		g.buf.WriteString(body)
+
+		if countIndex {
+			g.printf("%s++", index)
+		}
+
+		g.printf("}")
+
+	default:
+		g.unsupported("iteration on type %s", g.typeString(val.typ))
+	}
+}
+
+// truthy generates the code generated by then, executed only if given value is truthy
+func (g *generator) truthy(val value, then func()) {
+	switch cond := g.truthCond(val); cond {
+	case "true":
+		then()
+	case "false":
+		// never truthy
+	default:
+		g.printf("if %s {", cond)
+		then()
+		g.printf("}")
+	}
+}
+
+//
+// Statements
+//
+
+// program generates given program
+func (g *generator) program(node *ast.Program) {
+	if node == nil {
+		return
+	}
+
+	for _, n := range node.Body {
+		g.statement(n)
+	}
+}
+
+// statement generates given statement
+func (g *generator) statement(node ast.Node) {
+	g.curNode = node
+
+	switch n := node.(type) {
+	case *ast.ContentStatement:
+		if n.Value != "" {
+			g.printf("out.WriteString(%s)", strconv.Quote(n.Value))
+		}
+
+	case *ast.CommentStatement:
+		// NOOP
+
+	case *ast.MustacheStatement:
+		g.mustache(n)
+
+	case *ast.BlockStatement:
+		g.block(n)
+
+	case *ast.PartialStatement, *ast.PartialBlockStatement:
+		g.unsupported("partials")
+
+	case *ast.Decorator, *ast.DecoratorBlock:
+		g.unsupported("decorators")
+
+	case *ast.ParentStatement, *ast.NamedBlockStatement:
+		g.unsupported("mustache inheritance")
+
+	default:
+		g.unsupported("statement %s", node)
+	}
+}
+
+// mustache generates given mustache statement
+func (g *generator) mustache(node *ast.MustacheStatement) {
+	escape := !node.Unescaped
+
+	if g.isHelperCall(node.Expression) {
+		result := g.helperCall(node.Expression)
+		g.printf("out.WriteValue(%s, %t)", result, escape)
+		return
+	}
+
+	g.lookup(g.fieldPath(node.Expression), func(val value) {
+		g.deref(val, func(val value) {
+			g.write(val, escape)
+		}, func(val value) {
+			// a nil pointer is printed
+			g.printf("out.WriteValue(%s, %t)", val.expr, escape)
+		})
+	})
+}
+
+// write generates the code that writes given value
+func (g *generator) write(val value, escape bool) {
+	x := val.val()
+
+	if isSafeString(val.typ) {
+		g.printf("out.WriteString(string(%s))", x)
+		return
+	}
+
+	if basic, ok := val.typ.Underlying().(*types.Basic); ok && !isPrintable(val.typ) {
+		info := basic.Info()
+
+		switch {
+		case info&types.IsString != 0:
+			if escape {
+				g.printf("out.WriteEscaped(%s)", convert(x, val.typ, types.String))
+			} else {
+				g.printf("out.WriteString(%s)", convert(x, val.typ, types.String))
+			}
+			return
+		case info&types.IsBoolean != 0:
+			g.printf("out.WriteBool(%s)", convert(x, val.typ, types.Bool))
+			return
+		case info&types.IsInteger != 0:
+			if info&types.IsUnsigned != 0 {
+				g.printf("out.WriteUint(%s)", convert(x, val.typ, types.Uint64))
+			} else {
+				g.printf("out.WriteInt(%s)", convert(x, val.typ, types.Int64))
+			}
+			return
+		case info&types.IsFloat != 0:
+			g.printf("out.WriteFloat(%s)", convert(x, val.typ, types.Float64))
+			return
+		}
+	}
+
+	g.printf("out.WriteValue(%s, %t)", x, escape)
+}
+
+// convert returns given expression of given type, converted to given basic type
+func convert(x string, typ types.Type, kind types.BasicKind) string {
+	if isBasic(typ, kind) {
+		return x
+	}
+
+	return fmt.Sprintf("%s(%s)", types.Typ[kind].Name(), x)
+}
+
+// block generates given block statement
+func (g *generator) block(node *ast.BlockStatement) {
+	if node.Raw {
+		g.unsupported("raw blocks")
+	}
+
+	switch name := node.Expression.HelperName(); {
+	case (name == "if") || (name == "unless"):
+		g.conditional(node, name == "unless")
+	case name == "with":
+		g.with(node)
+	case name == "each":
+		g.each(node)
+	case builtinHelpers[name] || g.helpers[name]:
+		g.unsupported("block helper %q", name)
+	default:
+		g.section(node)
+	}
+}
+
+// blockParam returns the only parameter of given builtin block helper
+func (g *generator) builtinParam(node *ast.BlockStatement) ast.Node {
+	expr := node.Expression
+	if (len(expr.Params) != 1) || (expr.Hash != nil) {
+		g.unsupported("block helper %q with %d parameters and hash %v", expr.HelperName(), len(expr.Params), expr.Hash != nil)
+	}
+
+	return expr.Params[0]
+}
+
+// inverseFlag declares a flag set when block program is evaluated, if block has an inverse
+func (g *generator) inverseFlag(node *ast.BlockStatement) string {
+	if node.Inverse == nil {
+		return ""
+	}
+
+	result := g.newVar("ok")
+	g.printf("%s := false", result)
+
+	return result
+}
+
+// inverse generates the inverse of given block, evaluated if flag was not set
+func (g *generator) inverse(node *ast.BlockStatement, flag string) {
+	if flag == "" {
+		return
+	}
+
+	g.printf("if !%s {", flag)
+	g.program(node.Inverse)
+	g.printf("}")
+}
+
+// setFlag generates the code that sets given flag
+func (g *generator) setFlag(flag string) {
+	if flag != "" {
+		g.printf("%s = true", flag)
+	}
+}
+
+// conditional generates an #if or #unless block
+func (g *generator) conditional(node *ast.BlockStatement, unless bool) {
+	program, inverse := node.Program, node.Inverse
+	if unless {
+		program, inverse = inverse, program
+	}
+
+	cond := ""
+
+	switch param := g.builtinParam(node).(type) {
+	case *ast.StringLiteral, *ast.BooleanLiteral, *ast.NumberLiteral:
+		truth := false
+		switch lit := param.(type) {
+		case *ast.StringLiteral:
+			truth = lit.Value != ""
+		case *ast.BooleanLiteral:
+			truth = lit.Value
+		case *ast.NumberLiteral:
+			truth = lit.Value != 0
+		}
+
+		if truth {
+			g.program(program)
+		} else {
+			g.program(inverse)
+		}
+
+		return
+
+	case *ast.SubExpression:
+		if !g.isHelperCall(param.Expression) {
+			g.unsupported("subexpression")
+		}
+
+		cond = fmt.Sprintf("rt.IsTrue(%s)", g.helperCall(param.Expression))
+
+	case *ast.PathExpression:
+		cond = g.newVar("ok")
+		g.printf("%s := false", cond)
+
+		g.lookup(param, func(val value) {
+			g.deref(val, func(val value) {
+				g.truthy(val, func() {
+					g.printf("%s = true", cond)
+				})
+			}, nil)
+		})
+
+	default:
+		g.unsupported("parameter %s", param)
+	}
+
+	if (program == nil) && (inverse == nil) {
+		return
+	}
+
+	if program == nil {
+		g.printf("if !%s {", cond)
+		g.program(inverse)
+		g.printf("}")
+		return
+	}
+
+	g.printf("if %s {", cond)
+	g.program(program)
+	if inverse != nil {
+		g.printf("} else {")
+		g.program(inverse)
+	}
+	g.printf("}")
+}
+
+// builtinPath returns the path parameter of given #with or #each block
+func (g *generator) builtinPath(node *ast.BlockStatement) *ast.PathExpression {
+	path, ok := g.builtinParam(node).(*ast.PathExpression)
+	if !ok {
+		g.unsupported("block helper %q with a parameter that is not a path", node.Expression.HelperName())
+	}
+
+	return path
+}
+
+// with generates a #with block
+func (g *generator) with(node *ast.BlockStatement) {
+	path := g.builtinPath(node)
+	flag := g.inverseFlag(node)
+
+	g.lookup(path, func(val value) {
+		g.deref(val, func(val value) {
+			g.truthy(val, func() {
+				g.setFlag(flag)
+
+				if node.Program != nil {
+					g.withContext(node.Program, val, nil, nil)
+				}
+			})
+		}, nil)
+	})
+
+	g.inverse(node, flag)
+}
+
+// each generates an #each block
+func (g *generator) each(node *ast.BlockStatement) {
+	path := g.builtinPath(node)
+	flag := g.inverseFlag(node)
+
+	g.lookup(path, func(val value) {
+		g.deref(val, func(val value) {
+			switch val.typ.Underlying().(type) {
+			case *types.Slice, *types.Array, *types.Map:
+			default:
+				g.unsupported("#each on type %s", g.typeString(val.typ))
+			}
+
+			g.truthy(val, func() {
+				g.setFlag(flag)
+
+				if node.Program != nil {
+					g.iterate(node.Program, val)
+				}
+			})
+		}, nil)
+	})
+
+	g.inverse(node, flag)
+}
+
+// section generates a block whose expression is not a helper call
+//
+// This is the default blockHelperMissing behaviour.
+func (g *generator) section(node *ast.BlockStatement) {
+	path := g.fieldPath(node.Expression)
+	flag := g.inverseFlag(node)
+
+	g.lookup(path, func(val value) {
+		g.deref(val, func(val value) {
+			if val.funcCall && (isBasic(val.typ, types.String) || isSafeString(val.typ)) {
+				// the string returned by a function is output as is
+				g.setFlag(flag)
+				g.printf("out.WriteString(%s)", convert(val.val(), val.typ, types.String))
+				return
+			}
+
+			g.truthy(val, func() {
+				g.setFlag(flag)
+
+				if node.Program == nil {
+					return
+				}
+
+				switch val.typ.Underlying().(type) {
+				case *types.Slice, *types.Array:
+					g.iterate(node.Program, val)
+				case *types.Interface:
+					// iteration or context push depends on the runtime value
+					g.unsupported("section on type %s", g.typeString(val.typ))
+				default:
+					g.withContext(node.Program, val, nil, nil)
+				}
+			})
+		}, nil)
+	})
+
+	g.inverse(node, flag)
+}
+
+//
+// Expressions
+//
+
+// isHelperCall returns true if given expression is a call to a declared helper
+func (g *generator) isHelperCall(node *ast.Expression) bool {
+	name := node.HelperName()
+
+	return (name != "") && g.helpers[name]
+}
+
+// fieldPath returns the path of given expression, that must not be a helper call
+func (g *generator) fieldPath(node *ast.Expression) *ast.PathExpression {
+	name := node.HelperName()
+
+	if builtinHelpers[name] {
+		g.unsupported("helper %q", name)
+	}
+
+	if (len(node.Params) > 0) || (node.Hash != nil) {
+		g.unsupported("helper %q that is not declared", name)
+	}
+
+	path := node.FieldPath()
+	if path == nil {
+		g.unsupported("expression %s", node.Path)
+	}
+
+	return path
+}
+
+// helperCall generates a declared helper call, and returns the variable that holds its result
+func (g *generator) helperCall(node *ast.Expression) string {
+	params := "nil"
+	if len(node.Params) > 0 {
+		params = "[]interface{}{"
+		for i, param := range node.Params {
+			if i > 0 {
+				params += ", "
+			}
+			params += g.param(param)
+		}
+		params += "}"
+	}
+
+	hash := "nil"
+	if node.Hash != nil {
+		hash = "map[string]interface{}{"
+		for i, pair := range node.Hash.Pairs {
+			if i > 0 {
+				hash += ", "
+			}
+			hash += fmt.Sprintf("%q: %s", pair.Key, g.param(pair.Val))
+		}
+		hash += "}"
+	}
+
+	result := g.newVar("r")
+	g.printf("%s := out.Helper(%q, %s, %s, %s)", result, node.HelperName(), g.context(), params, hash)
+
+	return result
+}
+
+// param generates a helper parameter, and returns its Go expression
+func (g *generator) param(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.StringLiteral:
+		return strconv.Quote(n.Value)
+
+	case *ast.BooleanLiteral:
+		return strconv.FormatBool(n.Value)
+
+	case *ast.NumberLiteral:
+		if n.IsInt {
+			return strconv.Itoa(int(n.Value))
+		}
+
+		return fmt.Sprintf("float64(%s)", strconv.FormatFloat(n.Value, 'g', -1, 64))
+
+	case *ast.SubExpression:
+		if !g.isHelperCall(n.Expression) {
+			g.unsupported("subexpression")
+		}
+
+		return g.helperCall(n.Expression)
+
+	case *ast.PathExpression:
+		result := g.newVar("p")
+
+		code := g.capture(func() {
+			g.lookup(n, func(val value) {
+				g.deref(val, func(val value) {
+					g.printf("%s = %s", result, val.val())
+				}, func(val value) {
+					// helper gets a typed nil pointer
+					g.printf("%s = %s", result, val.expr)
+				})
+			})
+		})
+
+		switch {
+		case code == "":
+			return "nil"
+		case !strings.Contains(code[:len(code)-1], "\n"):
+			// unconditional value
+			return strings.TrimPrefix(code[:len(code)-1], result+" = ")
+		}
+
+		g.printf("var %s interface{}", result)
+		g.buf.WriteString(code)
+
+		return result
+	}
+
+	g.unsupported("parameter %s", node)
+	return ""
+}
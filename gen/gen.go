@@ -0,0 +1,297 @@
+// Package gen generates Go code that renders handlebars templates with a statically typed context.
+//
+// For each template, a function with that signature is generated:
+//
+//	func Render<Name>(w io.Writer, ctx *<Type>) error
+//
+// Paths are resolved at generation time against the context type, so the generated code accesses struct fields,
+// methods and map entries directly, instead of using reflection. Rendering rules are the same as the interpreter ones,
+// and the generated code produces the same output as raymond.Template.ExecTo(). A nil ctx is rendered like a nil
+// context with the interpreter.
+//
+// The if, unless, with and each builtin helpers are generated inline. Other helpers must be declared with
+// Config.Helpers: they are called through the global helpers registry, so they must be registered with
+// raymond.RegisterHelper() before calling the generated functions. Declared helpers can't be called as blocks.
+//
+// Like with the KnownHelpersOnly parse option, an expression that is not a declared helper is always a field lookup.
+//
+// Generated code doesn't reimplement helpers: a declared helper is called with raymond.CallHelper(), that evaluates it
+// like the interpreter does, so each helper call costs as much as with the interpreter.
+//
+// The generator supports a subset of the language. A template can't be generated if it uses:
+//
+//   - partials, partial blocks and mustache inheritance
+//   - decorators
+//   - raw blocks
+//   - block helpers, except #if, #unless, #with and #each with a single path parameter
+//   - the lookup, log and equal builtin helpers
+//   - literal expressions, eg. {{"foo"}} or {{true}}, and subexpressions that don't call a declared helper
+//   - private variables other than @root, @index, @key, @first and @last
+//   - lookups in a value of interface type, or in a value whose type has no field, method or map entry for that path
+//   - function values, and methods whose signature the interpreter would not call
+//   - #each on types other than slices, arrays and maps, and sections on values of interface type
+//
+// When a template uses such a construct, its generated function falls back on evaluating the template with the
+// interpreter, unless Config.Strict is set, in which case Generate fails. Each fallback is reported with
+// Config.Fallback, or logged by default.
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	hbsparser "github.com/aymerick/raymond/parser"
+)
+
+// Template represents a template to generate.
+type Template struct {
+	// Name is used to name the generated function: Render<Name>
+	Name string
+
+	// Source is the template source
+	Source string
+
+	// Type is the context type: the generated function takes a pointer to that type
+	Type types.Type
+}
+
+// Config represents the code generation configuration.
+type Config struct {
+	// Package is the package of the generated code
+	Package *types.Package
+
+	// Helpers are the names of the global helpers that templates can call
+	Helpers []string
+
+	// Strict makes generation fail if a template can't be generated, instead of falling back on the interpreter
+	Strict bool
+
+	// Fallback is called with the reason of each template that falls back on the interpreter. When nil, a warning is
+	// logged with the standard logger.
+	Fallback func(name string, err error)
+
+	// Templates to generate
+	Templates []Template
+}
+
+// header of generated files
+const header = "// Code generated by raymond-gen. DO NOT EDIT.\n"
+
+// generatedRegexp matches the comment that identifies a generated file
+var generatedRegexp = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// file represents a generated file
+type file struct {
+	pkg     *types.Package
+	imports map[string]string
+}
+
+// qualifier returns the name of given package in generated code, and imports it
+func (f *file) qualifier(pkg *types.Package) string {
+	if (f.pkg != nil) && (pkg.Path() == f.pkg.Path()) {
+		return ""
+	}
+
+	f.imports[pkg.Path()] = pkg.Name()
+
+	return pkg.Name()
+}
+
+// renderFuncName returns the name of the function that renders the template with given name
+func renderFuncName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+
+	return "Render" + string(unicode.ToUpper(r)) + name[size:]
+}
+
+// Generate generates the Go source code that renders configured templates.
+func Generate(config Config) ([]byte, error) {
+	if config.Package == nil {
+		return nil, errors.New("Missing package")
+	}
+
+	f := &file{
+		pkg:     config.Package,
+		imports: map[string]string{"io": "io"},
+	}
+
+	helpers := make(map[string]bool)
+	for _, name := range config.Helpers {
+		helpers[name] = true
+	}
+
+	body := new(bytes.Buffer)
+
+	for _, tpl := range config.Templates {
+		if !token.IsIdentifier(tpl.Name) {
+			return nil, fmt.Errorf("Invalid template name: %q", tpl.Name)
+		}
+
+		if tpl.Type == nil {
+			return nil, fmt.Errorf("Missing context type of template %s", tpl.Name)
+		}
+
+		program, err := hbsparser.Parse(tpl.Source)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse template %s: %s", tpl.Name, err)
+		}
+
+		funcName := renderFuncName(tpl.Name)
+		ctxType := types.TypeString(types.NewPointer(tpl.Type), f.qualifier)
+
+		code, err := newGenerator(f, helpers, tpl.Type).generate(program)
+		if err != nil {
+			if config.Strict {
+				return nil, fmt.Errorf("Failed to generate template %s: %s", tpl.Name, err)
+			}
+
+			// fallback on interpreter
+			if config.Fallback != nil {
+				config.Fallback(tpl.Name, err)
+			} else {
+				log.Printf("Template %s falls back on the interpreter: %s", tpl.Name, err)
+			}
+
+			varName := "tpl" + funcName[len("Render"):]
+
+			fmt.Fprintf(body, "\n// %s is the %s template, evaluated by the interpreter because:\n// %s\n", varName, tpl.Name, err)
+			fmt.Fprintf(body, "var %s = raymond.MustParse(%s)\n", varName, quote(tpl.Source))
+
+			code = fmt.Sprintf("return %s.ExecTo(w, ctx)\n", varName)
+
+			f.imports["github.com/aymerick/raymond"] = "raymond"
+		} else {
+			f.imports["github.com/aymerick/raymond/gen/rt"] = "rt"
+		}
+
+		fmt.Fprintf(body, "\n// %s renders the %s template with given context.\n", funcName, tpl.Name)
+		fmt.Fprintf(body, "func %s(w io.Writer, ctx %s) error {\n%s}\n", funcName, ctxType, code)
+	}
+
+	result := new(bytes.Buffer)
+
+	result.WriteString(header)
+	fmt.Fprintf(result, "\npackage %s\n\n", config.Package.Name())
+
+	writeImports(result, f.imports)
+
+	result.Write(body.Bytes())
+
+	return format.Source(result.Bytes())
+}
+
+// writeImports writes the import declaration of given packages
+func writeImports(buf *bytes.Buffer, imports map[string]string) {
+	var std, others []string
+
+	for path := range imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			others = append(others, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+
+	sort.Strings(std)
+	sort.Strings(others)
+
+	buf.WriteString("import (\n")
+
+	for i, paths := range [][]string{std, others} {
+		if (i > 0) && (len(std) > 0) && (len(others) > 0) {
+			buf.WriteString("\n")
+		}
+
+		for _, path := range paths {
+			if name := imports[path]; name != filepath.Base(path) {
+				fmt.Fprintf(buf, "%s %q\n", name, path)
+			} else {
+				fmt.Fprintf(buf, "%q\n", path)
+			}
+		}
+	}
+
+	buf.WriteString(")\n")
+}
+
+// quote returns given template source as a Go string literal
+func quote(source string) string {
+	if !strings.ContainsAny(source, "`\r") {
+		return "`" + source + "`"
+	}
+
+	return strconv.Quote(source)
+}
+
+// LoadPackage parses and type checks the Go package in given directory, so that the types of template contexts can
+// be looked up. Test files and generated files are ignored.
+func LoadPackage(dir string) (*types.Package, error) {
+	bpkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+
+	var files []*ast.File
+
+	for _, fileName := range bpkg.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, fileName), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		if !isGenerated(f) {
+			files = append(files, f)
+		}
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+	}
+
+	return conf.Check(bpkg.ImportPath, fset, files, nil)
+}
+
+// isGenerated returns true if given file is a generated file
+func isGenerated(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() > f.Package {
+			break
+		}
+
+		for _, comment := range group.List {
+			if generatedRegexp.MatchString(comment.Text) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// LookupType returns the type with given name, declared in given package.
+func LookupType(pkg *types.Package, name string) (types.Type, error) {
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("Type %s not found in package %s", name, pkg.Path())
+	}
+
+	return obj.Type(), nil
+}
@@ -0,0 +1,231 @@
+package gen
+
+import (
+	"bytes"
+	"flag"
+	"go/types"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aymerick/raymond/gen/internal/gentest"
+)
+
+var update = flag.Bool("update", false, "update generated test templates")
+
+var (
+	gentestDir  = filepath.Join("internal", "gentest")
+	gentestFile = filepath.Join(gentestDir, "templates_gen.go")
+)
+
+// generateTestTemplates generates the templates of the gentest package
+func generateTestTemplates(t *testing.T) []byte {
+	pkg, err := LoadPackage(gentestDir)
+	if err != nil {
+		t.Fatalf("Failed to load package: %s", err)
+	}
+
+	config := Config{
+		Package: pkg,
+		Helpers: gentest.Helpers,
+		Fallback: func(name string, err error) {
+			t.Logf("Template %s falls back on the interpreter: %s", name, err)
+		},
+	}
+
+	for _, tpl := range gentest.Templates {
+		typ, err := LookupType(pkg, tpl.Type)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config.Templates = append(config.Templates, Template{Name: tpl.Name, Source: tpl.Source, Type: typ})
+	}
+
+	source, err := Generate(config)
+	if err != nil {
+		t.Fatalf("Failed to generate templates: %s", err)
+	}
+
+	return source
+}
+
+func TestGeneratedTemplates(t *testing.T) {
+	source := generateTestTemplates(t)
+
+	if *update {
+		if err := ioutil.WriteFile(gentestFile, source, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current, err := ioutil.ReadFile(gentestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(current, source) {
+		t.Errorf("Generated templates are outdated, run: go test ./gen -update")
+	}
+}
+
+// testPackage returns a package with a Person struct type, and that type
+func testPackage() (*types.Package, types.Type) {
+	pkg := types.NewPackage("example.com/test", "test")
+
+	fields := []*types.Var{
+		types.NewField(0, pkg, "Name", types.Typ[types.String], false),
+		types.NewField(0, pkg, "Data", types.NewInterfaceType(nil, nil), false),
+	}
+
+	obj := types.NewTypeName(0, pkg, "Person", nil)
+	typ := types.NewNamed(obj, types.NewStruct(fields, nil), nil)
+	pkg.Scope().Insert(obj)
+
+	return pkg, typ
+}
+
+var generateTests = []struct {
+	name     string
+	source   string
+	strict   bool
+	contains []string
+	err      string
+}{
+	{
+		"static template",
+		`Hello {{name}}`,
+		false,
+		[]string{
+			`"github.com/aymerick/raymond/gen/rt"`,
+			"func RenderTest(w io.Writer, ctx *Person) error {",
+			`out.WriteString("Hello ")`,
+			"out.WriteEscaped(ctx.Name)",
+		},
+		"",
+	},
+	{
+		"fallback on interpreter",
+		`Hello {{data.name}}`,
+		false,
+		[]string{
+			`"github.com/aymerick/raymond"`,
+			"// Unsupported construct at line 1: lookup of \"name\" in a value of interface type interface{}",
+			"var tplTest = raymond.MustParse(`Hello {{data.name}}`)",
+			"return tplTest.ExecTo(w, ctx)",
+		},
+		"",
+	},
+	{
+		"strict mode",
+		`Hello {{> name}}`,
+		true,
+		nil,
+		"Failed to generate template test: Unsupported construct at line 1: partial",
+	},
+	{
+		"parse error",
+		`Hello {{name}`,
+		false,
+		nil,
+		"Failed to parse template test",
+	},
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	pkg, typ := testPackage()
+
+	for _, test := range generateTests {
+		var fallback error
+
+		source, err := Generate(Config{
+			Package:   pkg,
+			Strict:    test.strict,
+			Templates: []Template{{Name: "test", Source: test.source, Type: typ}},
+			Fallback: func(name string, err error) {
+				fallback = err
+			},
+		})
+
+		if test.err != "" {
+			if (err == nil) || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Test '%s' failed\nexpected error:\n\t%q\ngot:\n\t%v", test.name, test.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Test '%s' failed\nunexpected error: %s", test.name, err)
+			continue
+		}
+
+		if isFallback := strings.Contains(string(source), "raymond.MustParse"); isFallback != (fallback != nil) {
+			t.Errorf("Test '%s' failed\nfallback reported: %v", test.name, fallback)
+		}
+
+		for _, str := range test.contains {
+			if !strings.Contains(string(source), str) {
+				t.Errorf("Test '%s' failed\nexpected generated code to contain:\n\t%s\ngot:\n%s", test.name, str, source)
+			}
+		}
+	}
+}
+
+func TestGenerateUnicodeName(t *testing.T) {
+	t.Parallel()
+
+	pkg, typ := testPackage()
+
+	source, err := Generate(Config{Package: pkg, Templates: []Template{{Name: "été", Source: "{{name}}", Type: typ}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(source), "func RenderÉté(") {
+		t.Errorf("Expected first letter of template name to be uppercased, got:\n%s", source)
+	}
+}
+
+func TestGenerateInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	pkg, typ := testPackage()
+
+	if _, err := Generate(Config{}); err == nil {
+		t.Errorf("Expected an error when package is missing")
+	}
+
+	if _, err := Generate(Config{Package: pkg, Templates: []Template{{Name: "foo-bar", Type: typ}}}); err == nil {
+		t.Errorf("Expected an error with an invalid template name")
+	}
+
+	if _, err := Generate(Config{Package: pkg, Templates: []Template{{Name: "foo"}}}); err == nil {
+		t.Errorf("Expected an error when context type is missing")
+	}
+
+	if _, err := LookupType(pkg, "Unknown"); err == nil {
+		t.Errorf("Expected an error when looking up an unknown type")
+	}
+}
+
+func TestGenerateFallbackWarning(t *testing.T) {
+	pkg, typ := testPackage()
+
+	buf := new(bytes.Buffer)
+
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+
+	if _, err := Generate(Config{Package: pkg, Templates: []Template{{Name: "test", Source: `{{> name}}`, Type: typ}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "Template test falls back on the interpreter: Unsupported construct at line 1: partials") {
+		t.Errorf("Expected a fallback warning, got: %q", buf.String())
+	}
+}
@@ -0,0 +1,125 @@
+// Package gentest holds the templates generated by the gen package tests.
+//
+// Templates are generated in templates_gen.go, run `go test ./gen -update` to regenerate them.
+package gentest
+
+import (
+	"strconv"
+
+	"github.com/aymerick/raymond"
+)
+
+// Level is a user level, printed with its String() method
+type Level int
+
+// String implements the fmt.Stringer interface
+func (l Level) String() string {
+	switch l {
+	case 0:
+		return "guest"
+	case 1:
+		return "member"
+	default:
+		return "admin"
+	}
+}
+
+// Address is an address
+type Address struct {
+	City string
+	Zip  int
+}
+
+// Company is a company
+type Company struct {
+	Name    string
+	Website string
+	Address *Address
+	Boss    *Person `handlebars:"ceo"`
+}
+
+// Summary returns a company summary
+func (c Company) Summary() string {
+	if c.Address == nil {
+		return c.Name
+	}
+
+	return c.Name + " in " + c.Address.City
+}
+
+// Person is a person
+type Person struct {
+	Name     string
+	Age      int
+	Height   float64
+	Admin    bool
+	Bio      string
+	Level    Level
+	Badge    raymond.SafeString
+	Nickname string `handlebars:"nick"`
+	Company  *Company
+	Friends  []*Person
+	Tags     []string
+	Scores   map[string]int
+	Team     []Person
+	secret   string
+}
+
+// FullName returns the person name and age
+func (p *Person) FullName() string {
+	return p.Name + " (" + strconv.Itoa(p.Age) + ")"
+}
+
+// Greeting returns a greeting
+func (p Person) Greeting() string {
+	return "Hello " + p.Name
+}
+
+// Data is a dynamic context, used by the mustache specs templates
+type Data map[string]interface{}
+
+// Templates lists the templates to generate, with the name of their context type
+var Templates = []struct {
+	Name   string
+	Type   string
+	Source string
+}{
+	// person templates
+	{"Interpolation", "Person", `{{name}} {{age}} {{height}} {{admin}} {{bio}} {{{bio}}} {{&bio}} {{level}} {{badge}} {{nick}} [{{missing}}] [{{secret}}]`},
+	{"Dotted", "Person", `{{company.name}} {{company.address.city}} {{company.address.zip}} {{company.ceo.name}} {{company.ceo.company.name}}`},
+	{"Parent", "Person", `{{#company}}{{name}} {{age}} {{../name}}{{#address}} {{city}} {{name}} {{../../age}}{{/address}}{{/company}}`},
+	{"Sections", "Person", `{{#admin}}admin{{/admin}}{{^admin}}user{{/admin}} {{#friends}}{{name}},{{/friends}}{{^friends}}none{{/friends}} {{#tags}}[{{.}}]{{/tags}}{{^tags}}no tags{{/tags}} {{#company}}{{name}}{{else}}no company{{/company}}`},
+	{"Builtins", "Person", `{{#if admin}}A{{else if friends}}F{{else}}U{{/if}} {{#unless tags}}untagged{{/unless}} {{#with company}}{{name}} ({{../name}}){{else}}no company{{/with}} {{#each friends}}{{@index}}:{{name}}{{#if @first}} first{{/if}}{{#if @last}} last{{/if}}{{#unless @last}}, {{/unless}}{{else}}nobody{{/each}}`},
+	{"Each", "Person", `{{#each tags}}{{@index}}={{this}}/{{@last}} {{/each}}{{#each scores}}{{@key}}={{this}} {{/each}}{{#each friends}}{{#each tags}}{{@../index}}.{{@index}}:{{.}} {{/each}}{{/each}}`},
+	{"BlockParams", "Person", `{{#each friends as |friend i|}}{{i}}={{friend.name}} {{/each}}{{#each scores as |score subject|}}{{subject}}:{{score}} {{/each}}{{#with company as |c|}}{{c.name}} {{name}}{{/with}}`},
+	{"Maps", "Person", `{{scores.math}} [{{scores.art}}] {{#with scores}}{{math}} {{name}}{{/with}}`},
+	{"Indexes", "Person", `{{friends.[0].name}} {{tags.[1]}} [{{tags.[5]}}] {{friends.[0].age}}`},
+	{"Methods", "Person", `{{fullName}} {{greeting}} {{#greeting}}ignored{{/greeting}} {{#company}}{{summary}}{{/company}} {{#each team}}{{fullName}} {{greeting}}, {{/each}}`},
+	{"Root", "Person", `{{#each friends}}{{name}}/{{@root.name}}/{{@root.company.name}} {{/each}}`},
+	{"Whitespace", "Person", "{{#each tags}}\n  {{~this~}}  \n{{/each}}\n{{#if admin}}\n  admin\n{{/if}}\n{{! comment }}\n{{name}}"},
+	{"Helpers", "Person", `{{shout name}} {{link "Home" url=company.website}} {{link "Home" url=company.url}} {{{link name url="/x"}}} {{#if (shout bio)}}yes{{/if}} {{shout nick suffix=age}} {{#each tags}}{{shout this}}{{/each}}`},
+	{"Fallback", "Person", `{{> header}} {{name}}`},
+
+	// mustache specs templates
+	{"SpecNoInterpolation", "Data", `Hello from {Mustache}!`},
+	{"SpecBasicInterpolation", "Data", `Hello, {{subject}}!`},
+	{"SpecHTMLEscaping", "Data", `These characters should be HTML escaped: {{forbidden}}`},
+	{"SpecTripleMustache", "Data", `These characters should not be HTML escaped: {{{forbidden}}}`},
+	{"SpecAmpersand", "Data", `These characters should not be HTML escaped: {{&forbidden}}`},
+	{"SpecIntegerInterpolation", "Data", `"{{mph}} miles an hour!"`},
+	{"SpecDecimalInterpolation", "Data", `"{{power}} jiggawatts!"`},
+	{"SpecNullInterpolation", "Data", `I ({{cannot}}) be seen!`},
+	{"SpecDottedNames", "Data", `"{{person.name}}" == "{{#person}}{{name}}{{/person}}"`},
+	{"SpecStandalone", "Data", "  {{string}}\n"},
+	{"SpecPadding", "Data", `|{{ string }}|`},
+	{"SpecFalsey", "Data", `"{{^boolean}}This should be rendered.{{/boolean}}"`},
+	{"SpecTruthy", "Data", `"{{#boolean}}This should be rendered.{{/boolean}}"`},
+	{"SpecList", "Data", `"{{#list}}{{item}}{{/list}}"`},
+	{"SpecImplicitIterator", "Data", `"{{#list}}({{.}}){{/list}}"`},
+	{"SpecComment", "Data", `12345{{! Comment Block! }}67890`},
+	{"SpecStandaloneComment", "Data", "Begin.\n{{! Comment Block! }}\nEnd.\n"},
+	{"SpecStandaloneLines", "Data", "| This Is\n{{#boolean}}\n|\n{{/boolean}}\n| A Line\n"},
+}
+
+// Helpers lists the helpers that templates can call
+var Helpers = []string{"shout", "link"}
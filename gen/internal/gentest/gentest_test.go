@@ -0,0 +1,153 @@
+package gentest
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aymerick/raymond"
+)
+
+func init() {
+	raymond.RegisterHelper("shout", func(str string, options *raymond.Options) string {
+		return strings.ToUpper(str) + options.HashStr("suffix") + "!"
+	})
+
+	raymond.RegisterHelper("link", func(text string, options *raymond.Options) raymond.SafeString {
+		return raymond.SafeString(`<a href="` + raymond.Escape(options.HashStr("url")) + `">` + raymond.Escape(text) + `</a>`)
+	})
+
+	raymond.RegisterPartial("header", `<h1>{{name}}</h1>`)
+}
+
+func testPerson() *Person {
+	return &Person{
+		Name:     "Alan",
+		Age:      42,
+		Height:   1.85,
+		Admin:    true,
+		Bio:      "<b>Go</b> & co",
+		Level:    1,
+		Badge:    "<i>gold</i>",
+		Nickname: "al",
+		Company: &Company{
+			Name:    "Acme",
+			Website: "http://acme.com?a=1&b=2",
+			Address: &Address{City: "Paris", Zip: 75001},
+			Boss:    &Person{Name: "Bob", Company: &Company{Name: "Boss Corp"}},
+		},
+		Friends: []*Person{{Name: "Ada", Tags: []string{"x", "y"}}, nil, {Name: "Linus", Age: 7}},
+		Tags:    []string{"go", "hbs"},
+		Scores:  map[string]int{"math": 18},
+		Team:    []Person{{Name: "Tim"}, {Name: "Tom"}},
+		secret:  "secret",
+	}
+}
+
+type genTest struct {
+	name   string
+	render interface{}
+	ctx    interface{}
+	output string
+}
+
+var genTests = []genTest{
+	{"Interpolation", RenderInterpolation, testPerson(), `Alan 42 1.85 true &lt;b&gt;Go&lt;/b&gt; &amp; co <b>Go</b> & co <b>Go</b> & co 1 <i>gold</i> al [] []`},
+	{"Interpolation", RenderInterpolation, &Person{}, ` 0 0 false    0   [] []`},
+	{"Interpolation", RenderInterpolation, (*Person)(nil), `          [] []`},
+	{"Dotted", RenderDotted, testPerson(), `Acme Paris 75001 Bob Boss Corp`},
+	{"Dotted", RenderDotted, &Person{Company: &Company{Name: "Acme"}}, `Acme    `},
+	{"Dotted", RenderDotted, (*Person)(nil), `    `},
+	{"Parent", RenderParent, testPerson(), `Acme 42 Alan Paris Acme 42`},
+	{"Parent", RenderParent, &Person{Name: "Alan", Company: &Company{Name: "Acme"}}, `Acme 0 Alan`},
+	{"Sections", RenderSections, testPerson(), `admin Ada,Alan,Linus, [go][hbs] Acme`},
+	{"Sections", RenderSections, &Person{}, `user none no tags no company`},
+	{"Builtins", RenderBuiltins, testPerson(), `A  Acme (Alan) 0:Ada first, 1:Alan, 2:Linus last`},
+	{"Builtins", RenderBuiltins, &Person{Friends: []*Person{{Name: "Ada"}}}, `F untagged no company 0:Ada first last`},
+	{"Builtins", RenderBuiltins, &Person{}, `U untagged no company nobody`},
+	{"Each", RenderEach, testPerson(), `0=go/false 1=hbs/true math=18 0.0:x 0.1:y 1.0:go 1.1:hbs `},
+	{"Each", RenderEach, &Person{Tags: []string{"a"}, Friends: []*Person{nil}}, `0=a/true 0.0:a `},
+	{"BlockParams", RenderBlockParams, testPerson(), `0=Ada 1= 2=Linus math:18 Acme Acme`},
+	{"BlockParams", RenderBlockParams, &Person{}, ``},
+	{"Maps", RenderMaps, testPerson(), `18 [] 18 Alan`},
+	{"Maps", RenderMaps, &Person{Name: "Alan", Scores: map[string]int{"art": 12, "name": 3}}, ` [12]  3`},
+	{"Indexes", RenderIndexes, testPerson(), `Ada hbs [] 0`},
+	{"Indexes", RenderIndexes, &Person{Friends: []*Person{nil}}, `  [] `},
+	{"Methods", RenderMethods, testPerson(), `Alan (42) Hello Alan Hello Alan Acme in Paris Alan (42) Hello Tim, Alan (42) Hello Tom, `},
+	{"Methods", RenderMethods, &Person{}, ` (0) Hello  Hello   `},
+	{"Root", RenderRoot, testPerson(), `Ada/Alan/Acme Alan/Alan/Acme Linus/Alan/Acme `},
+	{"Whitespace", RenderWhitespace, testPerson(), "gohbs  admin\nAlan"},
+	{"Whitespace", RenderWhitespace, &Person{}, ``},
+	{"Helpers", RenderHelpers, testPerson(), `ALAN! <a href="http://acme.com?a=1&amp;b=2">Home</a> <a href="">Home</a> <a href="/x">Alan</a> yes AL42! GO!HBS!`},
+	{"Helpers", RenderHelpers, &Person{}, `! <a href="">Home</a> <a href="">Home</a> <a href="/x"></a> yes 0! `},
+	{"Fallback", RenderFallback, testPerson(), `<h1>Alan</h1> Alan`},
+
+	{"SpecNoInterpolation", RenderSpecNoInterpolation, &Data{}, `Hello from {Mustache}!`},
+	{"SpecBasicInterpolation", RenderSpecBasicInterpolation, &Data{"subject": "world"}, `Hello, world!`},
+	{"SpecHTMLEscaping", RenderSpecHTMLEscaping, &Data{"forbidden": `& " < >`}, `These characters should be HTML escaped: &amp; &quot; &lt; &gt;`},
+	{"SpecTripleMustache", RenderSpecTripleMustache, &Data{"forbidden": `& " < >`}, `These characters should not be HTML escaped: & " < >`},
+	{"SpecAmpersand", RenderSpecAmpersand, &Data{"forbidden": `& " < >`}, `These characters should not be HTML escaped: & " < >`},
+	{"SpecIntegerInterpolation", RenderSpecIntegerInterpolation, &Data{"mph": 85}, `"85 miles an hour!"`},
+	{"SpecDecimalInterpolation", RenderSpecDecimalInterpolation, &Data{"power": 1.210}, `"1.21 jiggawatts!"`},
+	{"SpecNullInterpolation", RenderSpecNullInterpolation, &Data{"cannot": nil}, `I () be seen!`},
+	{"SpecNullInterpolation", RenderSpecNullInterpolation, &Data{}, `I () be seen!`},
+	{"SpecDottedNames", RenderSpecDottedNames, &Data{"person": map[string]interface{}{"name": "Joe"}}, `"Joe" == "Joe"`},
+	{"SpecStandalone", RenderSpecStandalone, &Data{"string": "---"}, "  ---\n"},
+	{"SpecPadding", RenderSpecPadding, &Data{"string": "---"}, `|---|`},
+	{"SpecFalsey", RenderSpecFalsey, &Data{"boolean": false}, `"This should be rendered."`},
+	{"SpecFalsey", RenderSpecFalsey, &Data{"boolean": true}, `""`},
+	{"SpecTruthy", RenderSpecTruthy, &Data{"boolean": true}, `"This should be rendered."`},
+	{"SpecList", RenderSpecList, &Data{"list": []map[string]interface{}{{"item": 1}, {"item": 2}, {"item": 3}}}, `"123"`},
+	{"SpecImplicitIterator", RenderSpecImplicitIterator, &Data{"list": []string{"a", "b", "c"}}, `"(a)(b)(c)"`},
+	{"SpecComment", RenderSpecComment, &Data{}, `1234567890`},
+	{"SpecStandaloneComment", RenderSpecStandaloneComment, &Data{}, "Begin.\nEnd.\n"},
+	{"SpecStandaloneLines", RenderSpecStandaloneLines, &Data{"boolean": true}, "| This Is\n|\n| A Line\n"},
+}
+
+// templateSource returns the source of template with given name
+func templateSource(t *testing.T, name string) string {
+	for _, tpl := range Templates {
+		if tpl.Name == name {
+			return tpl.Source
+		}
+	}
+
+	t.Fatalf("Template not found: %s", name)
+	return ""
+}
+
+// exec renders given test with its generated function
+func (test genTest) exec(t *testing.T) string {
+	buf := new(bytes.Buffer)
+
+	results := reflect.ValueOf(test.render).Call([]reflect.Value{reflect.ValueOf(io.Writer(buf)), reflect.ValueOf(test.ctx)})
+	if err := results[0].Interface(); err != nil {
+		t.Errorf("Test '%s' failed\nunexpected error: %s", test.name, err)
+	}
+
+	return buf.String()
+}
+
+func TestGeneratedTemplates(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range genTests {
+		expected, err := raymond.MustParse(templateSource(t, test.name)).Exec(test.ctx)
+		if err != nil {
+			t.Errorf("Test '%s' failed\nunexpected interpreter error: %s", test.name, err)
+			continue
+		}
+
+		output := test.exec(t)
+
+		if output != expected {
+			t.Errorf("Test '%s' failed\ngenerated output:\n\t%q\ninterpreter output:\n\t%q", test.name, output, expected)
+		}
+
+		if output != test.output {
+			t.Errorf("Test '%s' failed\nexpected:\n\t%q\ngot:\n\t%q", test.name, test.output, output)
+		}
+	}
+}
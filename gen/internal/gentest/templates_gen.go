@@ -0,0 +1,835 @@
+// Code generated by raymond-gen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"io"
+
+	"github.com/aymerick/raymond"
+	"github.com/aymerick/raymond/gen/rt"
+)
+
+// RenderInterpolation renders the Interpolation template with given context.
+func RenderInterpolation(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		out.WriteEscaped(ctx.Name)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		out.WriteInt(int64(ctx.Age))
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		out.WriteFloat(ctx.Height)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		out.WriteBool(ctx.Admin)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		out.WriteEscaped(ctx.Bio)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		out.WriteString(ctx.Bio)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		out.WriteString(ctx.Bio)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		out.WriteValue(ctx.Level, true)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		out.WriteString(string(ctx.Badge))
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		out.WriteEscaped(ctx.Nickname)
+	}
+	out.WriteString(" [")
+	out.WriteString("] [")
+	out.WriteString("]")
+	return out.Err()
+}
+
+// RenderDotted renders the Dotted template with given context.
+func RenderDotted(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		if ctx.Company != nil {
+			out.WriteEscaped(ctx.Company.Name)
+		}
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		if ctx.Company != nil {
+			if ctx.Company.Address != nil {
+				out.WriteEscaped(ctx.Company.Address.City)
+			}
+		}
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		if ctx.Company != nil {
+			if ctx.Company.Address != nil {
+				out.WriteInt(int64(ctx.Company.Address.Zip))
+			}
+		}
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		if ctx.Company != nil {
+			if ctx.Company.Boss != nil {
+				out.WriteEscaped(ctx.Company.Boss.Name)
+			}
+		}
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		if ctx.Company != nil {
+			if ctx.Company.Boss != nil {
+				if ctx.Company.Boss.Company != nil {
+					out.WriteEscaped(ctx.Company.Boss.Company.Name)
+				}
+			}
+		}
+	}
+	return out.Err()
+}
+
+// RenderParent renders the Parent template with given context.
+func RenderParent(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		if ctx.Company != nil {
+			out.WriteEscaped(ctx.Company.Name)
+			out.WriteString(" ")
+			if ctx != nil {
+				out.WriteInt(int64(ctx.Age))
+			}
+			out.WriteString(" ")
+			if ctx != nil {
+				out.WriteEscaped(ctx.Name)
+			}
+			if ctx.Company.Address != nil {
+				out.WriteString(" ")
+				out.WriteEscaped(ctx.Company.Address.City)
+				out.WriteString(" ")
+				out.WriteEscaped(ctx.Company.Name)
+				out.WriteString(" ")
+				if ctx != nil {
+					out.WriteInt(int64(ctx.Age))
+				}
+			}
+		}
+	}
+	return out.Err()
+}
+
+// RenderSections renders the Sections template with given context.
+func RenderSections(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		if ctx.Admin {
+			out.WriteString("admin")
+		}
+	}
+	ok1 := false
+	if ctx != nil {
+		if ctx.Admin {
+			ok1 = true
+		}
+	}
+	if !ok1 {
+		out.WriteString("user")
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		if len(ctx.Friends) > 0 {
+			for i2 := range ctx.Friends {
+				if ctx.Friends[i2] != nil {
+					out.WriteEscaped(ctx.Friends[i2].Name)
+				} else {
+					if ctx != nil {
+						out.WriteEscaped(ctx.Name)
+					}
+				}
+				out.WriteString(",")
+			}
+		}
+	}
+	ok4 := false
+	if ctx != nil {
+		if len(ctx.Friends) > 0 {
+			ok4 = true
+		}
+	}
+	if !ok4 {
+		out.WriteString("none")
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		if len(ctx.Tags) > 0 {
+			for i5 := range ctx.Tags {
+				out.WriteString("[")
+				out.WriteEscaped(ctx.Tags[i5])
+				out.WriteString("]")
+			}
+		}
+	}
+	ok7 := false
+	if ctx != nil {
+		if len(ctx.Tags) > 0 {
+			ok7 = true
+		}
+	}
+	if !ok7 {
+		out.WriteString("no tags")
+	}
+	out.WriteString(" ")
+	ok8 := false
+	if ctx != nil {
+		if ctx.Company != nil {
+			ok8 = true
+			out.WriteEscaped(ctx.Company.Name)
+		}
+	}
+	if !ok8 {
+		out.WriteString("no company")
+	}
+	return out.Err()
+}
+
+// RenderBuiltins renders the Builtins template with given context.
+func RenderBuiltins(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	ok1 := false
+	if ctx != nil {
+		if ctx.Admin {
+			ok1 = true
+		}
+	}
+	if ok1 {
+		out.WriteString("A")
+	} else {
+		ok2 := false
+		if ctx != nil {
+			if len(ctx.Friends) > 0 {
+				ok2 = true
+			}
+		}
+		if ok2 {
+			out.WriteString("F")
+		} else {
+			out.WriteString("U")
+		}
+	}
+	out.WriteString(" ")
+	ok3 := false
+	if ctx != nil {
+		if len(ctx.Tags) > 0 {
+			ok3 = true
+		}
+	}
+	if !ok3 {
+		out.WriteString("untagged")
+	}
+	out.WriteString(" ")
+	ok4 := false
+	if ctx != nil {
+		if ctx.Company != nil {
+			ok4 = true
+			out.WriteEscaped(ctx.Company.Name)
+			out.WriteString(" (")
+			if ctx != nil {
+				out.WriteEscaped(ctx.Name)
+			}
+			out.WriteString(")")
+		}
+	}
+	if !ok4 {
+		out.WriteString("no company")
+	}
+	out.WriteString(" ")
+	ok5 := false
+	if ctx != nil {
+		if len(ctx.Friends) > 0 {
+			ok5 = true
+			n7 := len(ctx.Friends)
+			for i6 := range ctx.Friends {
+				out.WriteInt(int64(i6))
+				out.WriteString(":")
+				if ctx.Friends[i6] != nil {
+					out.WriteEscaped(ctx.Friends[i6].Name)
+				} else {
+					if ctx != nil {
+						out.WriteEscaped(ctx.Name)
+					}
+				}
+				ok8 := false
+				if i6 == 0 {
+					ok8 = true
+				}
+				if ok8 {
+					out.WriteString(" first")
+				}
+				ok9 := false
+				if i6 == n7-1 {
+					ok9 = true
+				}
+				if ok9 {
+					out.WriteString(" last")
+				}
+				ok10 := false
+				if i6 == n7-1 {
+					ok10 = true
+				}
+				if !ok10 {
+					out.WriteString(", ")
+				}
+			}
+		}
+	}
+	if !ok5 {
+		out.WriteString("nobody")
+	}
+	return out.Err()
+}
+
+// RenderEach renders the Each template with given context.
+func RenderEach(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		if len(ctx.Tags) > 0 {
+			n2 := len(ctx.Tags)
+			for i1 := range ctx.Tags {
+				out.WriteInt(int64(i1))
+				out.WriteString("=")
+				out.WriteEscaped(ctx.Tags[i1])
+				out.WriteString("/")
+				out.WriteBool(i1 == n2-1)
+				out.WriteString(" ")
+			}
+		}
+	}
+	if ctx != nil {
+		if len(ctx.Scores) > 0 {
+			for k5, e6 := range ctx.Scores {
+				out.WriteEscaped(k5)
+				out.WriteString("=")
+				out.WriteInt(int64(e6))
+				out.WriteString(" ")
+			}
+		}
+	}
+	if ctx != nil {
+		if len(ctx.Friends) > 0 {
+			for i7 := range ctx.Friends {
+				if ctx.Friends[i7] != nil {
+					if len(ctx.Friends[i7].Tags) > 0 {
+						for i9 := range ctx.Friends[i7].Tags {
+							out.WriteInt(int64(i7))
+							out.WriteString(".")
+							out.WriteInt(int64(i9))
+							out.WriteString(":")
+							out.WriteEscaped(ctx.Friends[i7].Tags[i9])
+							out.WriteString(" ")
+						}
+					}
+				} else {
+					if ctx != nil {
+						if len(ctx.Tags) > 0 {
+							for i11 := range ctx.Tags {
+								out.WriteInt(int64(i7))
+								out.WriteString(".")
+								out.WriteInt(int64(i11))
+								out.WriteString(":")
+								out.WriteEscaped(ctx.Tags[i11])
+								out.WriteString(" ")
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return out.Err()
+}
+
+// RenderBlockParams renders the BlockParams template with given context.
+func RenderBlockParams(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		if len(ctx.Friends) > 0 {
+			for i1 := range ctx.Friends {
+				out.WriteInt(int64(i1))
+				out.WriteString("=")
+				if ctx.Friends[i1] != nil {
+					out.WriteEscaped(ctx.Friends[i1].Name)
+				}
+				out.WriteString(" ")
+			}
+		}
+	}
+	if ctx != nil {
+		if len(ctx.Scores) > 0 {
+			for k5, e6 := range ctx.Scores {
+				out.WriteEscaped(k5)
+				out.WriteString(":")
+				out.WriteInt(int64(e6))
+				out.WriteString(" ")
+			}
+		}
+	}
+	if ctx != nil {
+		if ctx.Company != nil {
+			out.WriteEscaped(ctx.Company.Name)
+			out.WriteString(" ")
+			out.WriteEscaped(ctx.Company.Name)
+		}
+	}
+	return out.Err()
+}
+
+// RenderMaps renders the Maps template with given context.
+func RenderMaps(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		if v1, ok := ctx.Scores["math"]; ok {
+			out.WriteInt(int64(v1))
+		}
+	}
+	out.WriteString(" [")
+	if ctx != nil {
+		if v2, ok := ctx.Scores["art"]; ok {
+			out.WriteInt(int64(v2))
+		}
+	}
+	out.WriteString("] ")
+	if ctx != nil {
+		if len(ctx.Scores) > 0 {
+			if v3, ok := ctx.Scores["math"]; ok {
+				out.WriteInt(int64(v3))
+			}
+			out.WriteString(" ")
+			if v4, ok := ctx.Scores["name"]; ok {
+				out.WriteInt(int64(v4))
+			} else {
+				if ctx != nil {
+					out.WriteEscaped(ctx.Name)
+				}
+			}
+		}
+	}
+	return out.Err()
+}
+
+// RenderIndexes renders the Indexes template with given context.
+func RenderIndexes(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		if len(ctx.Friends) > 0 {
+			if ctx.Friends[0] != nil {
+				out.WriteEscaped(ctx.Friends[0].Name)
+			}
+		}
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		if len(ctx.Tags) > 1 {
+			out.WriteEscaped(ctx.Tags[1])
+		}
+	}
+	out.WriteString(" [")
+	if ctx != nil {
+		if len(ctx.Tags) > 5 {
+			out.WriteEscaped(ctx.Tags[5])
+		}
+	}
+	out.WriteString("] ")
+	if ctx != nil {
+		if len(ctx.Friends) > 0 {
+			if ctx.Friends[0] != nil {
+				out.WriteInt(int64(ctx.Friends[0].Age))
+			}
+		}
+	}
+	return out.Err()
+}
+
+// RenderMethods renders the Methods template with given context.
+func RenderMethods(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		v1 := ctx.FullName()
+		out.WriteEscaped(v1)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		v2 := ctx.Greeting()
+		out.WriteEscaped(v2)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		v3 := ctx.Greeting()
+		out.WriteString(v3)
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		if ctx.Company != nil {
+			v4 := ctx.Company.Summary()
+			out.WriteEscaped(v4)
+		}
+	}
+	out.WriteString(" ")
+	if ctx != nil {
+		if len(ctx.Team) > 0 {
+			for i5 := range ctx.Team {
+				if ctx != nil {
+					v7 := ctx.FullName()
+					out.WriteEscaped(v7)
+				}
+				out.WriteString(" ")
+				v8 := ctx.Team[i5].Greeting()
+				out.WriteEscaped(v8)
+				out.WriteString(", ")
+			}
+		}
+	}
+	return out.Err()
+}
+
+// RenderRoot renders the Root template with given context.
+func RenderRoot(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		if len(ctx.Friends) > 0 {
+			for i1 := range ctx.Friends {
+				if ctx.Friends[i1] != nil {
+					out.WriteEscaped(ctx.Friends[i1].Name)
+				} else {
+					if ctx != nil {
+						out.WriteEscaped(ctx.Name)
+					}
+				}
+				out.WriteString("/")
+				if ctx != nil {
+					out.WriteEscaped(ctx.Name)
+				}
+				out.WriteString("/")
+				if ctx != nil {
+					if ctx.Company != nil {
+						out.WriteEscaped(ctx.Company.Name)
+					}
+				}
+				out.WriteString(" ")
+			}
+		}
+	}
+	return out.Err()
+}
+
+// RenderWhitespace renders the Whitespace template with given context.
+func RenderWhitespace(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	if ctx != nil {
+		if len(ctx.Tags) > 0 {
+			for i1 := range ctx.Tags {
+				out.WriteEscaped(ctx.Tags[i1])
+			}
+		}
+	}
+	ok3 := false
+	if ctx != nil {
+		if ctx.Admin {
+			ok3 = true
+		}
+	}
+	if ok3 {
+		out.WriteString("  admin\n")
+	}
+	if ctx != nil {
+		out.WriteEscaped(ctx.Name)
+	}
+	return out.Err()
+}
+
+// RenderHelpers renders the Helpers template with given context.
+func RenderHelpers(w io.Writer, ctx *Person) error {
+	out := rt.NewWriter(w)
+	var p1 interface{}
+	if ctx != nil {
+		p1 = ctx.Name
+	}
+	r2 := out.Helper("shout", ctx, []interface{}{p1}, nil)
+	out.WriteValue(r2, true)
+	out.WriteString(" ")
+	var p3 interface{}
+	if ctx != nil {
+		if ctx.Company != nil {
+			p3 = ctx.Company.Website
+		}
+	}
+	r4 := out.Helper("link", ctx, []interface{}{"Home"}, map[string]interface{}{"url": p3})
+	out.WriteValue(r4, true)
+	out.WriteString(" ")
+	r6 := out.Helper("link", ctx, []interface{}{"Home"}, map[string]interface{}{"url": nil})
+	out.WriteValue(r6, true)
+	out.WriteString(" ")
+	var p7 interface{}
+	if ctx != nil {
+		p7 = ctx.Name
+	}
+	r8 := out.Helper("link", ctx, []interface{}{p7}, map[string]interface{}{"url": "/x"})
+	out.WriteValue(r8, false)
+	out.WriteString(" ")
+	var p9 interface{}
+	if ctx != nil {
+		p9 = ctx.Bio
+	}
+	r10 := out.Helper("shout", ctx, []interface{}{p9}, nil)
+	if rt.IsTrue(r10) {
+		out.WriteString("yes")
+	}
+	out.WriteString(" ")
+	var p11 interface{}
+	if ctx != nil {
+		p11 = ctx.Nickname
+	}
+	var p12 interface{}
+	if ctx != nil {
+		p12 = ctx.Age
+	}
+	r13 := out.Helper("shout", ctx, []interface{}{p11}, map[string]interface{}{"suffix": p12})
+	out.WriteValue(r13, true)
+	out.WriteString(" ")
+	if ctx != nil {
+		if len(ctx.Tags) > 0 {
+			for i14 := range ctx.Tags {
+				r17 := out.Helper("shout", ctx.Tags[i14], []interface{}{ctx.Tags[i14]}, nil)
+				out.WriteValue(r17, true)
+			}
+		}
+	}
+	return out.Err()
+}
+
+// tplFallback is the Fallback template, evaluated by the interpreter because:
+// Unsupported construct at line 1: partials
+var tplFallback = raymond.MustParse(`{{> header}} {{name}}`)
+
+// RenderFallback renders the Fallback template with given context.
+func RenderFallback(w io.Writer, ctx *Person) error {
+	return tplFallback.ExecTo(w, ctx)
+}
+
+// RenderSpecNoInterpolation renders the SpecNoInterpolation template with given context.
+func RenderSpecNoInterpolation(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("Hello from {Mustache}!")
+	return out.Err()
+}
+
+// RenderSpecBasicInterpolation renders the SpecBasicInterpolation template with given context.
+func RenderSpecBasicInterpolation(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("Hello, ")
+	if ctx != nil {
+		if v1, ok := (*ctx)["subject"]; ok {
+			out.WriteValue(v1, true)
+		}
+	}
+	out.WriteString("!")
+	return out.Err()
+}
+
+// RenderSpecHTMLEscaping renders the SpecHTMLEscaping template with given context.
+func RenderSpecHTMLEscaping(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("These characters should be HTML escaped: ")
+	if ctx != nil {
+		if v1, ok := (*ctx)["forbidden"]; ok {
+			out.WriteValue(v1, true)
+		}
+	}
+	return out.Err()
+}
+
+// RenderSpecTripleMustache renders the SpecTripleMustache template with given context.
+func RenderSpecTripleMustache(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("These characters should not be HTML escaped: ")
+	if ctx != nil {
+		if v1, ok := (*ctx)["forbidden"]; ok {
+			out.WriteValue(v1, false)
+		}
+	}
+	return out.Err()
+}
+
+// RenderSpecAmpersand renders the SpecAmpersand template with given context.
+func RenderSpecAmpersand(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("These characters should not be HTML escaped: ")
+	if ctx != nil {
+		if v1, ok := (*ctx)["forbidden"]; ok {
+			out.WriteValue(v1, false)
+		}
+	}
+	return out.Err()
+}
+
+// RenderSpecIntegerInterpolation renders the SpecIntegerInterpolation template with given context.
+func RenderSpecIntegerInterpolation(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("\"")
+	if ctx != nil {
+		if v1, ok := (*ctx)["mph"]; ok {
+			out.WriteValue(v1, true)
+		}
+	}
+	out.WriteString(" miles an hour!\"")
+	return out.Err()
+}
+
+// RenderSpecDecimalInterpolation renders the SpecDecimalInterpolation template with given context.
+func RenderSpecDecimalInterpolation(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("\"")
+	if ctx != nil {
+		if v1, ok := (*ctx)["power"]; ok {
+			out.WriteValue(v1, true)
+		}
+	}
+	out.WriteString(" jiggawatts!\"")
+	return out.Err()
+}
+
+// RenderSpecNullInterpolation renders the SpecNullInterpolation template with given context.
+func RenderSpecNullInterpolation(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("I (")
+	if ctx != nil {
+		if v1, ok := (*ctx)["cannot"]; ok {
+			out.WriteValue(v1, true)
+		}
+	}
+	out.WriteString(") be seen!")
+	return out.Err()
+}
+
+// tplSpecDottedNames is the SpecDottedNames template, evaluated by the interpreter because:
+// Unsupported construct at line 1: lookup of "name" in a value of interface type interface{}
+var tplSpecDottedNames = raymond.MustParse(`"{{person.name}}" == "{{#person}}{{name}}{{/person}}"`)
+
+// RenderSpecDottedNames renders the SpecDottedNames template with given context.
+func RenderSpecDottedNames(w io.Writer, ctx *Data) error {
+	return tplSpecDottedNames.ExecTo(w, ctx)
+}
+
+// RenderSpecStandalone renders the SpecStandalone template with given context.
+func RenderSpecStandalone(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("  ")
+	if ctx != nil {
+		if v1, ok := (*ctx)["string"]; ok {
+			out.WriteValue(v1, true)
+		}
+	}
+	out.WriteString("\n")
+	return out.Err()
+}
+
+// RenderSpecPadding renders the SpecPadding template with given context.
+func RenderSpecPadding(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("|")
+	if ctx != nil {
+		if v1, ok := (*ctx)["string"]; ok {
+			out.WriteValue(v1, true)
+		}
+	}
+	out.WriteString("|")
+	return out.Err()
+}
+
+// RenderSpecFalsey renders the SpecFalsey template with given context.
+func RenderSpecFalsey(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("\"")
+	ok1 := false
+	if ctx != nil {
+		if v2, ok := (*ctx)["boolean"]; ok {
+			if rt.IsTrue(v2) {
+				ok1 = true
+			}
+		}
+	}
+	if !ok1 {
+		out.WriteString("This should be rendered.")
+	}
+	out.WriteString("\"")
+	return out.Err()
+}
+
+// tplSpecTruthy is the SpecTruthy template, evaluated by the interpreter because:
+// Unsupported construct at line 1: section on type interface{}
+var tplSpecTruthy = raymond.MustParse(`"{{#boolean}}This should be rendered.{{/boolean}}"`)
+
+// RenderSpecTruthy renders the SpecTruthy template with given context.
+func RenderSpecTruthy(w io.Writer, ctx *Data) error {
+	return tplSpecTruthy.ExecTo(w, ctx)
+}
+
+// tplSpecList is the SpecList template, evaluated by the interpreter because:
+// Unsupported construct at line 1: section on type interface{}
+var tplSpecList = raymond.MustParse(`"{{#list}}{{item}}{{/list}}"`)
+
+// RenderSpecList renders the SpecList template with given context.
+func RenderSpecList(w io.Writer, ctx *Data) error {
+	return tplSpecList.ExecTo(w, ctx)
+}
+
+// tplSpecImplicitIterator is the SpecImplicitIterator template, evaluated by the interpreter because:
+// Unsupported construct at line 1: section on type interface{}
+var tplSpecImplicitIterator = raymond.MustParse(`"{{#list}}({{.}}){{/list}}"`)
+
+// RenderSpecImplicitIterator renders the SpecImplicitIterator template with given context.
+func RenderSpecImplicitIterator(w io.Writer, ctx *Data) error {
+	return tplSpecImplicitIterator.ExecTo(w, ctx)
+}
+
+// RenderSpecComment renders the SpecComment template with given context.
+func RenderSpecComment(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("12345")
+	out.WriteString("67890")
+	return out.Err()
+}
+
+// RenderSpecStandaloneComment renders the SpecStandaloneComment template with given context.
+func RenderSpecStandaloneComment(w io.Writer, ctx *Data) error {
+	out := rt.NewWriter(w)
+	out.WriteString("Begin.\n")
+	out.WriteString("End.\n")
+	return out.Err()
+}
+
+// tplSpecStandaloneLines is the SpecStandaloneLines template, evaluated by the interpreter because:
+// Unsupported construct at line 2: section on type interface{}
+var tplSpecStandaloneLines = raymond.MustParse(`| This Is
+{{#boolean}}
+|
+{{/boolean}}
+| A Line
+`)
+
+// RenderSpecStandaloneLines renders the SpecStandaloneLines template with given context.
+func RenderSpecStandaloneLines(w io.Writer, ctx *Data) error {
+	return tplSpecStandaloneLines.ExecTo(w, ctx)
+}
@@ -0,0 +1,588 @@
+package gen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+//
+// Paths are resolved at generation time, with the same rules as the interpreter: methods first, then struct fields,
+// struct tags, map keys and slice indexes. When the first part of a path is not found in a context, the parent
+// context is tried. A lookup that depends on runtime values (eg. a nil pointer, a missing map key) is generated
+// as a conditional branch.
+//
+
+// value represents a value computed by generated code
+type value struct {
+	expr string // Go expression
+	typ  types.Type
+
+	ptr         bool // expr is a non nil pointer to a value of type typ
+	addressable bool // the interpreter sees an addressable value, so methods with a pointer receiver can be called
+	funcCall    bool // value was computed by calling a method
+	indirect    bool // value was looked up, so the interpreter indirects it if it is a pointer
+	root        bool // value is the root context pointer: when nil, it is rendered like a nil interpreter context
+}
+
+// val returns the Go expression of value, as seen by the interpreter
+func (v value) val() string {
+	if v.ptr {
+		return "*" + v.expr
+	}
+
+	return v.expr
+}
+
+// ref returns the Go expression of value, suitable for indexing and ranging
+func (v value) ref() string {
+	if v.ptr {
+		return "(*" + v.expr + ")"
+	}
+
+	return v.expr
+}
+
+// frame represents a private data frame, set by iterations
+type frame struct {
+	index string
+	key   *value // nil if iteration keys are not set, ie. when iterating on a slice
+	first string
+	last  string
+}
+
+// get returns given private data value
+func (f *frame) get(name string) (value, bool) {
+	switch name {
+	case "index":
+		return value{expr: f.index, typ: types.Typ[types.Int]}, true
+	case "first":
+		return value{expr: f.first, typ: types.Typ[types.Bool]}, true
+	case "last":
+		return value{expr: f.last, typ: types.Typ[types.Bool]}, true
+	case "key":
+		if f.key != nil {
+			return *f.key, true
+		}
+	}
+
+	return value{}, false
+}
+
+// member represents the result of a path part lookup
+type member struct {
+	// conditions checked before computing value, eg. a nil pointer check
+	guards []string
+
+	// value binding: the result of a method call, or of a map lookup
+	bind    string
+	mapping bool // bind is a map index expression
+
+	value value
+}
+
+// conds returns the number of conditions that must be met for member to be found
+func (m *member) conds() int {
+	result := len(m.guards)
+
+	if m.mapping {
+		result++
+	}
+
+	return result
+}
+
+// stringerType is the fmt.Stringer interface type
+var stringerType = types.NewInterfaceType([]*types.Func{
+	types.NewFunc(token.NoPos, nil, "String", types.NewSignatureType(nil, nil, nil, nil, types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.String])), false)),
+}, nil).Complete()
+
+// errorType is the error interface type
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+// isSafeString returns true if given type is raymond.SafeString
+func isSafeString(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+
+	obj := named.Obj()
+
+	return (obj.Pkg() != nil) && (obj.Pkg().Path() == "github.com/aymerick/raymond") && (obj.Name() == "SafeString")
+}
+
+// isPrintable returns true if values of given type are printed with fmt.Stringer or error interface
+func isPrintable(typ types.Type) bool {
+	return types.Implements(typ, stringerType) || types.Implements(typ, errorType)
+}
+
+// isBasic returns true if given type is the given predeclared basic type
+func isBasic(typ types.Type, kind types.BasicKind) bool {
+	return types.Identical(typ, types.Typ[kind])
+}
+
+// pathParts returns the parts of given path expression
+func pathParts(node *ast.PathExpression) []string {
+	result := make([]string, len(node.Parts))
+
+	for i, part := range node.Parts {
+		// "[foo bar]"" => "foo bar"
+		if (len(part) >= 2) && (part[0] == '[') && (part[len(part)-1] == ']') {
+			part = part[1 : len(part)-1]
+		}
+
+		result[i] = part
+	}
+
+	return result
+}
+
+// lookup generates the code that resolves given path expression, and calls found with the resulting value
+//
+// Code generated by found is executed only if path is resolved.
+func (g *generator) lookup(node *ast.PathExpression, found func(value)) {
+	parts := pathParts(node)
+
+	if len(node.Parts) > 0 {
+		if param, ok := g.blockParam(node.Parts[0]); ok {
+			if node.Data || (node.Depth > 0) {
+				g.unsupported("block parameter %q used as a private variable or with a parent path", node.Parts[0])
+			}
+
+			g.resolveParts(param, parts[1:], found, true)
+			return
+		}
+	}
+
+	if node.Data {
+		g.lookupData(node, parts, found)
+		return
+	}
+
+	g.lookupCtx(node.Depth, parts, found)
+}
+
+// lookupData generates the code that resolves given private data path
+func (g *generator) lookupData(node *ast.PathExpression, parts []string, found func(value)) {
+	if node.IsDataRoot() {
+		g.resolveParts(g.scopes[0], parts[1:], found, len(parts) > 1)
+		return
+	}
+
+	if len(parts) > 1 {
+		g.unsupported("private variable path %q", node.Original)
+	}
+
+	if i := len(g.frames) - 1 - node.Depth; (i >= 0) && (g.frames[i] != nil) {
+		if val, ok := g.frames[i].get(parts[0]); ok {
+			g.resolveParts(val, nil, found, true)
+			return
+		}
+	}
+
+	// like the interpreter, a missing private variable is looked up in context
+	g.lookupCtx(node.Depth, parts, found)
+}
+
+// lookupCtx generates the code that resolves given path parts, starting with context at given depth
+func (g *generator) lookupCtx(depth int, parts []string, found func(value)) {
+	if len(parts) == 0 {
+		// eg: {{this}} or {{..}}
+		if i := len(g.scopes) - 1 - depth; i >= 0 {
+			found(g.scopes[i])
+		}
+
+		return
+	}
+
+	for ; depth < len(g.scopes); depth++ {
+		m := g.member(g.scopes[len(g.scopes)-1-depth], parts[0], true)
+		if m == nil {
+			// try with parent context
+			continue
+		}
+
+		parent := depth + 1
+
+		g.emitMember(m, func() {
+			g.resolveParts(m.value, parts[1:], found, true)
+		}, func() {
+			// first part was not found, try with parent context
+			g.lookupCtx(parent, parts, found)
+		})
+
+		return
+	}
+}
+
+// resolveParts generates the code that resolves given path parts on given value
+func (g *generator) resolveParts(val value, parts []string, found func(value), indirect bool) {
+	if len(parts) == 0 {
+		val.indirect = indirect
+		found(val)
+		return
+	}
+
+	m := g.member(val, parts[0], false)
+	if m == nil {
+		// missing
+		return
+	}
+
+	g.emitMember(m, func() {
+		g.resolveParts(m.value, parts[1:], found, true)
+	}, nil)
+}
+
+// emitMember generates the code that computes given member, then the code generated by found
+//
+// If member may not be found at runtime, the code generated by notFound is executed instead.
+func (g *generator) emitMember(m *member, found func(), notFound func()) {
+	body := g.capture(found)
+
+	name := m.value.expr
+	if !usesVar(body, name) {
+		name = "_"
+	}
+
+	var other string
+	if (notFound != nil) && (m.conds() > 0) {
+		other = g.capture(notFound)
+	}
+
+	if (body == "") && (other == "") && ((m.bind == "") || m.mapping) {
+		// nothing to compute
+		return
+	}
+
+	flag := ""
+	if (m.conds() > 1) && (other != "") {
+		flag = g.newVar("found")
+		g.printf("%s := false", flag)
+	}
+
+	closing := len(m.guards)
+
+	for _, guard := range m.guards {
+		g.printf("if %s {", guard)
+	}
+
+	switch {
+	case m.mapping:
+		g.printf("if %s, ok := %s; ok {", name, m.bind)
+		closing++
+	case m.bind == "":
+		// value is an expression
+	case name == "_":
+		// methods are called even if their result is not used
+		g.printf("%s", m.bind)
+	default:
+		g.printf("%s := %s", name, m.bind)
+	}
+
+	if flag != "" {
+		g.printf("%s = true", flag)
+	}
+
+	g.buf.WriteString(body)
+
+	if other == "" {
+		g.close(closing)
+		return
+	}
+
+	if flag != "" {
+		g.close(closing)
+		g.printf("if !%s {", flag)
+	} else {
+		g.printf("} else {")
+	}
+
+	g.buf.WriteString(other)
+
+	g.printf("}")
+}
+
+// blockParam returns the value of given block parameter
+func (g *generator) blockParam(name string) (value, bool) {
+	for i := len(g.params) - 1; i >= 0; i-- {
+		if val, ok := g.params[i][name]; ok {
+			return val, true
+		}
+	}
+
+	return value{}, false
+}
+
+// member looks up given path part in given value, and returns nil if it can't be found
+//
+// The scope argument is true if value is a context.
+func (g *generator) member(val value, name string, scope bool) *member {
+	result := &member{}
+
+	// pointers are indirected
+	if ptr, ok := val.typ.Underlying().(*types.Pointer); ok && !val.ptr {
+		g.checkPointer(ptr)
+
+		result.guards = append(result.guards, val.expr+" != nil")
+		val = value{expr: val.expr, typ: ptr.Elem(), ptr: true, addressable: true, funcCall: val.funcCall}
+	}
+
+	switch val.typ.Underlying().(type) {
+	case *types.Interface:
+		g.unsupported("lookup of %q in a value of interface type %s", name, g.typeString(val.typ))
+	case *types.Slice, *types.Array:
+		if scope {
+			g.unsupported("lookup of %q in a context of type %s", name, g.typeString(val.typ))
+		}
+	}
+
+	// methods first
+	if method := g.method(val, name); method != nil {
+		sig := method.Type().(*types.Signature)
+		if (sig.Params().Len() != 0) || (sig.Results().Len() != 1) {
+			g.unsupported("method %s with signature %s", method.Name(), sig)
+		}
+
+		result.bind = fmt.Sprintf("%s.%s()", val.expr, method.Name())
+		result.value = g.checkValue(value{expr: g.newVar("v"), typ: sig.Results().At(0).Type(), funcCall: true})
+
+		return result
+	}
+
+	switch u := val.typ.Underlying().(type) {
+	case *types.Struct:
+		// example: firstName => FirstName
+		if field := g.field(val, strings.Title(name)); field != nil {
+			result.value = g.checkValue(value{
+				expr:        val.expr + "." + field.Name(),
+				typ:         field.Type(),
+				addressable: val.addressable,
+				funcCall:    val.funcCall,
+			})
+
+			return result
+		}
+
+		// attempts to find name as a struct tag
+		for i := 0; i < u.NumFields(); i++ {
+			if reflect.StructTag(u.Tag(i)).Get("handlebars") != name {
+				continue
+			}
+
+			field := u.Field(i)
+			if !field.Exported() {
+				g.unsupported("unexported field %s with struct tag %q", field.Name(), name)
+			}
+
+			result.value = g.checkValue(value{
+				expr:     val.expr + "." + field.Name(),
+				typ:      field.Type(),
+				funcCall: val.funcCall,
+			})
+
+			return result
+		}
+
+	case *types.Map:
+		key := u.Key()
+		if iface, ok := key.Underlying().(*types.Interface); !isBasic(key, types.String) && !(ok && iface.Empty()) {
+			// key can't be a string
+			return nil
+		}
+
+		result.bind = fmt.Sprintf("%s[%q]", val.ref(), name)
+		result.mapping = true
+		result.value = g.checkValue(value{expr: g.newVar("v"), typ: u.Elem(), funcCall: val.funcCall})
+
+		return result
+
+	case *types.Slice, *types.Array:
+		i, err := strconv.Atoi(name)
+		if (err != nil) || (i < 0) {
+			return nil
+		}
+
+		var elem types.Type
+		addressable := true
+
+		if slice, ok := u.(*types.Slice); ok {
+			elem = slice.Elem()
+			result.guards = append(result.guards, fmt.Sprintf("len(%s) > %d", val.ref(), i))
+		} else {
+			array := u.(*types.Array)
+			if int64(i) >= array.Len() {
+				return nil
+			}
+
+			elem = array.Elem()
+			addressable = val.addressable
+		}
+
+		result.value = g.checkValue(value{
+			expr:        fmt.Sprintf("%s[%d]", val.ref(), i),
+			typ:         elem,
+			addressable: addressable,
+			funcCall:    val.funcCall,
+		})
+
+		return result
+	}
+
+	return nil
+}
+
+// method returns the method of given value that is called for given path part, or nil if there is none
+func (g *generator) method(val value, name string) *types.Func {
+	typ := val.typ
+	if _, ok := typ.Underlying().(*types.Interface); !ok && val.addressable {
+		typ = types.NewPointer(typ)
+	}
+
+	mset := types.NewMethodSet(typ)
+
+	// example: subject() => Subject()
+	for _, methodName := range []string{name, strings.Title(name)} {
+		if !token.IsExported(methodName) {
+			continue
+		}
+
+		if sel := mset.Lookup(nil, methodName); sel != nil {
+			return sel.Obj().(*types.Func)
+		}
+	}
+
+	return nil
+}
+
+// field returns the exported field of given struct value with given name, or nil if there is none
+func (g *generator) field(val value, name string) *types.Var {
+	if !token.IsExported(name) {
+		return nil
+	}
+
+	obj, index, indirect := types.LookupFieldOrMethod(val.typ, false, nil, name)
+
+	field, ok := obj.(*types.Var)
+	if !ok || !field.IsField() {
+		return nil
+	}
+
+	if indirect && (len(index) > 1) {
+		g.unsupported("field %s promoted through an embedded pointer", name)
+	}
+
+	return field
+}
+
+// checkValue aborts generation if given value can't be handled by generated code
+func (g *generator) checkValue(val value) value {
+	if _, ok := val.typ.Underlying().(*types.Signature); ok {
+		g.unsupported("function value of type %s", g.typeString(val.typ))
+	}
+
+	if ptr, ok := val.typ.Underlying().(*types.Pointer); ok {
+		g.checkPointer(ptr)
+	}
+
+	return val
+}
+
+// checkPointer aborts generation if given pointer type can't be indirected by generated code
+func (g *generator) checkPointer(ptr *types.Pointer) {
+	switch ptr.Elem().Underlying().(type) {
+	case *types.Pointer, *types.Signature:
+		g.unsupported("pointer of type %s", g.typeString(ptr))
+	}
+}
+
+// deref generates the code that indirects given value if it is a looked up pointer, like the interpreter does, then
+// the code generated by found
+//
+// If value is a nil pointer, then code generated by onNil is executed instead.
+func (g *generator) deref(val value, found func(value), onNil func(value)) {
+	ptr, ok := val.typ.Underlying().(*types.Pointer)
+	if !val.indirect || val.ptr || !ok {
+		found(val)
+		return
+	}
+
+	g.printf("if %s != nil {", val.expr)
+
+	found(value{expr: val.expr, typ: ptr.Elem(), ptr: true, addressable: true, funcCall: val.funcCall})
+
+	if (onNil != nil) && !val.root {
+		if other := g.capture(func() { onNil(val) }); other != "" {
+			g.printf("} else {")
+			g.buf.WriteString(other)
+		}
+	}
+
+	g.printf("}")
+}
+
+// truthCond returns the Go condition that checks if given value is truthy, like raymond.IsTrue() does
+//
+// It returns "true" or "false" if the result does not depend on value.
+func (g *generator) truthCond(val value) string {
+	x := val.val()
+
+	switch u := val.typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return x
+		case u.Info()&types.IsString != 0:
+			return x + ` != ""`
+		case u.Info()&types.IsNumeric != 0:
+			return x + " != 0"
+		case u.Kind() == types.UnsafePointer:
+			return x + " != nil"
+		}
+	case *types.Slice, *types.Map:
+		return "len(" + x + ") > 0"
+	case *types.Array:
+		return strconv.FormatBool(u.Len() > 0)
+	case *types.Chan, *types.Pointer, *types.Signature:
+		return x + " != nil"
+	case *types.Interface:
+		return "rt.IsTrue(" + x + ")"
+	case *types.Struct:
+		// struct values are always true
+		return "true"
+	}
+
+	g.unsupported("truthiness of type %s", g.typeString(val.typ))
+	return ""
+}
+
+// usesVar returns true if given generated code references given variable
+func usesVar(code string, name string) bool {
+	for i := strings.Index(code, name); i != -1; {
+		end := i + len(name)
+
+		if ((i == 0) || !isIdentChar(code[i-1])) && ((end == len(code)) || !isIdentChar(code[end])) {
+			return true
+		}
+
+		next := strings.Index(code[end:], name)
+		if next == -1 {
+			break
+		}
+
+		i = end + next
+	}
+
+	return false
+}
+
+// isIdentChar returns true if given byte can be part of a Go identifier
+func isIdentChar(c byte) bool {
+	return (c == '_') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
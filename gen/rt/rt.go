@@ -0,0 +1,152 @@
+// Package rt provides the runtime support of code generated by the gen package.
+//
+// It is not meant to be used directly.
+package rt
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/aymerick/raymond"
+)
+
+// Writer writes the output of a generated template.
+//
+// It keeps track of the first error that occurs, and ignores everything that is written after that error.
+type Writer struct {
+	w   io.StringWriter
+	err error
+}
+
+// stringWriter adapts an io.Writer to the io.StringWriter interface
+type stringWriter struct {
+	w io.Writer
+}
+
+// WriteString writes given string to underlying writer
+func (sw stringWriter) WriteString(s string) (int, error) {
+	return sw.w.Write([]byte(s))
+}
+
+// NewWriter instanciates a new Writer that writes to given writer.
+func NewWriter(w io.Writer) *Writer {
+	sw, ok := w.(io.StringWriter)
+	if !ok {
+		sw = stringWriter{w}
+	}
+
+	return &Writer{w: sw}
+}
+
+// Err returns the first error that occurred.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// fail records given error, if no error occurred yet
+func (w *Writer) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// WriteString writes given string as is.
+func (w *Writer) WriteString(s string) {
+	if (w.err != nil) || (s == "") {
+		return
+	}
+
+	if _, err := w.w.WriteString(s); err != nil {
+		w.fail(err)
+	}
+}
+
+// WriteEscaped writes given string, with special HTML characters escaped.
+func (w *Writer) WriteEscaped(s string) {
+	w.WriteString(raymond.Escape(s))
+}
+
+// WriteInt writes given integer.
+func (w *Writer) WriteInt(i int64) {
+	w.WriteString(strconv.FormatInt(i, 10))
+}
+
+// WriteUint writes given unsigned integer.
+func (w *Writer) WriteUint(i uint64) {
+	w.WriteString(strconv.FormatUint(i, 10))
+}
+
+// WriteFloat writes given float.
+func (w *Writer) WriteFloat(f float64) {
+	w.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// WriteBool writes given boolean.
+func (w *Writer) WriteBool(b bool) {
+	w.WriteString(strconv.FormatBool(b))
+}
+
+// WriteValue writes the string representation of given value, as computed by raymond.Str(). It is escaped if the
+// escape argument is true and if the value is not a raymond.SafeString.
+func (w *Writer) WriteValue(value interface{}, escape bool) {
+	if w.err != nil {
+		return
+	}
+
+	str, err := Str(value)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+
+	if _, safe := value.(raymond.SafeString); escape && !safe {
+		w.WriteEscaped(str)
+	} else {
+		w.WriteString(str)
+	}
+}
+
+// Helper calls the global helper registered with given name, with given context, parameters and hash, and returns
+// its result. Nil hash values are ignored, like with templates evaluation.
+//
+// If the helper fails, then the error is recorded and nil is returned.
+func (w *Writer) Helper(name string, ctx interface{}, params []interface{}, hash map[string]interface{}) interface{} {
+	if w.err != nil {
+		return nil
+	}
+
+	for key, value := range hash {
+		if value == nil {
+			delete(hash, key)
+		}
+	}
+
+	result, err := raymond.CallHelper(name, ctx, params, hash)
+	if err != nil {
+		w.fail(err)
+		return nil
+	}
+
+	return result
+}
+
+// Str returns the string representation of given value, as computed by raymond.Str(), or an error if that value
+// can't be printed.
+func Str(value interface{}) (result string, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+
+	result = raymond.Str(value)
+
+	// named return values
+	return
+}
+
+// IsTrue returns true if given value is truthy, as computed by raymond.IsTrue().
+func IsTrue(value interface{}) bool {
+	return raymond.IsTrue(value)
+}
@@ -25,7 +25,18 @@ type Test struct {
 	output   interface{}
 }
 
+// testOptions represents the options used to parse and evaluate the templates of a tests suite
+type testOptions struct {
+	parse    raymond.ParseOptions
+	trackIds bool
+	compat   bool
+}
+
 func launchTests(t *testing.T, tests []Test) {
+	launchTestsWithOptions(t, tests, testOptions{})
+}
+
+func launchTestsWithOptions(t *testing.T, tests []Test, options testOptions) {
 	t.Parallel()
 
 	for _, test := range tests {
@@ -41,10 +52,13 @@ func launchTests(t *testing.T, tests []Test) {
 		}
 
 		// parse template
-		tpl, err = raymond.Parse(test.input)
+		tpl, err = raymond.ParseWithOptions(test.input, options.parse)
 		if err != nil {
 			t.Errorf("Test '%s' failed - Failed to parse template\ninput:\n\t'%s'\nerror:\n\t%s", test.name, test.input, err)
 		} else {
+			tpl.SetTrackIds(options.trackIds)
+			tpl.SetCompat(options.compat)
+
 			if len(test.helpers) > 0 {
 				// register helpers
 				tpl.RegisterHelpers(test.helpers)
@@ -1,6 +1,10 @@
 package handlebars
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/aymerick/raymond"
+)
 
 //
 // Those tests come from:
@@ -174,26 +178,13 @@ var blocksTests = []Test{
 		nil, nil, nil,
 		"1\n3\n5\nOK.",
 	},
-	// // @todo compat mode
-	// {
-	// 	"block with deep recursive lookup lookup",
-	// 	"{{#outer}}Goodbye {{#inner}}cruel {{omg}}{{/inner}}{{/outer}}",
-	// 	map[string]interface{}{"omg": "OMG!", "outer": []map[string]interface{}{{"inner": []map[string]string{{"text": "goodbye"}}}}},
-	// 	nil,
-	// 	nil,
-	//  nil,
-	// 	"Goodbye cruel OMG!",
-	// },
-	// // @todo compat mode
-	// {
-	// 	"block with deep recursive pathed lookup",
-	// 	"{{#outer}}Goodbye {{#inner}}cruel {{omg.yes}}{{/inner}}{{/outer}}",
-	// 	map[string]interface{}{"omg": map[string]string{"yes": "OMG!"}, "outer": []map[string]interface{}{{"inner": []map[string]string{{"yes": "no", "text": "goodbye"}}}}},
-	// 	nil,
-	// 	nil,
-	//  nil,
-	// 	"Goodbye cruel OMG!",
-	// },
+	{
+		"block with nil lookup",
+		"{{#outer}}Goodbye {{#inner}}cruel {{omg}}{{/inner}}{{/outer}}",
+		map[string]interface{}{"omg": "OMG!", "outer": []map[string]interface{}{{"inner": []map[string]interface{}{{"omg": nil}}}}},
+		nil, nil, nil,
+		"Goodbye cruel ",
+	},
 	{
 		"block with missed recursive lookup",
 		"{{#outer}}Goodbye {{#inner}}cruel {{omg.yes}}{{/inner}}{{/outer}}",
@@ -206,3 +197,66 @@ var blocksTests = []Test{
 func TestBlocks(t *testing.T) {
 	launchTests(t, blocksTests)
 }
+
+var compatBlocksTests = []Test{
+	{
+		"block with deep recursive lookup lookup",
+		"{{#outer}}Goodbye {{#inner}}cruel {{omg}}{{/inner}}{{/outer}}",
+		map[string]interface{}{"omg": "OMG!", "outer": []map[string]interface{}{{"inner": []map[string]string{{"text": "goodbye"}}}}},
+		nil, nil, nil,
+		"Goodbye cruel OMG!",
+	},
+	{
+		"block with deep recursive pathed lookup",
+		"{{#outer}}Goodbye {{#inner}}cruel {{omg.yes}}{{/inner}}{{/outer}}",
+		map[string]interface{}{"omg": map[string]string{"yes": "OMG!"}, "outer": []map[string]interface{}{{"inner": []map[string]string{{"yes": "no", "text": "goodbye"}}}}},
+		nil, nil, nil,
+		"Goodbye cruel OMG!",
+	},
+	{
+		"block with deep recursive lookup of nil value",
+		"{{#outer}}Goodbye {{#inner}}cruel {{omg}}{{/inner}}{{/outer}}",
+		map[string]interface{}{"omg": "OMG!", "outer": []map[string]interface{}{{"inner": []map[string]interface{}{{"omg": nil}}}}},
+		nil, nil, nil,
+		"Goodbye cruel OMG!",
+	},
+	{
+		"block with deep recursive pathed lookup of nil value",
+		"{{#outer}}Goodbye {{#inner}}cruel {{omg.yes}}{{/inner}}{{/outer}}",
+		map[string]interface{}{"omg": map[string]string{"yes": "OMG!"}, "outer": []map[string]interface{}{{"omg": nil, "inner": []map[string]interface{}{{"yes": "no", "omg": nil}}}}},
+		nil, nil, nil,
+		"Goodbye cruel OMG!",
+	},
+	{
+		"block with missed recursive pathed lookup",
+		"{{#outer}}Goodbye {{#inner}}cruel {{omg.yes}}{{/inner}}{{/outer}}",
+		map[string]interface{}{"omg": map[string]string{"yes": "OMG!"}, "outer": []map[string]interface{}{{"inner": []map[string]interface{}{{"omg": map[string]string{"no": "no"}}}}}},
+		nil, nil, nil,
+		"Goodbye cruel ",
+	},
+}
+
+func TestCompatBlocks(t *testing.T) {
+	launchTestsWithOptions(t, compatBlocksTests, testOptions{compat: true})
+}
+
+var ignoreStandaloneBlocksTests = []Test{
+	{
+		"block standalone else sections can be disabled (1)",
+		"{{#people}}\n{{name}}\n{{^}}\n{{none}}\n{{/people}}\n",
+		map[string]interface{}{"none": "No people"},
+		nil, nil, nil,
+		"\nNo people\n\n",
+	},
+	{
+		"block standalone else sections can be disabled (2)",
+		"{{#none}}\n{{.}}\n{{^}}\nFail\n{{/none}}\n",
+		map[string]interface{}{"none": "No people"},
+		nil, nil, nil,
+		"\nNo people\n\n",
+	},
+}
+
+func TestIgnoreStandaloneBlocks(t *testing.T) {
+	launchTestsWithOptions(t, ignoreStandaloneBlocksTests, testOptions{parse: raymond.ParseOptions{IgnoreStandalone: true}})
+}
@@ -1,6 +1,12 @@
 package handlebars
 
-import "testing"
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aymerick/raymond"
+)
 
 //
 // Those tests come from:
@@ -279,21 +285,7 @@ var builtinsTests = []Test{
 
 	// @todo "each on implicit context" should throw error
 
-	// SKIP: #log - "should call logger at default level"
-	// SKIP: #log - "should call logger at data level"
-	// SKIP: #log - "should output to info"
-	// SKIP: #log - "should log at data level"
-	// SKIP: #log - "should handle missing logger"
-
-	// @note Test added
-	// @todo Check log output
-	{
-		"#log",
-		"{{log blah}}",
-		map[string]string{"blah": "whee"},
-		nil, nil, nil,
-		"",
-	},
+	// "#log" tests are in TestLog
 
 	// @note Test added
 	{
@@ -339,3 +331,100 @@ var builtinsTests = []Test{
 func TestBuiltins(t *testing.T) {
 	launchTests(t, builtinsTests)
 }
+
+var logTests = []struct {
+	name     string
+	input    string
+	data     interface{}
+	privData map[string]interface{}
+	level    raymond.LogLevel
+	args     []interface{}
+}{
+	{
+		"should call logger at default level",
+		"{{log blah}}",
+		map[string]string{"blah": "whee"},
+		nil,
+		raymond.LogInfo,
+		[]interface{}{"whee"},
+	},
+	{
+		"should call logger at data level",
+		"{{log blah}}",
+		map[string]string{"blah": "whee"},
+		map[string]interface{}{"level": "03"},
+		raymond.LogError,
+		[]interface{}{"whee"},
+	},
+	{
+		"should handle string log levels",
+		"{{log blah}}",
+		map[string]string{"blah": "whee"},
+		map[string]interface{}{"level": "error"},
+		raymond.LogError,
+		[]interface{}{"whee"},
+	},
+	{
+		"should handle hash log levels",
+		`{{log blah level="error"}}`,
+		map[string]string{"blah": "whee"},
+		nil,
+		raymond.LogError,
+		[]interface{}{"whee"},
+	},
+	{
+		"should handle hash log levels over data",
+		`{{log blah level="error"}}`,
+		map[string]string{"blah": "whee"},
+		map[string]interface{}{"level": "debug"},
+		raymond.LogError,
+		[]interface{}{"whee"},
+	},
+	{
+		"should pass multiple log arguments",
+		`{{log blah "foo" 1}}`,
+		map[string]string{"blah": "whee"},
+		nil,
+		raymond.LogInfo,
+		[]interface{}{"whee", "foo", 1},
+	},
+	{
+		"should pass zero log arguments",
+		`{{log}}`,
+		nil, nil,
+		raymond.LogInfo,
+		nil,
+	},
+}
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range logTests {
+		var level raymond.LogLevel
+		var args []interface{}
+		called := 0
+
+		tpl := raymond.MustParse(test.input)
+		tpl.SetLogger(raymond.LoggerFunc(func(ctx context.Context, l raymond.LogLevel, a ...interface{}) {
+			level, args = l, a
+			called++
+		}))
+
+		privData := raymond.NewDataFrame()
+		for k, v := range test.privData {
+			privData.Set(k, v)
+		}
+
+		output, err := tpl.ExecWith(test.data, privData)
+		if err != nil {
+			t.Errorf("Test '%s' failed - Unexpected error: %s", test.name, err)
+		} else if output != "" {
+			t.Errorf("Test '%s' failed - Unexpected output: %q", test.name, output)
+		} else if called != 1 {
+			t.Errorf("Test '%s' failed - Logger called %d times", test.name, called)
+		} else if level != test.level || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("Test '%s' failed - Expected %s %v but got %s %v", test.name, test.level, test.args, level, args)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package handlebars
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aymerick/raymond/internal/spectest"
+)
+
+// TestGenerated runs the tests suites that use the default options through the code generator
+//
+// The tests that fall back on the interpreter, or that are not run, are listed in testdata/generated.txt
+func TestGenerated(t *testing.T) {
+	spectest.Run(t, generatedCases(t), "generatedCases", filepath.Join("testdata", "generated.txt"))
+}
+
+// generatedCases returns the test cases run through the code generator
+func generatedCases(t *testing.T) []spectest.Case {
+	suites := [][]Test{
+		basicTests,
+		blocksTests,
+		builtinsTests,
+		dataTests,
+		helpersTests,
+		partialsTests,
+		subexpressionsTests,
+		whitespaceControlTests,
+	}
+
+	var cases []spectest.Case
+
+	for _, tests := range suites {
+		for _, test := range tests {
+			cases = append(cases, spectest.Case{
+				Name:     test.name,
+				Input:    test.input,
+				Data:     test.data,
+				PrivData: test.privData,
+				Helpers:  test.helpers,
+				Output:   test.output,
+			})
+		}
+	}
+
+	return cases
+}
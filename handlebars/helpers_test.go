@@ -38,6 +38,10 @@ func rawHelper(options *raymond.Options) string {
 	return options.Fn()
 }
 
+func rawContentHelper(options *raymond.Options) string {
+	return options.RawContent()
+}
+
 func rawThreeHelper(a, b, c string, options *raymond.Options) string {
 	return options.Fn() + a + b + c
 }
@@ -126,6 +130,33 @@ var helpersTests = []Test{
 		nil,
 		" {{test}} 123",
 	},
+	{
+		"helper for raw block gets standalone raw content",
+		"{{{{raw}}}}\n  {{test}}\n{{{{/raw}}}}\n",
+		map[string]interface{}{"test": "hello"},
+		nil,
+		map[string]interface{}{"raw": rawHelper},
+		nil,
+		"  {{test}}\n",
+	},
+	{
+		"helper for raw block gets verbatim raw content",
+		"{{{{raw}}}}\n  {{#test}}{{~foo~}}{{/test}}\n{{{{/raw}}}}\n",
+		map[string]interface{}{"test": "hello"},
+		nil,
+		map[string]interface{}{"raw": rawContentHelper},
+		nil,
+		"\n  {{#test}}{{~foo~}}{{/test}}\n",
+	},
+	{
+		"raw content is empty for a block that is not raw",
+		"{{#raw}} {{test}} {{/raw}}",
+		map[string]interface{}{"test": "hello"},
+		nil,
+		map[string]interface{}{"raw": rawContentHelper},
+		nil,
+		"",
+	},
 	{
 		"helper block with complex lookup expression",
 		"{{#goodbyes}}{{../name}}{{/goodbyes}}",
@@ -561,11 +592,65 @@ var helpersTests = []Test{
 		"NOT PRINTING",
 	},
 
-	// @todo "helperMissing - if a context is not found, helperMissing is used" throw error
-
-	// @todo "helperMissing - if a context is not found, custom helperMissing is used"
+	// "helperMissing - if a context is not found, helperMissing is used" throw error: cf. TestHelperMissingError()
 
-	// @todo "helperMissing - if a value is not found, custom helperMissing is used"
+	{
+		"helperMissing - if a context is not found, custom helperMissing is used",
+		`{{hello}} {{link_to world}}`,
+		map[string]string{"hello": "Hello", "world": "world"},
+		nil,
+		map[string]interface{}{"helperMissing": func(mesg string, options *raymond.Options) interface{} {
+			if options.Name() == "link_to" {
+				return raymond.SafeString("<a>" + mesg + "</a>")
+			}
+			return nil
+		}},
+		nil,
+		"Hello <a>world</a>",
+	},
+	{
+		"helperMissing - if a value is not found, custom helperMissing is used",
+		`{{hello}} {{link_to}}`,
+		map[string]string{"hello": "Hello", "world": "world"},
+		nil,
+		map[string]interface{}{"helperMissing": func(options *raymond.Options) interface{} {
+			if options.Name() == "link_to" {
+				return raymond.SafeString("<a>winning</a>")
+			}
+			return nil
+		}},
+		nil,
+		"Hello <a>winning</a>",
+	},
+	{
+		"helperMissing - if a value is not found, default helperMissing outputs nothing",
+		`{{hello}} {{link_to}} {{link_to foo=world}}`,
+		map[string]string{"hello": "Hello", "world": "world"},
+		nil, nil, nil,
+		"Hello  ",
+	},
+	{
+		"helperMissing - custom helperMissing only taking options gets all params",
+		`{{link_to "foo" world}}`,
+		map[string]string{"world": "world"},
+		nil,
+		map[string]interface{}{"helperMissing": func(options *raymond.Options) string {
+			return options.Name() + ": " + options.ParamStr(0) + " " + options.ParamStr(1)
+		}},
+		nil,
+		"link_to: foo world",
+	},
+	{
+		"helperMissing - block with parameters uses helperMissing",
+		`{{#link_to world}}foo{{/link_to}}`,
+		map[string]string{"world": "world"},
+		nil,
+		map[string]interface{}{"helperMissing": func(mesg string, options *raymond.Options) string {
+			return options.Name() + ": " + mesg + " " + options.Fn()
+		}},
+		nil,
+		"link_to: world foo",
+	},
 
 	{
 		"block helpers can take an optional hash with booleans (1)",
@@ -586,11 +671,103 @@ var helpersTests = []Test{
 		"NOT PRINTING",
 	},
 
-	// @todo "knownHelpers/knownHelpersOnly" tests
+	// "knownHelpers/knownHelpersOnly" tests: cf. TestKnownHelpers()
 
-	// @todo "blockHelperMissing" tests
+	{
+		"blockHelperMissing - lambdas are resolved by blockHelperMissing, not handlebars proper",
+		`{{#truthy}}yep{{/truthy}}`,
+		map[string]interface{}{"truthy": func() bool { return true }},
+		nil, nil, nil,
+		"yep",
+	},
+	{
+		"blockHelperMissing - lambdas resolved by blockHelperMissing are bound to the context",
+		`{{#truthy}}yep{{/truthy}}`,
+		map[string]interface{}{
+			"truthy": func(options *raymond.Options) interface{} {
+				return options.Value("truthiness")
+			},
+			"truthiness": func() bool { return false },
+		},
+		nil, nil, nil,
+		"",
+	},
+	{
+		"blockHelperMissing - custom blockHelperMissing is used",
+		`{{#foo}}bar{{else}}baz{{/foo}} {{#missing}}bar{{else}}baz{{/missing}}`,
+		map[string]interface{}{"foo": []string{"a", "b"}},
+		nil,
+		map[string]interface{}{"blockHelperMissing": func(context interface{}, options *raymond.Options) string {
+			if !raymond.IsTrue(context) {
+				return options.Name() + ": " + options.Inverse()
+			}
+			return options.Name() + ": " + raymond.Str(context) + " " + options.Fn()
+		}},
+		nil,
+		"foo: ab bar missing: baz",
+	},
+	{
+		"blockHelperMissing - custom blockHelperMissing is not used for helpers",
+		`{{#if foo}}bar{{/if}}`,
+		map[string]interface{}{"foo": true},
+		nil,
+		map[string]interface{}{"blockHelperMissing": func(options *raymond.Options) string {
+			return "THIS SHOULD NOT HAPPEN"
+		}},
+		nil,
+		"bar",
+	},
 
-	// @todo "name field" tests
+	{
+		"name field - should include in ambiguous mustache calls",
+		`{{helper}}`,
+		nil, nil,
+		map[string]interface{}{"helper": func(options *raymond.Options) string {
+			return "ran: " + options.Name()
+		}},
+		nil,
+		"ran: helper",
+	},
+	{
+		"name field - should include in helper mustache calls",
+		`{{helper 1}}`,
+		nil, nil,
+		map[string]interface{}{"helper": func(param int, options *raymond.Options) string {
+			return "ran: " + options.Name()
+		}},
+		nil,
+		"ran: helper",
+	},
+	{
+		"name field - should include in ambiguous block calls",
+		`{{#helper}}{{/helper}}`,
+		nil, nil,
+		map[string]interface{}{"helper": func(options *raymond.Options) string {
+			return "ran: " + options.Name()
+		}},
+		nil,
+		"ran: helper",
+	},
+	{
+		"name field - should include in simple block calls",
+		`{{#helper 1}}{{/helper}}`,
+		nil, nil,
+		map[string]interface{}{"helper": func(param int, options *raymond.Options) string {
+			return "ran: " + options.Name()
+		}},
+		nil,
+		"ran: helper",
+	},
+	{
+		"name field - should include in dashed helper calls",
+		`{{dash-helper}}`,
+		nil, nil,
+		map[string]interface{}{"dash-helper": func(options *raymond.Options) string {
+			return "ran: " + options.Name()
+		}},
+		nil,
+		"ran: dash-helper",
+	},
 
 	{
 		"name conflicts - helpers take precedence over same-named context properties",
@@ -663,3 +840,196 @@ var helpersTests = []Test{
 func TestHelpers(t *testing.T) {
 	launchTests(t, helpersTests)
 }
+
+type knownHelpersTest struct {
+	name             string
+	input            string
+	data             interface{}
+	helpers          map[string]interface{}
+	knownHelpers     map[string]bool
+	knownHelpersOnly bool
+	output           string
+}
+
+var knownHelpersTests = []knownHelpersTest{
+	{
+		"Known helper should render string",
+		`{{hello}}`,
+		nil,
+		map[string]interface{}{"hello": func() string { return "foo" }},
+		map[string]bool{"hello": true},
+		false,
+		"foo",
+	},
+	{
+		"Known helper should render string in knownHelpersOnly mode",
+		`{{hello}}`,
+		nil,
+		map[string]interface{}{"hello": func() string { return "foo" }},
+		map[string]bool{"hello": true},
+		true,
+		"foo",
+	},
+	{
+		"Known helper should pass args",
+		`{{hello "cruel" world}}`,
+		map[string]string{"world": "world"},
+		map[string]interface{}{"hello": func(a, b string) string { return "goodbye " + a + " " + b }},
+		map[string]bool{"hello": true},
+		true,
+		"goodbye cruel world",
+	},
+	{
+		"Unknown helper in knownHelpersOnly mode should be passed as undefined",
+		`{{typeof hello}}`,
+		nil,
+		map[string]interface{}{
+			"typeof": func(arg interface{}) string {
+				if arg == nil {
+					return "undefined"
+				}
+				return "defined"
+			},
+			"hello": func() string { return "foo" },
+		},
+		map[string]bool{"typeof": true},
+		true,
+		"undefined",
+	},
+	{
+		"Unknown ambiguous helper in knownHelpersOnly mode is a field lookup",
+		`{{hello}}`,
+		map[string]string{"hello": "bar"},
+		map[string]interface{}{"hello": func() string { return "foo" }},
+		nil,
+		true,
+		"bar",
+	},
+	{
+		"Builtin helpers available in knownHelpersOnly mode",
+		`{{#unless foo}}bar{{/unless}}`,
+		nil, nil, nil,
+		true,
+		"bar",
+	},
+	{
+		"Field lookup works in knownHelpersOnly mode",
+		`{{foo}}`,
+		map[string]string{"foo": "bar"},
+		nil, nil,
+		true,
+		"bar",
+	},
+	{
+		"Conditional blocks work in knownHelpersOnly mode",
+		`{{#foo}}bar{{/foo}}`,
+		map[string]string{"foo": "baz"},
+		nil, nil,
+		true,
+		"bar",
+	},
+	{
+		"Invert blocks work in knownHelpersOnly mode",
+		`{{^foo}}bar{{/foo}}`,
+		map[string]bool{"foo": false},
+		nil, nil,
+		true,
+		"bar",
+	},
+	{
+		"Functions are bound to the context in knownHelpersOnly mode",
+		`{{foo}}`,
+		map[string]interface{}{
+			"foo": func(options *raymond.Options) string { return options.ValueStr("bar") },
+			"bar": "bar",
+		},
+		nil, nil,
+		true,
+		"bar",
+	},
+	{
+		"Unknown ambiguous helper in knownHelpersOnly mode does not use helperMissing",
+		`{{foo}}`,
+		nil,
+		map[string]interface{}{"helperMissing": func(options *raymond.Options) string { return "missing" }},
+		nil,
+		true,
+		"",
+	},
+}
+
+func TestKnownHelpers(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range knownHelpersTests {
+		tpl, err := raymond.ParseWithOptions(test.input, raymond.ParseOptions{
+			KnownHelpers:     test.knownHelpers,
+			KnownHelpersOnly: test.knownHelpersOnly,
+		})
+		if err != nil {
+			t.Errorf("Test '%s' failed - Failed to parse template: %s", test.name, err)
+			continue
+		}
+
+		tpl.RegisterHelpers(test.helpers)
+
+		output, err := tpl.Exec(test.data)
+		if err != nil {
+			t.Errorf("Test '%s' failed - Unexpected error: %s", test.name, err)
+		} else if output != test.output {
+			t.Errorf("Test '%s' failed - Expected %q but got %q", test.name, test.output, output)
+		}
+	}
+}
+
+func TestKnownHelpersErrors(t *testing.T) {
+	t.Parallel()
+
+	inputs := map[string]string{
+		"{{typeof hello}}":                                             "typeof",
+		"{{#each (typeof hello)}}{{/each}}":                            "typeof",
+		"{{#if true}}\n{{foo bar=baz}}{{/if}}":                         "foo",
+		"{{#each items}}{{else}}{{> (partial)}}{{/each}}":              "partial",
+		"{{#unless true}}{{else}}{{#foo}}{{/foo}}{{bar 1}}{{/unless}}": "bar",
+	}
+
+	for input, name := range inputs {
+		_, err := raymond.ParseWithOptions(input, raymond.ParseOptions{KnownHelpersOnly: true})
+
+		unknownErr, ok := err.(*raymond.UnknownHelperError)
+		if !ok {
+			t.Errorf("Test failed - Expected an UnknownHelperError for input %q but got: %v", input, err)
+		} else if unknownErr.Name != name {
+			t.Errorf("Test failed - Expected unknown helper %q for input %q but got %q", name, input, unknownErr.Name)
+		}
+	}
+
+	// builtin helpers can be disabled
+	if _, err := raymond.ParseWithOptions("{{#if foo}}bar{{/if}}", raymond.ParseOptions{
+		KnownHelpers:     map[string]bool{"if": false},
+		KnownHelpersOnly: true,
+	}); err == nil {
+		t.Errorf("Test failed - Disabled builtin helper must be rejected")
+	}
+
+	// unknown helpers are accepted if knownHelpersOnly mode is disabled
+	if _, err := raymond.ParseWithOptions("{{typeof hello}}", raymond.ParseOptions{}); err != nil {
+		t.Errorf("Test failed - Unexpected error: %s", err)
+	}
+}
+
+func TestHelperMissingError(t *testing.T) {
+	t.Parallel()
+
+	// helperMissing - if a context is not found, helperMissing is used
+	tpl := raymond.MustParse(`{{hello}} {{link_to world}}`)
+
+	_, err := tpl.Exec(map[string]string{"hello": "Hello", "world": "world"})
+	if err == nil {
+		t.Fatalf("Test failed - Error expected")
+	}
+
+	if expected := `Missing helper: "link_to"`; !strings.Contains(err.Error(), expected) {
+		t.Errorf("Test failed - Expected error:\n\t%s\n\nGot:\n\t%s", expected, err)
+	}
+}
@@ -1,6 +1,11 @@
 package handlebars
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/aymerick/raymond"
+)
 
 //
 // Those tests come from:
@@ -164,19 +169,344 @@ var partialsTests = []Test{
 		"Dudes:\n  Yehuda\n   http://yehuda!\n  Alan\n   http://alan!\n",
 	},
 
-	// // @todo preventIndent option
-	// {
-	// 	"standalone partials (3) - prevent nested indented partials",
-	// 	"Dudes:\n{{#dudes}}\n  {{>dude}}\n{{/dudes}}",
-	// 	map[string]interface{}{"dudes": []map[string]string{{"name": "Yehuda", "url": "http://yehuda"}, {"name": "Alan", "url": "http://alan"}}},
-	// 	nil, nil,
-	// 	map[string]string{"dude": "{{name}}\n {{> url}}", "url": "{{url}}!\n"},
-	// 	"Dudes:\n  Yehuda\n http://yehuda!\n  Alan\n http://alan!\n",
-	// },
+	{
+		"inline partials - should define inline partials for template",
+		`{{#*inline "myPartial"}}success{{/inline}}{{> myPartial}}`,
+		nil, nil, nil, nil,
+		"success",
+	},
+	{
+		"inline partials - should overwrite multiple partials in the same template",
+		`{{#*inline "myPartial"}}fail{{/inline}}{{#*inline "myPartial"}}success{{/inline}}{{> myPartial}}`,
+		nil, nil, nil, nil,
+		"success",
+	},
+	{
+		"inline partials - should define inline partials for block",
+		`{{#with .}}{{#*inline "myPartial"}}success{{/inline}}{{> myPartial}}{{/with}}`,
+		map[string]string{"foo": "bar"},
+		nil, nil, nil,
+		"success",
+	},
+	{
+		"inline partials - should override global partials",
+		`{{#*inline "myPartial"}}success{{/inline}}{{> myPartial}}`,
+		nil, nil, nil,
+		map[string]string{"myPartial": "fail"},
+		"success",
+	},
+	{
+		"inline partials - should override template partials",
+		`{{#*inline "myPartial"}}fail{{/inline}}{{#with .}}{{#*inline "myPartial"}}success{{/inline}}{{> myPartial}}{{/with}}`,
+		map[string]string{"foo": "bar"},
+		nil, nil, nil,
+		"success",
+	},
+	{
+		"inline partials - should override partials down the entire stack",
+		`{{#with .}}{{#*inline "myPartial"}}success{{/inline}}{{#with .}}{{#with .}}{{> myPartial}}{{/with}}{{/with}}{{/with}}`,
+		map[string]string{"foo": "bar"},
+		nil, nil, nil,
+		"success",
+	},
+	{
+		"inline partials - should define inline partials for partial call",
+		`{{#*inline "myPartial"}}success{{/inline}}{{> dude}}`,
+		nil, nil, nil,
+		map[string]string{"dude": "{{> myPartial }}"},
+		"success",
+	},
+	{
+		"inline partials - are defined before program body",
+		`{{> myPartial}}{{#*inline "myPartial"}}{{name}}{{/inline}}`,
+		map[string]string{"name": "success"},
+		nil, nil, nil,
+		"success",
+	},
+	{
+		"inline partials - standalone",
+		"{{#*inline \"myPartial\"}}\n  {{name}}\n{{/inline}}\n{{> myPartial}}",
+		map[string]string{"name": "success"},
+		nil, nil, nil,
+		"  success\n",
+	},
 
-	// @todo "compat mode"
+	{
+		"partial blocks - should render partial block as default",
+		`{{#> dude}}success{{/dude}}`,
+		nil, nil, nil, nil,
+		"success",
+	},
+	{
+		"partial blocks - should execute default block with proper context",
+		`{{#> dude context}}{{value}}{{/dude}}`,
+		map[string]interface{}{"context": map[string]string{"value": "success"}},
+		nil, nil, nil,
+		"success",
+	},
+	{
+		"partial blocks - should propagate block parameters to default block",
+		`{{#with context as |me|}}{{#> dude}}{{me.value}}{{/dude}}{{/with}}`,
+		map[string]interface{}{"context": map[string]string{"value": "success"}},
+		nil, nil, nil,
+		"success",
+	},
+	{
+		"partial blocks - should not use partial block if partial exists",
+		`{{#> dude}}fail{{/dude}}`,
+		nil, nil, nil,
+		map[string]string{"dude": "success"},
+		"success",
+	},
+	{
+		"partial blocks - should render block from partial",
+		`{{#> dude}}success{{/dude}}`,
+		nil, nil, nil,
+		map[string]string{"dude": "{{> @partial-block }}"},
+		"success",
+	},
+	{
+		"partial blocks - should be able to render the partial-block twice",
+		`{{#> dude}}success{{/dude}}`,
+		nil, nil, nil,
+		map[string]string{"dude": "{{> @partial-block }} {{> @partial-block }}"},
+		"success success",
+	},
+	{
+		"partial blocks - should render block from partial with context",
+		`{{#> dude}}{{value}}{{/dude}}`,
+		map[string]interface{}{"context": map[string]string{"value": "success"}},
+		nil, nil,
+		map[string]string{"dude": "{{#with context}}{{> @partial-block }}{{/with}}"},
+		"success",
+	},
+	{
+		"partial blocks - should be able to access the @data frame from a partial-block",
+		`{{#> dude}}in-block: {{@root/value}}{{/dude}}`,
+		map[string]string{"value": "success"},
+		nil, nil,
+		map[string]string{"dude": "<code>before-block: {{@root/value}} {{>   @partial-block }}</code>"},
+		"<code>before-block: success in-block: success</code>",
+	},
+	{
+		"partial blocks - should allow the #each-helper to be used along with partial-blocks",
+		`<template>{{#> list value}}value = {{.}}{{/list}}</template>`,
+		map[string]interface{}{"value": []string{"a", "b", "c"}},
+		nil, nil,
+		map[string]string{"list": "<list>{{#each .}}<item>{{> @partial-block}}</item>{{/each}}</list>"},
+		"<template><list><item>value = a</item><item>value = b</item><item>value = c</item></list></template>",
+	},
+	{
+		"partial blocks - should render block from partial with context (twice)",
+		`{{#> dude}}{{value}}{{/dude}}`,
+		map[string]interface{}{"context": map[string]string{"value": "success"}},
+		nil, nil,
+		map[string]string{"dude": "{{#with context}}{{> @partial-block }} {{> @partial-block }}{{/with}}"},
+		"success success",
+	},
+	{
+		"partial blocks - should render block from partial with context (parent)",
+		`{{#> dude}}{{../context/value}}{{/dude}}`,
+		map[string]interface{}{"context": map[string]string{"value": "success"}},
+		nil, nil,
+		map[string]string{"dude": "{{#with context}}{{> @partial-block }}{{/with}}"},
+		"success",
+	},
+	{
+		"partial blocks - should render block from partial with block params",
+		`{{#with context as |me|}}{{#> dude}}{{me.value}}{{/dude}}{{/with}}`,
+		map[string]interface{}{"context": map[string]string{"value": "success"}},
+		nil, nil,
+		map[string]string{"dude": "{{> @partial-block }}"},
+		"success",
+	},
+	{
+		"partial blocks - should render nested partial blocks",
+		`<template>{{#> outer}}{{value}}{{/outer}}</template>`,
+		map[string]string{"value": "success"},
+		nil, nil,
+		map[string]string{
+			"outer":  "<outer>{{#> nested}}<outer-block>{{> @partial-block}}</outer-block>{{/nested}}</outer>",
+			"nested": "<nested>{{> @partial-block}}</nested>",
+		},
+		"<template><outer><nested><outer-block>success</outer-block></nested></outer></template>",
+	},
+	{
+		"partial blocks - should render nested partial blocks at different nesting levels",
+		`<template>{{#> outer}}{{value}}{{/outer}}</template>`,
+		map[string]string{"value": "success"},
+		nil, nil,
+		map[string]string{
+			"outer":  "<outer>{{#> nested}}<outer-block>{{> @partial-block}}</outer-block>{{/nested}}{{> @partial-block}}</outer>",
+			"nested": "<nested>{{> @partial-block}}</nested>",
+		},
+		"<template><outer><nested><outer-block>success</outer-block></nested>success</outer></template>",
+	},
+	{
+		"partial blocks - should render nested partial blocks at different nesting levels (twice)",
+		`<template>{{#> outer}}{{value}}{{/outer}}</template>`,
+		map[string]string{"value": "success"},
+		nil, nil,
+		map[string]string{
+			"outer":  "<outer>{{#> nested}}<outer-block>{{> @partial-block}} {{> @partial-block}}</outer-block>{{/nested}}{{> @partial-block}}+{{> @partial-block}}</outer>",
+			"nested": "<nested>{{> @partial-block}}</nested>",
+		},
+		"<template><outer><nested><outer-block>success success</outer-block></nested>success+success</outer></template>",
+	},
+	{
+		"partial blocks - should render nested partial blocks (twice at each level)",
+		`<template>{{#> outer}}{{value}}{{/outer}}</template>`,
+		map[string]string{"value": "success"},
+		nil, nil,
+		map[string]string{
+			"outer":  "<outer>{{#> nested}}<outer-block>{{> @partial-block}} {{> @partial-block}}</outer-block>{{/nested}}</outer>",
+			"nested": "<nested>{{> @partial-block}}{{> @partial-block}}</nested>",
+		},
+		"<template><outer><nested><outer-block>success success</outer-block><outer-block>success success</outer-block></nested></outer></template>",
+	},
+	{
+		"partial blocks - should render partial block with inline partials",
+		`{{#> dude}}{{#*inline "myPartial"}}success{{/inline}}{{/dude}}`,
+		nil, nil, nil,
+		map[string]string{"dude": "{{> myPartial }}"},
+		"success",
+	},
+	{
+		"partial blocks - should render nested inline partials",
+		`{{#*inline "outer"}}{{#>inner}}<outer-block>{{>@partial-block}}</outer-block>{{/inner}}{{/inline}}{{#*inline "inner"}}<inner>{{>@partial-block}}</inner>{{/inline}}{{#>outer}}{{value}}{{/outer}}`,
+		map[string]string{"value": "success"},
+		nil, nil, nil,
+		"<inner><outer-block>success</outer-block></inner>",
+	},
+	{
+		"partial blocks - should render nested inline partials with partial-blocks on different nesting levels",
+		`{{#*inline "outer"}}{{#>inner}}<outer-block>{{>@partial-block}}</outer-block>{{/inner}}{{>@partial-block}}{{/inline}}{{#*inline "inner"}}<inner>{{>@partial-block}}</inner>{{/inline}}{{#>outer}}{{value}}{{/outer}}`,
+		map[string]string{"value": "success"},
+		nil, nil, nil,
+		"<inner><outer-block>success</outer-block></inner>success",
+	},
+	{
+		"partial blocks - standalone layout",
+		"{{#> layout}}\n  {{#*inline \"content\"}}\n    {{title}}\n  {{/inline}}\n{{/layout}}\n",
+		map[string]string{"title": "success"},
+		nil, nil,
+		map[string]string{"layout": "<div>\n  {{> content}}\n</div>\n"},
+		"<div>\n      success\n</div>\n",
+	},
+	{
+		"partial blocks - indented partial block",
+		"{{#> layout}}\nline1\nline2\n{{/layout}}",
+		nil, nil, nil,
+		map[string]string{"layout": "<div>\n  {{> @partial-block}}\n</div>\n"},
+		"<div>\n  line1\n  line2\n</div>\n",
+	},
+	{
+		"partials do not look up nil values in parent contexts",
+		"Dudes: {{> dude}}",
+		map[string]interface{}{"root": "yes", "dudes": []map[string]interface{}{{"name": "Yehuda", "root": nil}, {"name": "Alan"}}},
+		nil, nil,
+		map[string]string{"dude": "{{#dudes}}{{name}} {{root}} {{/dudes}}"},
+		"Dudes: Yehuda  Alan yes ",
+	},
 }
 
 func TestPartials(t *testing.T) {
 	launchTests(t, partialsTests)
 }
+
+var compatPartialsTests = []Test{
+	{
+		"compat mode - partials inherit compat",
+		"Dudes: {{> dude}}",
+		map[string]interface{}{"root": "yes", "dudes": []map[string]interface{}{{"name": "Yehuda", "root": nil}, {"name": "Alan"}}},
+		nil, nil,
+		map[string]string{"dude": "{{#dudes}}{{name}} {{root}} {{/dudes}}"},
+		"Dudes: Yehuda yes Alan yes ",
+	},
+}
+
+func TestCompatPartials(t *testing.T) {
+	launchTestsWithOptions(t, compatPartialsTests, testOptions{compat: true})
+}
+
+var preventIndentTests = []Test{
+	{
+		"standalone partials (3) - prevent nested indented partials",
+		"Dudes:\n{{#dudes}}\n  {{>dude}}\n{{/dudes}}",
+		map[string]interface{}{"dudes": []map[string]string{{"name": "Yehuda", "url": "http://yehuda"}, {"name": "Alan", "url": "http://alan"}}},
+		nil, nil,
+		map[string]string{"dude": "{{name}}\n {{> url}}", "url": "{{url}}!\n"},
+		"Dudes:\n  Yehuda\n http://yehuda!\n  Alan\n http://alan!\n",
+	},
+	{
+		"prevent indent - multi-line partial",
+		"<pre>\n  {{> code}}\n</pre>",
+		nil, nil, nil,
+		map[string]string{"code": "a\n  b\n"},
+		"<pre>\n  a\n  b\n</pre>",
+	},
+	{
+		"prevent indent - nested partials",
+		"Dudes:\n  {{> outer}}",
+		nil, nil, nil,
+		map[string]string{"outer": "a\n  {{> inner}}\n", "inner": "b\nc\n"},
+		"Dudes:\n  a\n  b\nc\n",
+	},
+}
+
+func TestPreventIndent(t *testing.T) {
+	launchTestsWithOptions(t, preventIndentTests, testOptions{parse: raymond.ParseOptions{PreventIndent: true}})
+}
+
+var ignoreStandaloneTests = []Test{
+	{
+		"ignore standalone - partial",
+		"Dudes:\n  {{> dude}}\n",
+		nil, nil, nil,
+		map[string]string{"dude": "a\nb\n"},
+		"Dudes:\n  a\nb\n\n",
+	},
+	{
+		"ignore standalone - block in partial",
+		"{{> p}}",
+		map[string]bool{"a": true},
+		nil, nil,
+		map[string]string{"p": "{{#if a}}\nX\n{{/if}}\n"},
+		"\nX\n\n",
+	},
+}
+
+func TestIgnoreStandalone(t *testing.T) {
+	launchTestsWithOptions(t, ignoreStandaloneTests, testOptions{parse: raymond.ParseOptions{IgnoreStandalone: true}})
+}
+
+func TestInlinePartialsScope(t *testing.T) {
+	t.Parallel()
+
+	// inline partials - should define inline partials for block
+	tpl := raymond.MustParse(`{{#with .}}{{#*inline "myPartial"}}success{{/inline}}{{/with}}{{> myPartial}}`)
+
+	_, err := tpl.Exec(map[string]string{"foo": "bar"})
+	if err == nil {
+		t.Fatalf("Test failed - Error expected")
+	}
+
+	if expected := "Partial not found: myPartial"; !strings.Contains(err.Error(), expected) {
+		t.Errorf("Test failed - Expected error:\n\t%s\n\nGot:\n\t%s", expected, err)
+	}
+}
+
+func TestMissingPartialBlock(t *testing.T) {
+	t.Parallel()
+
+	tpl := raymond.MustParse(`{{> @partial-block}}`)
+
+	_, err := tpl.Exec(nil)
+	if err == nil {
+		t.Fatalf("Test failed - Error expected")
+	}
+
+	if expected := "Partial not found: @partial-block"; !strings.Contains(err.Error(), expected) {
+		t.Errorf("Test failed - Expected error:\n\t%s\n\nGot:\n\t%s", expected, err)
+	}
+}
@@ -1,6 +1,7 @@
 package handlebars
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/aymerick/raymond"
@@ -183,9 +184,7 @@ var subexpressionsTests = []Test{
 		`<input aria-label="Name" placeholder="Example User" />`,
 	},
 
-	// @todo "in string params mode"
-
-	// @todo "as hashes in string params mode"
+	// "in string params mode" and "as hashes in string params mode" are in TestSubexpressionsStringParams
 
 	{
 		"subexpression functions on the context",
@@ -207,3 +206,77 @@ var subexpressionsTests = []Test{
 func TestSubexpressions(t *testing.T) {
 	launchTests(t, subexpressionsTests)
 }
+
+func TestSubexpressionsStringParams(t *testing.T) {
+	t.Parallel()
+
+	// in string params mode
+	tpl := raymond.MustParse("{{snog (blorg foo x=y) yeah a=b}}")
+	tpl.SetStringParams(true)
+	tpl.RegisterHelpers(map[string]interface{}{
+		"snog": func(a, b string, options *raymond.Options) string {
+			if len(options.Params()) != 2 || options.ParamType(0) != raymond.ParamTypeSubExpression || options.ParamType(1) != raymond.ParamTypeID {
+				t.Errorf("string params for outer helper processed incorrectly")
+			}
+
+			if options.HashType("a") != raymond.ParamTypeID || options.HashStr("a") != "b" {
+				t.Errorf("string params hash for outer helper processed incorrectly")
+			}
+
+			return a + b
+		},
+		"blorg": func(a string, options *raymond.Options) string {
+			if len(options.Params()) != 1 || options.ParamType(0) != raymond.ParamTypeID {
+				t.Errorf("string params for inner helper processed incorrectly")
+			}
+
+			return a
+		},
+	})
+
+	if output := tpl.MustExec(map[string]interface{}{"foo": map[string]string{}, "yeah": map[string]string{}}); output != "fooyeah" {
+		t.Errorf("Expected %q but got %q", "fooyeah", output)
+	}
+
+	// as hashes in string params mode
+	tpl = raymond.MustParse("{{blog fun=(bork)}}")
+	tpl.SetStringParams(true)
+	tpl.RegisterHelpers(map[string]interface{}{
+		"blog": func(options *raymond.Options) string {
+			if options.HashType("fun") != raymond.ParamTypeSubExpression {
+				t.Errorf("Expected SubExpression hash type but got %q", options.HashType("fun"))
+			}
+
+			return "val is " + options.HashStr("fun")
+		},
+		"bork": func() string {
+			return "BORK"
+		},
+	})
+
+	if output := tpl.MustExec(nil); output != "val is BORK" {
+		t.Errorf("Expected %q but got %q", "val is BORK", output)
+	}
+}
+
+func TestStringParamsTypesAndContexts(t *testing.T) {
+	t.Parallel()
+
+	tpl := raymond.MustParse(`{{#foo}}{{types ../bar ./baz "str" 1 true}}{{/foo}}`)
+	tpl.SetStringParams(true)
+	tpl.RegisterHelper("types", func(a, b, c, d, e interface{}, options *raymond.Options) string {
+		result := ""
+		for i, param := range options.Params() {
+			result += fmt.Sprintf("%v:%s:%v ", param, options.ParamType(i), options.ParamContext(i))
+		}
+
+		return result
+	})
+
+	ctx := map[string]interface{}{"foo": "FOO", "bar": "BAR"}
+	expected := "bar:ID:map[bar:BAR foo:FOO] baz:ID:FOO str:STRING:FOO 1:NUMBER:FOO true:BOOLEAN:FOO "
+
+	if output := tpl.MustExec(ctx); output != expected {
+		t.Errorf("Expected %q but got %q", expected, output)
+	}
+}
@@ -0,0 +1,159 @@
+package handlebars
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aymerick/raymond"
+)
+
+//
+// Those tests come from:
+//   https://github.com/wycats/handlebars.js/blob/master/spec/track-ids.js
+//
+
+// outputs params ids, "foo" hash id and context path
+func trackedIds(options *raymond.Options) string {
+	var ids []string
+	for i := range options.Params() {
+		ids = append(ids, options.ParamID(i))
+	}
+
+	return strings.Join(ids, ":") + "|" + options.HashID("foo") + "|" + options.DataStr("contextPath") + "\n"
+}
+
+func idsHelper0(options *raymond.Options) string {
+	return trackedIds(options)
+}
+
+func idsHelper1(a interface{}, options *raymond.Options) string {
+	return trackedIds(options)
+}
+
+func idsHelper2(a, b interface{}, options *raymond.Options) string {
+	return trackedIds(options)
+}
+
+func idsHelper3(a, b, c interface{}, options *raymond.Options) string {
+	return trackedIds(options)
+}
+
+func idsHelper4(a, b, c, d interface{}, options *raymond.Options) string {
+	return trackedIds(options)
+}
+
+var trackIdsTests = []Test{
+	{
+		"should include argument ids",
+		`{{wycats is.a slave.driver}}`,
+		map[string]interface{}{"is": map[string]string{"a": "foo"}, "slave": map[string]string{"driver": "bar"}},
+		nil,
+		map[string]interface{}{"wycats": idsHelper2},
+		nil,
+		"is.a:slave.driver||\n",
+	},
+	{
+		"should include hash ids",
+		`{{wycats foo=is.a}}`,
+		map[string]interface{}{"is": map[string]string{"a": "foo"}},
+		nil,
+		map[string]interface{}{"wycats": idsHelper0},
+		nil,
+		"|is.a|\n",
+	},
+	{
+		"should note ../ and ./ references",
+		`{{#with is}}{{wycats ./a ../slave.driver this.a this}}{{/with}}`,
+		map[string]interface{}{"is": map[string]string{"a": "foo"}, "slave": map[string]string{"driver": "bar"}},
+		nil,
+		map[string]interface{}{"wycats": idsHelper4},
+		nil,
+		"a:../slave.driver:a:||is\n",
+	},
+	{
+		"should note @data references",
+		`{{wycats @is.a @slave.driver}}`,
+		nil,
+		map[string]interface{}{"is": map[string]string{"a": "foo"}, "slave": map[string]string{"driver": "bar"}},
+		map[string]interface{}{"wycats": idsHelper2},
+		nil,
+		"@is.a:@slave.driver||\n",
+	},
+	{
+		"should return empty ids for constants",
+		`{{wycats 1 "foo" (sub) foo=false}}`,
+		nil, nil,
+		map[string]interface{}{"wycats": idsHelper3, "sub": func() string { return "" }},
+		nil,
+		"::||\n",
+	},
+	{
+		"#each should track contextPath",
+		`{{#each is.a}}{{wycats this}}{{/each}}`,
+		map[string]interface{}{"is": map[string]interface{}{"a": []string{"foo", "bar"}}},
+		nil,
+		map[string]interface{}{"wycats": idsHelper1},
+		nil,
+		"||is.a.0\n||is.a.1\n",
+	},
+	{
+		"#each should track contextPath of map keys",
+		`{{#each is}}{{wycats this}}{{/each}}`,
+		map[string]interface{}{"is": map[string]string{"a": "foo"}},
+		nil,
+		map[string]interface{}{"wycats": idsHelper1},
+		nil,
+		"||is.a\n",
+	},
+	{
+		"#with should track contextPath",
+		`{{#with field}}{{wycats this}}{{/with}}`,
+		map[string]interface{}{"field": map[string]string{"name": "foo"}},
+		nil,
+		map[string]interface{}{"wycats": idsHelper1},
+		nil,
+		"||field\n",
+	},
+	{
+		"should handle nested contexts",
+		`{{#with field}}{{#each list}}{{wycats name}}{{/each}}{{/with}}`,
+		map[string]interface{}{"field": map[string]interface{}{"list": []map[string]string{{"name": "foo"}, {"name": "bar"}}}},
+		nil,
+		map[string]interface{}{"wycats": idsHelper1},
+		nil,
+		"name||field.list.0\nname||field.list.1\n",
+	},
+	{
+		"block values should track contextPath",
+		`{{#list}}{{#item}}{{wycats name}}{{/item}}{{/list}}`,
+		map[string]interface{}{"list": []map[string]interface{}{{"item": map[string]string{"name": "foo"}}}},
+		nil,
+		map[string]interface{}{"wycats": idsHelper1},
+		nil,
+		"name||list.0.item\n",
+	},
+	{
+		"@contextPath is available in templates",
+		`{{#each list}}<input name="{{@contextPath}}.name" value="{{name}}">{{/each}}`,
+		map[string]interface{}{"list": []map[string]string{{"name": "foo"}}},
+		nil, nil, nil,
+		`<input name="list.0.name" value="foo">`,
+	},
+}
+
+func TestTrackIds(t *testing.T) {
+	launchTestsWithOptions(t, trackIdsTests, testOptions{trackIds: true})
+}
+
+func TestTrackIdsDisabled(t *testing.T) {
+	t.Parallel()
+
+	// should not include anything without the flag
+	tpl := raymond.MustParse(`{{#each list}}{{wycats name foo=name}}{{/each}}`)
+	tpl.RegisterHelper("wycats", idsHelper1)
+
+	output := tpl.MustExec(map[string]interface{}{"list": []map[string]string{{"name": "foo"}}})
+	if output != "||\n" {
+		t.Errorf("Expected %q but got %q", "||\n", output)
+	}
+}
@@ -1,20 +1,67 @@
 package raymond
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"reflect"
 	"sync"
+	"sync/atomic"
+
+	"github.com/aymerick/raymond/ast"
 )
 
 // Options represents the options argument provided to helpers and context functions.
+//
+// Options are only valid during the evaluation that called the helper: a helper must not keep them once it returns, as
+// methods that access evaluation state panic once that evaluation is done.
 type Options struct {
-	// evaluation visitor
-	eval *evalVisitor
+	// evaluation visitor, and its generation when options were instanciated
+	eval       *evalVisitor
+	generation uint64
+
+	// helper name
+	name string
 
 	// params
 	params []interface{}
 	hash   map[string]interface{}
+
+	// params and hash ids in trackIds mode
+	ids     []string
+	hashIds map[string]string
+
+	// params and hash types and contexts in stringParams mode
+	types        []ParamType
+	contexts     []interface{}
+	hashTypes    map[string]ParamType
+	hashContexts map[string]interface{}
+}
+
+// ParamType represents the type of a helper parameter in stringParams mode.
+type ParamType string
+
+// helper parameter types in stringParams mode
+const (
+	ParamTypeID            ParamType = "ID"
+	ParamTypeString        ParamType = "STRING"
+	ParamTypeNumber        ParamType = "NUMBER"
+	ParamTypeBoolean       ParamType = "BOOLEAN"
+	ParamTypeSubExpression ParamType = "SubExpression"
+)
+
+// names of the helpers called when a helper can't be resolved
+const (
+	helperMissingName      = "helperMissing"
+	blockHelperMissingName = "blockHelperMissing"
+)
+
+// variadicHelpers lists the helpers that accept any number of parameters when they only take an options argument
+var variadicHelpers = map[string]bool{
+	"log":                  true,
+	helperMissingName:      true,
+	blockHelperMissingName: true,
 }
 
 // helpers stores all globally registered helpers
@@ -23,6 +70,12 @@ var helpers = make(map[string]reflect.Value)
 // protects global helpers
 var helpersMutex sync.RWMutex
 
+// incremented each time a global helper is registered or removed, to invalidate memoized helper lookups
+var helpersGen uint64
+
+// template used to evaluate helpers called with CallHelper()
+var callHelperTemplate = newTemplate("")
+
 func init() {
 	// register builtin helpers
 	RegisterHelper("if", ifHelper)
@@ -47,6 +100,7 @@ func RegisterHelper(name string, helper interface{}) {
 	ensureValidHelper(name, val)
 
 	helpers[name] = val
+	atomic.AddUint64(&helpersGen, 1)
 }
 
 // RegisterHelpers registers several global helpers. Those helpers will be available to all templates.
@@ -62,6 +116,7 @@ func RemoveHelper(name string) {
 	defer helpersMutex.Unlock()
 
 	delete(helpers, name)
+	atomic.AddUint64(&helpersGen, 1)
 }
 
 // RemoveAllHelpers unregisters all global helpers
@@ -70,6 +125,7 @@ func RemoveAllHelpers() {
 	defer helpersMutex.Unlock()
 
 	helpers = make(map[string]reflect.Value)
+	atomic.AddUint64(&helpersGen, 1)
 }
 
 // ensureValidHelper panics if given helper is not valid
@@ -95,30 +151,74 @@ func findHelper(name string) reflect.Value {
 	return helpers[name]
 }
 
+// CallHelper calls the global helper registered with given name, with given context, parameters and hash, and returns its result.
+//
+// It is used by code generated with the gen package. The helper is not called for a block, so Options.Fn() and Options.Inverse() return an empty string.
+func CallHelper(name string, ctx interface{}, params []interface{}, hash map[string]interface{}) (result interface{}, err error) {
+	defer errRecover(&err)
+
+	helper := findHelper(name)
+	if helper == zero {
+		return nil, fmt.Errorf("Missing helper: %q", name)
+	}
+
+	v := newEvalVisitor(context.Background(), callHelperTemplate, ctx, nil, nil, io.Discard)
+	defer v.release()
+
+	result = v.callHelper(name, helper, newOptions(v, params, hash))
+
+	// named return values
+	return
+}
+
 // newOptions instanciates a new Options
 func newOptions(eval *evalVisitor, params []interface{}, hash map[string]interface{}) *Options {
 	return &Options{
-		eval:   eval,
-		params: params,
-		hash:   hash,
+		eval:       eval,
+		generation: eval.generation,
+		params:     params,
+		hash:       hash,
 	}
 }
 
 // newEmptyOptions instanciates a new empty Options
 func newEmptyOptions(eval *evalVisitor) *Options {
 	return &Options{
-		eval: eval,
-		hash: make(map[string]interface{}),
+		eval:       eval,
+		generation: eval.generation,
+		hash:       make(map[string]interface{}),
 	}
 }
 
+// errOptionsReleased is raised when helper options are used once the evaluation that called the helper is done
+var errOptionsReleased = errors.New("Helper options can't be used once template evaluation is done")
+
+// visitor returns the evaluation visitor, and panics if the evaluation that called the helper is done, as that
+// visitor may have been reused by another evaluation
+func (options *Options) visitor() *evalVisitor {
+	if options.eval.generation != options.generation {
+		panic(errOptionsReleased)
+	}
+
+	return options.eval
+}
+
+// Name returns the name of the called helper.
+//
+// In helperMissing and blockHelperMissing helpers, this is the name that could not be resolved.
+func (options *Options) Name() string {
+	return options.name
+}
+
 //
 // Context Values
 //
 
 // Value returns field value from current context.
 func (options *Options) Value(name string) interface{} {
-	value := options.eval.evalField(options.eval.curCtx(), name, false)
+	v := options.visitor()
+
+	value := v.evalField(v.curCtx(), name, false)
 	if !value.IsValid() {
 		return nil
 	}
@@ -133,7 +233,12 @@ func (options *Options) ValueStr(name string) string {
 
 // Ctx returns current evaluation context.
 func (options *Options) Ctx() interface{} {
-	return options.eval.curCtx().Interface()
+	return options.visitor().curCtx().Interface()
+}
+
+// Context returns the execution context provided to Template.ExecContext(), or context.Background() if template was not executed with that function.
+func (options *Options) Context() context.Context {
+	return options.visitor().execCtx
 }
 
 //
@@ -155,6 +260,21 @@ func (options *Options) Hash() map[string]interface{} {
 	return options.hash
 }
 
+// HashType returns the type of hash property in stringParams mode. It returns an empty string if stringParams mode is disabled.
+func (options *Options) HashType(name string) ParamType {
+	return options.hashTypes[name]
+}
+
+// HashContext returns the context a hash property that is a path would have been resolved in, in stringParams mode.
+func (options *Options) HashContext(name string) interface{} {
+	return options.hashContexts[name]
+}
+
+// HashID returns the path used to resolve hash property in trackIds mode, relatively to current context. It returns an empty string if that property is not a path, or if trackIds mode is disabled.
+func (options *Options) HashID(name string) string {
+	return options.hashIds[name]
+}
+
 //
 // Parameters
 //
@@ -178,35 +298,76 @@ func (options *Options) Params() []interface{} {
 	return options.params
 }
 
+// ParamType returns the type of parameter at given position in stringParams mode. It returns an empty string if stringParams mode is disabled.
+func (options *Options) ParamType(pos int) ParamType {
+	if len(options.types) > pos {
+		return options.types[pos]
+	}
+
+	return ""
+}
+
+// ParamContext returns the context a parameter that is a path would have been resolved in, in stringParams mode.
+func (options *Options) ParamContext(pos int) interface{} {
+	if len(options.contexts) > pos {
+		return options.contexts[pos]
+	}
+
+	return nil
+}
+
+// ParamID returns the path used to resolve parameter at given position in trackIds mode, relatively to current context. It returns an empty string if that parameter is not a path, or if trackIds mode is disabled.
+func (options *Options) ParamID(pos int) string {
+	if len(options.ids) > pos {
+		return options.ids[pos]
+	}
+
+	return ""
+}
+
 //
 // Private data
 //
 
 // Data returns private data value.
 func (options *Options) Data(name string) interface{} {
-	return options.eval.dataFrame.Get(name)
+	return options.visitor().dataFrame.Get(name)
 }
 
 // DataStr returns string representation of private data value.
 func (options *Options) DataStr(name string) string {
-	return Str(options.eval.dataFrame.Get(name))
+	return Str(options.visitor().dataFrame.Get(name))
 }
 
 // DataFrame returns current private data frame.
 func (options *Options) DataFrame() *DataFrame {
-	return options.eval.dataFrame
+	return options.visitor().dataFrame
 }
 
 // NewDataFrame instanciates a new data frame that is a copy of current evaluation data frame.
 //
 // Parent of returned data frame is set to current evaluation data frame.
 func (options *Options) NewDataFrame() *DataFrame {
-	return options.eval.dataFrame.Copy()
+	return options.visitor().dataFrame.Copy()
 }
 
 // newIterDataFrame instanciates a new data frame and set iteration specific vars
 func (options *Options) newIterDataFrame(length int, i int, key interface{}) *DataFrame {
-	return options.eval.dataFrame.newIterDataFrame(length, i, key)
+	v := options.visitor()
+
+	result := v.dataFrame.newIterDataFrame(length, i, key)
+
+	if v.trackIds {
+		// iterated context is a child of first parameter
+		field := key
+		if field == nil {
+			field = i
+		}
+
+		result.Set(contextPathName, appendContextPath(v.childContextPath(options.ParamID(0)), Str(field)))
+	}
+
+	return result
 }
 
 //
@@ -215,15 +376,26 @@ func (options *Options) newIterDataFrame(length int, i int, key interface{}) *Da
 
 // evalBlock evaluates block with given context, private data and iteration key
 func (options *Options) evalBlock(ctx interface{}, data *DataFrame, key interface{}) string {
+	v := options.visitor()
+
 	result := ""
 
-	if block := options.eval.curBlock(); (block != nil) && (block.Program != nil) {
-		result = options.eval.evalProgram(block.Program, ctx, data, key)
+	if block := v.curBlock(); (block != nil) && (block.Program != nil) {
+		result = v.evalProgram(block.Program, ctx, data, key)
 	}
 
 	return result
 }
 
+// writeBlock evaluates block with given context, private data and iteration key, and writes result to current output
+func (options *Options) writeBlock(ctx interface{}, data *DataFrame, key interface{}) {
+	v := options.visitor()
+
+	if block := v.curBlock(); (block != nil) && (block.Program != nil) {
+		v.writeProgram(block.Program, ctx, data, key)
+	}
+}
+
 // Fn evaluates block with current evaluation context.
 func (options *Options) Fn() string {
 	return options.evalBlock(nil, nil, nil)
@@ -246,14 +418,36 @@ func (options *Options) FnData(data *DataFrame) string {
 
 // Inverse evaluates "else block".
 func (options *Options) Inverse() string {
+	v := options.visitor()
+
 	result := ""
-	if block := options.eval.curBlock(); (block != nil) && (block.Inverse != nil) {
-		result, _ = block.Inverse.Accept(options.eval).(string)
+	if block := v.curBlock(); (block != nil) && (block.Inverse != nil) {
+		result = v.capture(func() {
+			block.Inverse.Accept(v)
+		})
 	}
 
 	return result
 }
 
+// RawContent returns the verbatim content of a raw block, ie. {{{{raw-helper}}}}content{{{{/raw-helper}}}}.
+//
+// Unlike Fn(), content is not affected by standalone lines whitespace control. It returns an empty string if the helper is not called for a raw block.
+func (options *Options) RawContent() string {
+	block := options.visitor().curBlock()
+	if (block == nil) || !block.Raw || (block.Program == nil) {
+		return ""
+	}
+
+	for _, node := range block.Program.Body {
+		if content, ok := node.(*ast.ContentStatement); ok {
+			return content.Original
+		}
+	}
+
+	return ""
+}
+
 // Eval evaluates field for given context.
 func (options *Options) Eval(ctx interface{}, field string) interface{} {
 	if ctx == nil {
@@ -264,7 +458,7 @@ func (options *Options) Eval(ctx interface{}, field string) interface{} {
 		return nil
 	}
 
-	val := options.eval.evalField(reflect.ValueOf(ctx), field, false)
+	val := options.visitor().evalField(reflect.ValueOf(ctx), field, false)
 	if !val.IsValid() {
 		return nil
 	}
@@ -314,7 +508,7 @@ func unlessHelper(conditional interface{}, options *Options) interface{} {
 // #with block helper
 func withHelper(context interface{}, options *Options) interface{} {
 	if IsTrue(context) {
-		return options.FnWith(context)
+		return options.FnCtxData(context, options.visitor().contextPathFrame(options.ParamID(0)))
 	}
 
 	return options.Inverse()
@@ -326,9 +520,14 @@ func eachHelper(context interface{}, options *Options) interface{} {
 		return options.Inverse()
 	}
 
-	result := ""
+	// iterations are written directly to the output of the block
+	options.eachBlock(reflect.ValueOf(context))
 
-	val := reflect.ValueOf(context)
+	return nil
+}
+
+// eachBlock evaluates block for each item of given value, and writes result to current output
+func (options *Options) eachBlock(val reflect.Value) {
 	switch val.Kind() {
 	case reflect.Array, reflect.Slice:
 		for i := 0; i < val.Len(); i++ {
@@ -336,7 +535,7 @@ func eachHelper(context interface{}, options *Options) interface{} {
 			data := options.newIterDataFrame(val.Len(), i, nil)
 
 			// evaluates block
-			result += options.evalBlock(val.Index(i).Interface(), data, i)
+			options.writeBlock(val.Index(i).Interface(), data, i)
 		}
 	case reflect.Map:
 		// note: a go hash is not ordered, so result may vary, this behaviour differs from the JS implementation
@@ -349,7 +548,7 @@ func eachHelper(context interface{}, options *Options) interface{} {
 			data := options.newIterDataFrame(len(keys), i, key)
 
 			// evaluates block
-			result += options.evalBlock(ctx, data, key)
+			options.writeBlock(ctx, data, key)
 		}
 	case reflect.Struct:
 		var exportedFields []int
@@ -369,16 +568,24 @@ func eachHelper(context interface{}, options *Options) interface{} {
 			data := options.newIterDataFrame(len(exportedFields), i, key)
 
 			// evaluates block
-			result += options.evalBlock(ctx, data, key)
+			options.writeBlock(ctx, data, key)
 		}
 	}
-
-	return result
 }
 
 // #log helper
-func logHelper(message string) interface{} {
-	log.Print(message)
+func logHelper(options *Options) interface{} {
+	level := LogInfo
+
+	if value := options.HashProp("level"); value != nil {
+		level, _ = lookupLogLevel(value)
+	} else if value := options.Data("level"); value != nil {
+		level, _ = lookupLogLevel(value)
+	}
+
+	v := options.visitor()
+	v.logger.Log(v.execCtx, level, options.Params()...)
+
 	return ""
 }
 
@@ -1,6 +1,9 @@
 package raymond
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 const (
 	VERBOSE = false
@@ -244,6 +247,29 @@ func TestRemoveHelper(t *testing.T) {
 	}
 }
 
+func TestHelperRegisteredAfterExec(t *testing.T) {
+	tpl := MustParse(`{{testlatehelper}} {{#testlatehelper}}block{{/testlatehelper}}`)
+	ctx := map[string]string{"testlatehelper": "field"}
+
+	if output := tpl.MustExec(ctx); output != "field block" {
+		t.Errorf("Unexpected output before helper registration: %q", output)
+	}
+
+	RegisterHelper("testlatehelper", func() string { return "global" })
+
+	if output := tpl.MustExec(ctx); output != "global global" {
+		t.Errorf("Unexpected output after global helper registration: %q", output)
+	}
+
+	RemoveHelper("testlatehelper")
+
+	tpl.RegisterHelper("testlatehelper", func() string { return "template" })
+
+	if output := tpl.MustExec(ctx); output != "template template" {
+		t.Errorf("Unexpected output after template helper registration: %q", output)
+	}
+}
+
 //
 // Fixes: https://github.com/aymerick/raymond/issues/2
 //
@@ -271,3 +297,82 @@ func TestHelperCtx(t *testing.T) {
 		t.Errorf("Failed to render template in helper: %q", result)
 	}
 }
+
+func TestCallHelper(t *testing.T) {
+	RegisterHelper("testcallhelper", func(name string, options *Options) SafeString {
+		return SafeString(options.HashStr("greeting") + " " + name + " from " + options.ValueStr("city"))
+	})
+	defer RemoveHelper("testcallhelper")
+
+	ctx := map[string]string{"city": "Paris"}
+
+	result, err := CallHelper("testcallhelper", ctx, []interface{}{"Alan"}, map[string]interface{}{"greeting": "Hi"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if result != SafeString("Hi Alan from Paris") {
+		t.Errorf("Unexpected helper result: %q", result)
+	}
+
+	if _, err := CallHelper("testcallhelper", ctx, nil, nil); err == nil {
+		t.Errorf("Expected an error when calling helper with wrong number of arguments")
+	}
+
+	if _, err := CallHelper("testcallhelpermissing", ctx, nil, nil); err == nil {
+		t.Errorf("Expected an error when calling a missing helper")
+	}
+}
+
+func TestHelperWithOptionsArgumentOnly(t *testing.T) {
+	t.Parallel()
+
+	helpers := []interface{}{
+		func(options *Options) string { return "foo" },
+		func(options *Options) SafeString { return "foo" },
+	}
+
+	for _, helper := range helpers {
+		tpl := MustParse(`{{foo a b}}`)
+		tpl.RegisterHelper("foo", helper)
+
+		_, err := tpl.Exec(map[string]string{"a": "a", "b": "b"})
+		if err == nil || !strings.Contains(err.Error(), "Helper 'foo' called with wrong number of arguments, needed 1 but got 2") {
+			t.Errorf("Expected an error when calling helper with wrong number of arguments, got: %v", err)
+		}
+	}
+}
+
+func TestHelperOptionsAfterExec(t *testing.T) {
+	t.Parallel()
+
+	var stored *Options
+
+	tpl := MustParse(`{{#store}}foo{{/store}}`)
+	tpl.RegisterHelper("store", func(options *Options) string {
+		stored = options
+		return options.Fn()
+	})
+
+	if output := tpl.MustExec(nil); output != "foo" {
+		t.Fatalf("Unexpected output: %q", output)
+	}
+
+	// options may be used by another evaluation that reuses the same visitor
+	other := MustParse(`{{#each items}}{{reuse}}{{/each}}`)
+	other.RegisterHelper("reuse", func() string {
+		return stored.Fn()
+	})
+
+	if _, err := other.Exec(map[string][]int{"items": {1, 2}}); err != errOptionsReleased {
+		t.Errorf("Expected an error when using helper options of a previous evaluation, got: %v", err)
+	}
+
+	defer func() {
+		if r := recover(); r != errOptionsReleased {
+			t.Errorf("Expected a panic when using helper options once evaluation is done, got: %v", r)
+		}
+	}()
+
+	stored.Fn()
+}
@@ -0,0 +1,311 @@
+package spectest
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aymerick/raymond"
+)
+
+var safeStringType = reflect.TypeOf(raymond.SafeString(""))
+
+// declarer declares the Go types and values of a test case data
+type declarer struct {
+	prefix string
+	decls  bytes.Buffer
+	nbType int
+
+	// declared types, by definition
+	types map[string]string
+}
+
+// root returns the declarations of the context type of given data, named <prefix>Ctx, and of a pointer to its value,
+// named <prefix>Value
+func (d *declarer) root(data interface{}) (string, error) {
+	if data == nil {
+		fmt.Fprintf(&d.decls, "\ntype %sCtx struct{}\n\nvar %sValue *%sCtx\n", d.prefix, d.prefix, d.prefix)
+
+		return d.decls.String(), nil
+	}
+
+	val := reflect.ValueOf(data)
+	if (val.Kind() == reflect.Ptr) && !val.IsNil() {
+		val = val.Elem()
+	}
+
+	typ, lit, err := d.declare(val)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(&d.decls, "\ntype %sCtx %s\n\nvar %sData = %sCtx(%s)\n\nvar %sValue = &%sData\n", d.prefix, typ, d.prefix, d.prefix, lit, d.prefix, d.prefix)
+
+	return d.decls.String(), nil
+}
+
+// declare returns the type and the literal of given value
+func (d *declarer) declare(val reflect.Value) (string, string, error) {
+	if !val.IsValid() {
+		return "interface{}", "nil", nil
+	}
+
+	if val.Type() == safeStringType {
+		return "raymond.SafeString", fmt.Sprintf("raymond.SafeString(%s)", strconv.Quote(val.String())), nil
+	}
+
+	if (val.Kind() != reflect.Interface) && (val.Kind() != reflect.Func) && hasMethods(val.Type()) {
+		// the interpreter would call those methods
+		return "", "", fmt.Errorf("type %s has methods", val.Type())
+	}
+
+	switch val.Kind() {
+	case reflect.Interface:
+		if val.IsNil() {
+			return "interface{}", "nil", nil
+		}
+
+		return d.declare(val.Elem())
+
+	case reflect.Bool:
+		return "bool", strconv.FormatBool(val.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Kind().String(), fmt.Sprintf("%s(%d)", val.Kind(), val.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return val.Kind().String(), fmt.Sprintf("%s(%d)", val.Kind(), val.Uint()), nil
+
+	case reflect.Float32, reflect.Float64:
+		return val.Kind().String(), fmt.Sprintf("%s(%s)", val.Kind(), strconv.FormatFloat(val.Float(), 'g', -1, 64)), nil
+
+	case reflect.String:
+		return "string", strconv.Quote(val.String()), nil
+
+	case reflect.Slice, reflect.Array:
+		return d.declareSlice(val)
+
+	case reflect.Map:
+		return d.declareMap(val)
+
+	case reflect.Struct:
+		return d.declareStruct(val)
+
+	case reflect.Ptr:
+		if val.IsNil() {
+			typ, _, err := d.declare(reflect.Zero(val.Type().Elem()))
+			if err != nil {
+				return "", "", err
+			}
+
+			return "*" + typ, fmt.Sprintf("(*%s)(nil)", typ), nil
+		}
+
+		if val.Elem().Kind() != reflect.Struct {
+			return "", "", fmt.Errorf("pointer of type %s", val.Type())
+		}
+
+		typ, lit, err := d.declare(val.Elem())
+		if err != nil {
+			return "", "", err
+		}
+
+		return "*" + typ, "&" + lit, nil
+
+	case reflect.Func:
+		typ, ok := funcType(val.Type())
+		if !ok {
+			return "", "", fmt.Errorf("function of type %s", val.Type())
+		}
+
+		// never called: the generator doesn't support function values
+		return typ, fmt.Sprintf("(%s)(nil)", typ), nil
+	}
+
+	return "", "", fmt.Errorf("value of type %s", val.Type())
+}
+
+// declareSlice returns the type and the literal of given slice or array
+func (d *declarer) declareSlice(val reflect.Value) (string, string, error) {
+	var elemTypes, elemLits []string
+
+	for i := 0; i < val.Len(); i++ {
+		typ, lit, err := d.declare(val.Index(i))
+		if err != nil {
+			return "", "", err
+		}
+
+		elemTypes = append(elemTypes, typ)
+		elemLits = append(elemLits, lit)
+	}
+
+	elemType := commonType(elemTypes)
+
+	if (val.Kind() == reflect.Slice) && val.IsNil() {
+		return "[]" + elemType, fmt.Sprintf("[]%s(nil)", elemType), nil
+	}
+
+	return "[]" + elemType, fmt.Sprintf("[]%s{%s}", elemType, strings.Join(elemLits, ", ")), nil
+}
+
+// declareMap returns the type and the literal of given map
+//
+// A map whose values have different types is declared as a struct, with a field tagged with each key.
+func (d *declarer) declareMap(val reflect.Value) (string, string, error) {
+	entries := make(map[string]reflect.Value)
+
+	for _, key := range val.MapKeys() {
+		if key.Kind() == reflect.Interface {
+			key = key.Elem()
+		}
+
+		if key.Kind() != reflect.String {
+			return "", "", fmt.Errorf("map key of type %s", key.Type())
+		}
+
+		entries[key.String()] = val.MapIndex(key)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var elemTypes, elemLits []string
+
+	for _, key := range keys {
+		typ, lit, err := d.declare(entries[key])
+		if err != nil {
+			return "", "", err
+		}
+
+		elemTypes = append(elemTypes, typ)
+		elemLits = append(elemLits, lit)
+	}
+
+	elemType := commonType(elemTypes)
+
+	if (elemType != "interface{}") || (len(keys) == 0) {
+		lits := make([]string, len(keys))
+		for i, key := range keys {
+			lits[i] = fmt.Sprintf("%s: %s", strconv.Quote(key), elemLits[i])
+		}
+
+		return "map[string]" + elemType, fmt.Sprintf("map[string]%s{%s}", elemType, strings.Join(lits, ", ")), nil
+	}
+
+	fields := make([]string, len(keys))
+	lits := make([]string, len(keys))
+
+	for i, key := range keys {
+		fields[i] = fmt.Sprintf("Field_%d %s `handlebars:%s`", i, elemTypes[i], strconv.Quote(key))
+		lits[i] = fmt.Sprintf("Field_%d: %s", i, elemLits[i])
+	}
+
+	typ := d.declareType("struct {\n" + strings.Join(fields, "\n") + "\n}")
+
+	return typ, fmt.Sprintf("%s{%s}", typ, strings.Join(lits, ", ")), nil
+}
+
+// declareStruct returns the type and the literal of given struct
+func (d *declarer) declareStruct(val reflect.Value) (string, string, error) {
+	var fields, lits []string
+
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		if field.Anonymous {
+			return "", "", fmt.Errorf("embedded field %s", field.Name)
+		}
+
+		typ, lit, err := d.declare(val.Field(i))
+		if err != nil {
+			return "", "", err
+		}
+
+		if field.Type.Kind() == reflect.Interface {
+			typ = "interface{}"
+		}
+
+		decl := field.Name + " " + typ
+		if field.Tag != "" {
+			decl += " " + strconv.Quote(string(field.Tag))
+		}
+
+		fields = append(fields, decl)
+		lits = append(lits, fmt.Sprintf("%s: %s", field.Name, lit))
+	}
+
+	typ := d.declareType("struct {\n" + strings.Join(fields, "\n") + "\n}")
+
+	return typ, fmt.Sprintf("%s{%s}", typ, strings.Join(lits, ", ")), nil
+}
+
+// declareType declares a type with given definition, and returns its name
+func (d *declarer) declareType(def string) string {
+	if name, ok := d.types[def]; ok {
+		return name
+	}
+
+	if d.types == nil {
+		d.types = make(map[string]string)
+	}
+
+	d.nbType++
+
+	name := fmt.Sprintf("%sT%d", d.prefix, d.nbType)
+
+	fmt.Fprintf(&d.decls, "\ntype %s %s\n", name, def)
+	d.types[def] = name
+
+	return name
+}
+
+// commonType returns the type of all given types if they are the same, and interface{} otherwise
+func commonType(types []string) string {
+	if len(types) == 0 {
+		return "interface{}"
+	}
+
+	for _, typ := range types[1:] {
+		if typ != types[0] {
+			return "interface{}"
+		}
+	}
+
+	return types[0]
+}
+
+// hasMethods returns true if given type, or a pointer to it, has methods
+func hasMethods(typ reflect.Type) bool {
+	return (typ.NumMethod() > 0) || (reflect.PtrTo(typ).NumMethod() > 0)
+}
+
+// funcType returns the Go source of given function type, and false if it uses types of other packages than raymond
+func funcType(typ reflect.Type) (string, bool) {
+	for _, t := range append(funcParams(typ.In, typ.NumIn()), funcParams(typ.Out, typ.NumOut())...) {
+		for (t.Kind() == reflect.Ptr) || (t.Kind() == reflect.Slice) {
+			t = t.Elem()
+		}
+
+		if (t.PkgPath() != "") && (t.PkgPath() != safeStringType.PkgPath()) {
+			return "", false
+		}
+	}
+
+	return typ.String(), true
+}
+
+// funcParams returns the types of the parameters or results of a function type
+func funcParams(get func(int) reflect.Type, nb int) []reflect.Type {
+	result := make([]reflect.Type, nb)
+	for i := range result {
+		result[i] = get(i)
+	}
+
+	return result
+}
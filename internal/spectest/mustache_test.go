@@ -0,0 +1,89 @@
+package spectest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+var mustacheSpecsDir = filepath.Join("..", "..", "mustache", "specs")
+
+type mustacheTest struct {
+	Name     string
+	Data     interface{}
+	Template string
+	Expected string
+}
+
+type mustacheTestFile struct {
+	Tests []mustacheTest
+}
+
+// TestMustache runs the mustache specs through the code generator
+//
+// The ~lambdas.yml specs are not run, as lambdas are implemented in Go by the raymond tests.
+//
+// The tests that fall back on the interpreter, or that are not run, are listed in testdata/mustache.txt
+func TestMustache(t *testing.T) {
+	if _, err := os.Stat(mustacheSpecsDir); err != nil {
+		t.Skipf("Mustache specs not found: %s", err)
+	}
+
+	Run(t, mustacheCases(t), "mustacheCases", filepath.Join("testdata", "mustache.txt"))
+}
+
+// mustacheCases returns the mustache specs test cases
+func mustacheCases(t *testing.T) []Case {
+	files, err := ioutil.ReadDir(mustacheSpecsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cases []Case
+
+	for _, file := range files {
+		fileName := file.Name()
+		if file.IsDir() || !strings.HasSuffix(fileName, ".yml") || (fileName == "~lambdas.yml") {
+			continue
+		}
+
+		fileData, err := ioutil.ReadFile(filepath.Join(mustacheSpecsDir, fileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var testFile mustacheTestFile
+		if err := yaml.Unmarshal(fileData, &testFile); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, test := range testFile.Tests {
+			if mustBeSkipped(test, fileName) {
+				continue
+			}
+
+			cases = append(cases, Case{
+				Name:   fileName + ": " + test.Name,
+				Input:  test.Template,
+				Data:   test.Data,
+				Output: test.Expected,
+			})
+		}
+	}
+
+	return cases
+}
+
+// mustBeSkipped returns true if test is skipped by the raymond tests
+func mustBeSkipped(test mustacheTest, fileName string) bool {
+	switch fileName {
+	case "partials.yml", "~dynamic-names.yml":
+		return test.Name == "Failed Lookup" || test.Name == "Standalone Indentation"
+	}
+
+	return false
+}
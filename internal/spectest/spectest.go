@@ -0,0 +1,457 @@
+// Package spectest runs test suites through the code generator of the gen package.
+//
+// The context type of each test template is declared from the test data, then the template is generated, compiled
+// and run, and its output is checked against the expected one. The test cases that fall back on the interpreter, or
+// that can't be run, are checked against a baseline file, so that they can't grow silently.
+package spectest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aymerick/raymond"
+	"github.com/aymerick/raymond/gen"
+)
+
+var update = flag.Bool("update", false, "update the baseline of test cases that are not generated")
+
+// Case represents a test case
+type Case struct {
+	Name     string
+	Input    string
+	Data     interface{}
+	PrivData map[string]interface{}
+	Helpers  map[string]interface{}
+
+	// Output is the expected output, or a list of accepted outputs
+	Output interface{}
+}
+
+// Result represents the output of a generated template
+type Result struct {
+	Output string
+	Err    string
+}
+
+// testedPackage represents the test package that calls Run
+type testedPackage struct {
+	path string
+	name string
+}
+
+// generated represents a generated test case
+type generated struct {
+	index  int
+	source []byte
+}
+
+// Run runs given test cases through the code generator.
+//
+// The generated code is run by a child go test process of the calling test package, that is built with the generated
+// files and with a test function that registers the helpers of test cases. That test function gets the test cases by
+// calling the function with given name, declared in the calling test package as: func(t *testing.T) []Case
+//
+// The test cases that fall back on the interpreter, or that can't be run, must be the ones listed in given baseline
+// file. Run the tests with the -update flag to rewrite it.
+//
+// Run is skipped in short mode, or if the go command is not available.
+func Run(t *testing.T, cases []Case, casesFunc string, baseline string) {
+	if testing.Short() {
+		t.Skip("Skipping generated code tests in short mode")
+	}
+
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("Skipping generated code tests: go command not found")
+	}
+
+	// the calling test package
+	listed, err := exec.Command(goCmd, "list", "-f", "{{.ImportPath}} {{.Name}}", ".").Output()
+	if err != nil {
+		t.Fatalf("Failed to list calling package: %s", err)
+	}
+
+	var callingPkg testedPackage
+	if _, err := fmt.Sscan(string(listed), &callingPkg.path, &callingPkg.name); err != nil {
+		t.Fatalf("Failed to list calling package: %s", err)
+	}
+
+	fset := token.NewFileSet()
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+
+	// declare the context types of test cases
+	decls := new(bytes.Buffer)
+	fmt.Fprintf(decls, "package %s\n\nimport \"github.com/aymerick/raymond\"\n\nvar _ raymond.SafeString\n", callingPkg.name)
+
+	declared := make(map[int]bool)
+	var report []string
+
+	for i, test := range cases {
+		if test.PrivData != nil {
+			report = append(report, fmt.Sprintf("not run: %s: private data can't be passed to generated code", test.Name))
+			continue
+		}
+
+		if (test.Helpers["helperMissing"] != nil) || (test.Helpers["blockHelperMissing"] != nil) {
+			report = append(report, fmt.Sprintf("not run: %s: generated code doesn't call helperMissing and blockHelperMissing", test.Name))
+			continue
+		}
+
+		if name := overriddenBuiltin(test.Helpers); name != "" {
+			report = append(report, fmt.Sprintf("not run: %s: helper %q overrides a builtin global helper", test.Name, name))
+			continue
+		}
+
+		d := &declarer{prefix: fmt.Sprintf("C%d", i)}
+
+		source, err := d.root(test.Data)
+		if err != nil {
+			report = append(report, fmt.Sprintf("not run: %s: context can't be declared: %s", test.Name, err))
+			continue
+		}
+
+		decls.WriteString(source)
+		declared[i] = true
+	}
+
+	declsFile, err := parser.ParseFile(fset, "decls.go", decls.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("Failed to parse declared context types: %s\n%s", err, decls.Bytes())
+	}
+
+	pkg, err := conf.Check(callingPkg.path, fset, []*ast.File{declsFile}, nil)
+	if err != nil {
+		t.Fatalf("Failed to check declared context types: %s", err)
+	}
+
+	// generate templates
+	var runs []generated
+
+	for i, test := range cases {
+		if !declared[i] {
+			continue
+		}
+
+		name := fmt.Sprintf("C%d", i)
+
+		typ, err := gen.LookupType(pkg, name+"Ctx")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var helpers []string
+		for helperName := range test.Helpers {
+			helpers = append(helpers, helperName)
+		}
+
+		var fallback error
+
+		source, err := gen.Generate(gen.Config{
+			Package:   pkg,
+			Helpers:   helpers,
+			Templates: []gen.Template{{Name: name, Source: test.Input, Type: typ}},
+			Fallback: func(name string, err error) {
+				fallback = err
+			},
+		})
+		if err != nil {
+			t.Errorf("Test '%s' failed\ninput:\n\t%q\ngeneration error:\n\t%s", test.Name, test.Input, err)
+			continue
+		}
+
+		if fallback != nil {
+			// the declared type names depend on the test case index
+			reason := strings.Replace(fallback.Error(), name+"T", "T", -1)
+
+			report = append(report, fmt.Sprintf("fallback: %s: %s", test.Name, reason))
+			continue
+		}
+
+		genFile, err := parser.ParseFile(fset, name+"_gen.go", source, 0)
+		if err == nil {
+			_, err = conf.Check(callingPkg.path, fset, []*ast.File{declsFile, genFile}, nil)
+		}
+
+		if err != nil {
+			t.Errorf("Test '%s' failed\ninput:\n\t%q\ngenerated code does not compile: %s\n%s", test.Name, test.Input, err, source)
+			continue
+		}
+
+		runs = append(runs, generated{index: i, source: source})
+	}
+
+	results := runGenerated(t, goCmd, callingPkg, casesFunc, decls.Bytes(), runs)
+
+	for _, run := range runs {
+		test := cases[run.index]
+
+		res, ok := results[run.index]
+		switch {
+		case !ok:
+			t.Errorf("Test '%s' failed\nmissing generated code result", test.Name)
+		case res.Err != "":
+			t.Errorf("Test '%s' failed\ninput:\n\t%q\ngenerated code error:\n\t%s", test.Name, test.Input, res.Err)
+		case !isExpected(test.Output, res.Output):
+			t.Errorf("Test '%s' failed\ninput:\n\t%q\nexpected:\n\t%q\ngenerated code output:\n\t%q\ngenerated code:\n%s", test.Name, test.Input, test.Output, res.Output, run.source)
+		}
+	}
+
+	checkBaseline(t, baseline, report)
+
+	t.Logf("%d tests: %d generated and run, %d fall back on the interpreter or are not run", len(cases), len(runs), len(report))
+}
+
+// builtinHelpers are the global helpers registered by raymond, that generated code can't replace by test case helpers
+var builtinHelpers = []string{"if", "unless", "with", "each", "log", "lookup", "equal"}
+
+// overriddenBuiltin returns the name of a builtin helper overridden by given helpers, or an empty string
+func overriddenBuiltin(helpers map[string]interface{}) string {
+	for _, name := range builtinHelpers {
+		if helpers[name] != nil {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// isExpected returns true if given output is the expected one
+func isExpected(expected interface{}, output string) bool {
+	if list, ok := expected.([]string); ok {
+		for _, str := range list {
+			if str == output {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return expected == output
+}
+
+// checkBaseline checks that given report of test cases that are not generated matches given baseline file
+func checkBaseline(t *testing.T, baseline string, report []string) {
+	sort.Strings(report)
+
+	content := ""
+	for _, line := range report {
+		content += line + "\n"
+	}
+
+	if *update {
+		if err := ioutil.WriteFile(baseline, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := make(map[string]int)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			expected[line]++
+		}
+	}
+
+	var added []string
+
+	for _, line := range report {
+		if expected[line] > 0 {
+			expected[line]--
+		} else {
+			added = append(added, line)
+		}
+	}
+
+	var removed []string
+
+	for line, nb := range expected {
+		for i := 0; i < nb; i++ {
+			removed = append(removed, line)
+		}
+	}
+
+	sort.Strings(removed)
+
+	if (len(added) > 0) || (len(removed) > 0) {
+		t.Errorf("Test cases that are not generated differ from %s, run the tests with -update to rewrite it\nnew:\n\t%s\nremoved:\n\t%s", baseline, strings.Join(added, "\n\t"), strings.Join(removed, "\n\t"))
+	}
+}
+
+// runGenerated runs the generated code, and returns the results by test case index
+func runGenerated(t *testing.T, goCmd string, callingPkg testedPackage, casesFunc string, decls []byte, runs []generated) map[int]Result {
+	if len(runs) == 0 {
+		return nil
+	}
+
+	pkgDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "spectest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	resultsFile := filepath.Join(dir, "results.json")
+
+	// the generated files are added to the calling test package with an overlay
+	overlay := make(map[string]string)
+
+	addFile := func(name string, source []byte) {
+		fileName := filepath.Join(dir, name)
+
+		if err := ioutil.WriteFile(fileName, source, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		overlay[filepath.Join(pkgDir, "zz_spectest_"+name)] = fileName
+	}
+
+	test := new(bytes.Buffer)
+	if callingPkg.path == selfImportPath {
+		fmt.Fprintf(test, selfTestHeader, callingPkg.name, casesFunc, resultsFile)
+	} else {
+		fmt.Fprintf(test, testHeader, callingPkg.name, casesFunc, resultsFile)
+	}
+
+	for _, run := range runs {
+		fmt.Fprintf(test, "\t\t%d: func(w io.Writer) error { return RenderC%d(w, C%dValue) },\n", run.index, run.index, run.index)
+
+		addFile(fmt.Sprintf("c%d_gen_test.go", run.index), run.source)
+	}
+
+	test.WriteString("\t})\n}\n")
+
+	addFile("decls_test.go", decls)
+	addFile("run_test.go", test.Bytes())
+
+	overlayJSON, err := json.Marshal(map[string]interface{}{"Replace": overlay})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlayFile := filepath.Join(dir, "overlay.json")
+	if err := ioutil.WriteFile(overlayFile, overlayJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(goCmd, "test", "-overlay", overlayFile, "-count", "1", "-run", "^TestSpectestGenerated$", ".")
+	cmd.Dir = pkgDir
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to run generated code: %s\n%s", err, output)
+	}
+
+	data, err := ioutil.ReadFile(resultsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results map[int]Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatalf("Failed to decode generated code results: %s", err)
+	}
+
+	return results
+}
+
+// selfImportPath is the import path of this package
+const selfImportPath = "github.com/aymerick/raymond/internal/spectest"
+
+// testHeader is the beginning of the test function that runs the generated code in the child go test process
+const testHeader = `package %s
+
+import (
+	"io"
+	"testing"
+
+	"github.com/aymerick/raymond/internal/spectest"
+)
+
+func TestSpectestGenerated(t *testing.T) {
+	spectest.Exec(t, %s(t), %q, map[int]func(io.Writer) error{
+`
+
+// selfTestHeader is the beginning of the test function that runs the generated code of this package test cases
+const selfTestHeader = `package %s
+
+import (
+	"io"
+	"testing"
+)
+
+func TestSpectestGenerated(t *testing.T) {
+	Exec(t, %s(t), %q, map[int]func(io.Writer) error{
+`
+
+// Exec is called by the child go test process started by Run: it calls given render functions of generated templates,
+// by test case index, and writes the results to given file.
+//
+// The helpers of a test case are registered as global helpers while its template is rendered.
+func Exec(t *testing.T, cases []Case, resultsFile string, renders map[int]func(io.Writer) error) {
+	results := make(map[int]Result)
+
+	for i, render := range renders {
+		results[i] = execCase(cases[i], render)
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(resultsFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// execCase renders given test case with given render function
+func execCase(test Case, render func(io.Writer) error) (result Result) {
+	var registered []string
+
+	defer func() {
+		for _, name := range registered {
+			raymond.RemoveHelper(name)
+		}
+
+		if e := recover(); e != nil {
+			result.Err = fmt.Sprintf("panic: %v", e)
+		}
+	}()
+
+	for name, helper := range test.Helpers {
+		raymond.RegisterHelper(name, helper)
+		registered = append(registered, name)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := render(buf); err != nil {
+		result.Err = err.Error()
+	}
+
+	result.Output = buf.String()
+
+	return
+}
@@ -0,0 +1,208 @@
+package raymond
+
+import (
+	"fmt"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+// builtinKnownHelpers lists helpers that are known by default in knownHelpersOnly mode
+var builtinKnownHelpers = []string{
+	"if", "unless", "with", "each", "log", "lookup", "equal",
+	helperMissingName, blockHelperMissingName,
+}
+
+// knownHelpersSet returns the set of known helpers for given parse options, or nil if knownHelpersOnly mode is disabled
+func knownHelpersSet(options ParseOptions) map[string]bool {
+	if !options.KnownHelpersOnly {
+		return nil
+	}
+
+	result := make(map[string]bool)
+
+	for _, name := range builtinKnownHelpers {
+		result[name] = true
+	}
+
+	for name, known := range options.KnownHelpers {
+		if known {
+			result[name] = true
+		} else {
+			delete(result, name)
+		}
+	}
+
+	return result
+}
+
+// UnknownHelperError is returned when a template parsed in knownHelpersOnly mode calls an unknown helper.
+type UnknownHelperError struct {
+	Name string
+	Line int
+	Pos  int
+}
+
+// Error implements the error interface
+func (err *UnknownHelperError) Error() string {
+	return fmt.Sprintf("Unknown helper %q on line %d", err.Name, err.Line)
+}
+
+// knownHelpersVisitor walks through the AST to check that all helper calls are known
+type knownHelpersVisitor struct {
+	known map[string]bool
+}
+
+// checkKnownHelpers returns an error if given program calls an helper that is not known
+func checkKnownHelpers(program *ast.Program, known map[string]bool) (err error) {
+	defer errRecover(&err)
+
+	program.Accept(&knownHelpersVisitor{known: known})
+
+	return nil
+}
+
+// checkExpression panics if given expression is a call to an unknown helper
+//
+// A sub-expression is always a helper call, other expressions are helper calls only when they have parameters or hash.
+func (v *knownHelpersVisitor) checkExpression(node *ast.Expression, helperCall bool) {
+	name := node.HelperName()
+	if name == "" || v.known[name] {
+		return
+	}
+
+	if helperCall || (len(node.Params) > 0) || (node.Hash != nil) {
+		panic(&UnknownHelperError{
+			Name: name,
+			Line: node.Line,
+			Pos:  node.Pos,
+		})
+	}
+}
+
+// acceptParams visits given params and hash
+func (v *knownHelpersVisitor) acceptParams(params []ast.Node, hash *ast.Hash) {
+	for _, param := range params {
+		param.Accept(v)
+	}
+
+	if hash != nil {
+		hash.Accept(v)
+	}
+}
+
+//
+// Visitor interface
+//
+
+// VisitProgram implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitProgram(node *ast.Program) interface{} {
+	for _, n := range node.Body {
+		n.Accept(v)
+	}
+
+	return nil
+}
+
+// VisitMustache implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitMustache(node *ast.MustacheStatement) interface{} {
+	return node.Expression.Accept(v)
+}
+
+// VisitBlock implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitBlock(node *ast.BlockStatement) interface{} {
+	node.Expression.Accept(v)
+
+	if node.Program != nil {
+		node.Program.Accept(v)
+	}
+
+	if node.Inverse != nil {
+		node.Inverse.Accept(v)
+	}
+
+	return nil
+}
+
+// VisitPartial implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitPartial(node *ast.PartialStatement) interface{} {
+	node.Name.Accept(v)
+
+	v.acceptParams(node.Params, node.Hash)
+
+	return nil
+}
+
+// VisitPartialBlock implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitPartialBlock(node *ast.PartialBlockStatement) interface{} {
+	node.Name.Accept(v)
+
+	v.acceptParams(node.Params, node.Hash)
+
+	return node.Program.Accept(v)
+}
+
+// VisitParent implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitParent(node *ast.ParentStatement) interface{} {
+	return node.Program.Accept(v)
+}
+
+// VisitNamedBlock implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitNamedBlock(node *ast.NamedBlockStatement) interface{} {
+	return node.Program.Accept(v)
+}
+
+// VisitDecorator implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitDecorator(node *ast.Decorator) interface{} {
+	// decorator name is not a helper
+	v.acceptParams(node.Expression.Params, node.Expression.Hash)
+
+	return nil
+}
+
+// VisitDecoratorBlock implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitDecoratorBlock(node *ast.DecoratorBlock) interface{} {
+	// decorator name is not a helper
+	v.acceptParams(node.Expression.Params, node.Expression.Hash)
+
+	return node.Program.Accept(v)
+}
+
+// VisitExpression implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitExpression(node *ast.Expression) interface{} {
+	v.checkExpression(node, false)
+
+	v.acceptParams(node.Params, node.Hash)
+
+	return nil
+}
+
+// VisitSubExpression implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitSubExpression(node *ast.SubExpression) interface{} {
+	v.checkExpression(node.Expression, true)
+
+	v.acceptParams(node.Expression.Params, node.Expression.Hash)
+
+	return nil
+}
+
+// VisitHash implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitHash(node *ast.Hash) interface{} {
+	for _, pair := range node.Pairs {
+		pair.Accept(v)
+	}
+
+	return nil
+}
+
+// VisitHashPair implements corresponding Visitor interface method
+func (v *knownHelpersVisitor) VisitHashPair(node *ast.HashPair) interface{} {
+	return node.Val.Accept(v)
+}
+
+// NOOP
+func (v *knownHelpersVisitor) VisitContent(node *ast.ContentStatement) interface{} { return nil }
+func (v *knownHelpersVisitor) VisitComment(node *ast.CommentStatement) interface{} { return nil }
+func (v *knownHelpersVisitor) VisitPath(node *ast.PathExpression) interface{}      { return nil }
+func (v *knownHelpersVisitor) VisitString(node *ast.StringLiteral) interface{}     { return nil }
+func (v *knownHelpersVisitor) VisitBoolean(node *ast.BooleanLiteral) interface{}   { return nil }
+func (v *knownHelpersVisitor) VisitNumber(node *ast.NumberLiteral) interface{}     { return nil }
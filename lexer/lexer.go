@@ -3,7 +3,6 @@ package lexer
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -14,13 +13,9 @@ import (
 //   - https://github.com/golang/go/blob/master/src/text/template/parse/lex.go
 
 const (
-	// Mustaches detection
-	escapedEscapedOpenMustache  = "\\\\{{"
-	escapedOpenMustache         = "\\{{"
-	openMustache                = "{{"
-	closeMustache               = "}}"
-	closeStripMustache          = "~}}"
-	closeUnescapedStripMustache = "}~}}"
+	// default mustaches delimiters
+	defaultOpenDelimiter  = "{{"
+	defaultCloseDelimiter = "}}"
 )
 
 const eof = -1
@@ -29,11 +24,16 @@ const eof = -1
 type lexFunc func(*Lexer) lexFunc
 
 // Lexer is a lexical analyzer.
+//
+// Tokens are scanned on demand, each time NextToken() is called.
 type Lexer struct {
-	input    string     // input to scan
-	name     string     // lexer name, used for testing purpose
-	tokens   chan Token // channel of scanned tokens
-	nextFunc lexFunc    // the next function to execute
+	input    string  // input to scan
+	name     string  // lexer name, used for testing purpose
+	nextFunc lexFunc // the next function to execute
+
+	tokens   [2]Token // scanned tokens, not fetched yet
+	nbTokens int      // number of scanned tokens, not fetched yet
+	last     Token    // last fetched token
 
 	pos   int // current byte position in input string
 	line  int // current line position in input string
@@ -41,66 +41,116 @@ type Lexer struct {
 	start int // start position of the token we are scanning
 
 	// the shameful contextual properties needed because `nextFunc` is not enough
-	closeComment *regexp.Regexp // regexp to scan close of current comment
-	rawBlock     bool           // are we parsing a raw block content ?
+	commentDash bool        // is current comment a {{!-- ... --}} one ?
+	rawBlock    bool        // are we parsing a raw block content ?
+	delims      *delimiters // current mustaches delimiters
 }
 
-var (
-	lookheadChars        = `[\s` + regexp.QuoteMeta("=~}/)|") + `]`
-	literalLookheadChars = `[\s` + regexp.QuoteMeta("~})") + `]`
+// delimiters holds the strings used to scan mustaches with given delimiters
+type delimiters struct {
+	open  string
+	close string
+
+	escapedOpen         string // \{{
+	escapedEscapedOpen  string // \\{{
+	openSet             string // {{=
+	closeSet            string // =}}
+	closeStrip          string // ~}}
+	closeUnescaped      string // }}}
+	closeUnescapedStrip string // }~}}
+	closeRaw            string // }}}}
+	openEndRaw          string // {{{{/
+	closeFirst          string // first character of close delimiter
+}
 
+var (
 	// characters not allowed in an identifier
 	unallowedIDChars = " \n\t!\"#%&'()*+,./;<=>@[\\]^`{|}~"
 
-	// regular expressions
-	rID                  = regexp.MustCompile(`^[^` + regexp.QuoteMeta(unallowedIDChars) + `]+`)
-	rDotID               = regexp.MustCompile(`^\.` + lookheadChars)
-	rTrue                = regexp.MustCompile(`^true` + literalLookheadChars)
-	rFalse               = regexp.MustCompile(`^false` + literalLookheadChars)
-	rOpenRaw             = regexp.MustCompile(`^\{\{\{\{`)
-	rCloseRaw            = regexp.MustCompile(`^\}\}\}\}`)
-	rOpenEndRaw          = regexp.MustCompile(`^\{\{\{\{/`)
-	rOpenEndRawLookAhead = regexp.MustCompile(`\{\{\{\{/`)
-	rOpenUnescaped       = regexp.MustCompile(`^\{\{~?\{`)
-	rCloseUnescaped      = regexp.MustCompile(`^\}~?\}\}`)
-	rOpenBlock           = regexp.MustCompile(`^\{\{~?#`)
-	rOpenEndBlock        = regexp.MustCompile(`^\{\{~?/`)
-	rOpenPartial         = regexp.MustCompile(`^\{\{~?>`)
-	// {{^}} or {{else}}
-	rInverse          = regexp.MustCompile(`^(\{\{~?\^\s*~?\}\}|\{\{~?\s*else\s*~?\}\})`)
-	rOpenInverse      = regexp.MustCompile(`^\{\{~?\^`)
-	rOpenInverseChain = regexp.MustCompile(`^\{\{~?\s*else`)
-	// {{ or {{&
-	rOpen            = regexp.MustCompile(`^\{\{~?&?`)
-	rClose           = regexp.MustCompile(`^~?\}\}`)
-	rOpenBlockParams = regexp.MustCompile(`^as\s+\|`)
-	// {{!--  ... --}}
-	rOpenCommentDash  = regexp.MustCompile(`^\{\{~?!--\s*`)
-	rCloseCommentDash = regexp.MustCompile(`^\s*--~?\}\}`)
-	// {{! ... }}
-	rOpenComment  = regexp.MustCompile(`^\{\{~?!\s*`)
-	rCloseComment = regexp.MustCompile(`^\s*~?\}\}`)
+	// default delimiters
+	defaultDelimiters = newDelimiters(defaultOpenDelimiter, defaultCloseDelimiter)
 )
 
+// newDelimiters instanciates the delimiters structure for given open and close mustaches
+func newDelimiters(open, close string) *delimiters {
+	// first character of close delimiter
+	_, w := utf8.DecodeRuneInString(close)
+
+	return &delimiters{
+		open:  open,
+		close: close,
+
+		escapedOpen:         "\\" + open,
+		escapedEscapedOpen:  "\\\\" + open,
+		openSet:             open + "=",
+		closeSet:            "=" + close,
+		closeStrip:          "~" + close,
+		closeUnescaped:      "}" + close,
+		closeUnescapedStrip: "}~" + close,
+		closeRaw:            "}}" + close,
+		openEndRaw:          open + "{{/",
+		closeFirst:          close[:w],
+	}
+}
+
+// Options represents scanning options.
+type Options struct {
+	// OpenDelimiter and CloseDelimiter are the mustaches delimiters used at the start of input. Default delimiters
+	// are "{{" and "}}".
+	OpenDelimiter  string
+	CloseDelimiter string
+}
+
 // Scan scans given input.
 //
 // Tokens can then be fetched sequentially thanks to NextToken() function on returned lexer.
 func Scan(input string) *Lexer {
-	return scanWithName(input, "")
+	return scan(input, "", Options{})
+}
+
+// ScanWithOptions scans given input with given options.
+//
+// Tokens can then be fetched sequentially thanks to NextToken() function on returned lexer.
+func ScanWithOptions(input string, options Options) *Lexer {
+	return scan(input, "", options)
 }
 
 // scanWithName scans given input, with a name used for testing
 //
 // Tokens can then be fetched sequentially thanks to NextToken() function on returned lexer.
 func scanWithName(input string, name string) *Lexer {
+	return scan(input, name, Options{})
+}
+
+// scan scans given input with given name and options
+func scan(input string, name string, options Options) *Lexer {
 	result := &Lexer{
 		input:  input,
 		name:   name,
-		tokens: make(chan Token),
 		line:   1,
+		delims: defaultDelimiters,
 	}
 
-	go result.run()
+	result.nextFunc = lexContent
+
+	if (options.OpenDelimiter != "") || (options.CloseDelimiter != "") {
+		open, close := options.OpenDelimiter, options.CloseDelimiter
+		if open == "" {
+			open = defaultOpenDelimiter
+		}
+
+		if close == "" {
+			close = defaultCloseDelimiter
+		}
+
+		if isValidDelimiter(open) && isValidDelimiter(close) {
+			result.delims = newDelimiters(open, close)
+		} else {
+			result.nextFunc = func(l *Lexer) lexFunc {
+				return l.errorf("Invalid delimiters: %q %q", open, close)
+			}
+		}
+	}
 
 	return result
 }
@@ -125,17 +175,32 @@ func Collect(input string) []Token {
 }
 
 // NextToken returns the next scanned token.
+//
+// Once the input is fully scanned, or if an error occurred, it always returns the final TokenEOF or TokenError token.
 func (l *Lexer) NextToken() Token {
-	result := <-l.tokens
+	// scan until a token is available
+	for (l.nbTokens == 0) && (l.nextFunc != nil) {
+		l.nextFunc = l.nextFunc(l)
+	}
 
-	return result
-}
+	if l.nbTokens == 0 {
+		return l.last
+	}
 
-// run starts lexical analysis
-func (l *Lexer) run() {
-	for l.nextFunc = lexContent; l.nextFunc != nil; {
-		l.nextFunc = l.nextFunc(l)
+	l.last = l.tokens[0]
+
+	l.nbTokens--
+	if l.nbTokens > 0 {
+		l.tokens[0] = l.tokens[1]
 	}
+
+	return l.last
+}
+
+// push adds given token to scanned tokens
+func (l *Lexer) push(token Token) {
+	l.tokens[l.nbTokens] = token
+	l.nbTokens++
 }
 
 // next returns next character from input, or eof of there is nothing left to scan
@@ -153,7 +218,7 @@ func (l *Lexer) next() rune {
 }
 
 func (l *Lexer) produce(kind TokenKind, val string) {
-	l.tokens <- Token{kind, val, l.start, l.line}
+	l.push(Token{kind, val, l.start, l.line})
 
 	// scanning a new token
 	l.start = l.pos
@@ -224,7 +289,7 @@ func (l *Lexer) acceptRun(valid string) {
 
 // errorf emits an error token
 func (l *Lexer) errorf(format string, args ...interface{}) lexFunc {
-	l.tokens <- Token{TokenError, fmt.Sprintf(format, args...), l.start, l.line}
+	l.push(Token{TokenError, fmt.Sprintf(format, args...), l.start, l.line})
 	return nil
 }
 
@@ -233,76 +298,138 @@ func (l *Lexer) isString(str string) bool {
 	return strings.HasPrefix(l.input[l.pos:], str)
 }
 
-// findRegexp returns the first string from current scanning position that matches given regular expression
-func (l *Lexer) findRegexp(r *regexp.Regexp) string {
-	return r.FindString(l.input[l.pos:])
+// isStringAt returns true if content at given offset from current scanning position starts with given string
+func (l *Lexer) isStringAt(offset int, str string) bool {
+	return strings.HasPrefix(l.input[l.pos+offset:], str)
 }
 
-// indexRegexp returns the index of the first string from current scanning position that matches given regular expression
-//
-// It returns -1 if not found
-func (l *Lexer) indexRegexp(r *regexp.Regexp) int {
-	loc := r.FindStringIndex(l.input[l.pos:])
-	if loc == nil {
+// isByteAt returns true if character at given offset from current scanning position is given byte
+func (l *Lexer) isByteAt(offset int, b byte) bool {
+	return (l.pos+offset < len(l.input)) && (l.input[l.pos+offset] == b)
+}
+
+// skipSpaces returns the offset of the first character from given offset that is not a space
+func (l *Lexer) skipSpaces(offset int) int {
+	for (l.pos+offset < len(l.input)) && isSpace(l.input[l.pos+offset]) {
+		offset++
+	}
+
+	return offset
+}
+
+// skipStrip returns the offset after the optional strip flag ~ at given offset
+func (l *Lexer) skipStrip(offset int) int {
+	if l.isByteAt(offset, '~') {
+		return offset + 1
+	}
+
+	return offset
+}
+
+// matchOpen returns the length of the open mustache followed by given string, with an optional strip flag in
+// between, or -1 if not found: {{~?str
+func (l *Lexer) matchOpen(str string) int {
+	if !l.isString(l.delims.open) {
 		return -1
 	}
-	return loc[0]
+
+	offset := len(l.delims.open)
+	if l.isByteAt(offset, '~') && l.isStringAt(offset+1, str) {
+		return offset + 1 + len(str)
+	}
+
+	if l.isStringAt(offset, str) {
+		return offset + len(str)
+	}
+
+	return -1
 }
 
-// lexContent scans content (ie: not between mustaches)
-func lexContent(l *Lexer) lexFunc {
-	var next lexFunc
+// matchClose returns the offset after the close mustache at given offset, with an optional strip flag, or -1 if
+// not found: ~?}}
+func (l *Lexer) matchClose(offset int) int {
+	if l.isByteAt(offset, '~') && l.isStringAt(offset+1, l.delims.close) {
+		return offset + 1 + len(l.delims.close)
+	}
 
-	if l.rawBlock {
-		if i := l.indexRegexp(rOpenEndRawLookAhead); i != -1 {
-			// {{{{/
-			l.rawBlock = false
-			l.pos += i
+	if l.isStringAt(offset, l.delims.close) {
+		return offset + len(l.delims.close)
+	}
 
-			next = lexOpenMustache
-		} else {
-			return l.errorf("Unclosed raw block")
-		}
-	} else if l.isString(escapedEscapedOpenMustache) {
-		// \\{{
+	return -1
+}
 
-		// emit content with only one escaped escape
-		l.next()
-		l.emitContent()
+// matchInverse returns the length of an inverse mustache, or -1 if not found: {{^}} or {{else}}
+func (l *Lexer) matchInverse() int {
+	if offset := l.matchOpen("^"); offset != -1 {
+		return l.matchClose(l.skipSpaces(offset))
+	}
 
-		// ignore second escaped escape
-		l.next()
-		l.ignore()
+	if offset := l.matchOpenElse(); offset != -1 {
+		return l.matchClose(l.skipSpaces(offset))
+	}
 
-		next = lexContent
-	} else if l.isString(escapedOpenMustache) {
-		// \{{
-		next = lexEscapedOpenMustache
-	} else if str := l.findRegexp(rOpenCommentDash); str != "" {
-		// {{!--
-		l.closeComment = rCloseCommentDash
+	return -1
+}
+
+// matchOpenElse returns the length of an inverse chain opening, or -1 if not found: {{else
+func (l *Lexer) matchOpenElse() int {
+	if !l.isString(l.delims.open) {
+		return -1
+	}
+
+	offset := l.skipSpaces(l.skipStrip(len(l.delims.open)))
+	if l.isStringAt(offset, "else") {
+		return offset + len("else")
+	}
+
+	return -1
+}
+
+// matchStar returns the offset after an optional star preceded by spaces at given offset: (\s*\*)?
+func (l *Lexer) matchStar(offset int) int {
+	if i := l.skipSpaces(offset); l.isByteAt(i, '*') {
+		return i + 1
+	}
 
-		next = lexComment
-	} else if str := l.findRegexp(rOpenComment); str != "" {
-		// {{!
-		l.closeComment = rCloseComment
+	return offset
+}
 
-		next = lexComment
-	} else if l.isString(openMustache) {
-		// {{
-		next = lexOpenMustache
+// isKeyword returns true if content at current scanning position starts with given keyword, followed by one of given
+// characters, a space or the first character of the close mustache
+func (l *Lexer) isKeyword(keyword string, followers string) bool {
+	if !l.isString(keyword) || (l.pos+len(keyword) >= len(l.input)) {
+		return false
 	}
 
-	if next != nil {
+	c := l.input[l.pos+len(keyword)]
+
+	return isSpace(c) || (strings.IndexByte(followers, c) >= 0) || l.isStringAt(len(keyword), l.delims.closeFirst)
+}
+
+// lexContent scans content (ie: not between mustaches)
+func lexContent(l *Lexer) lexFunc {
+	if l.rawBlock {
+		i := strings.Index(l.input[l.pos:], l.delims.openEndRaw)
+		if i == -1 {
+			return l.errorf("Unclosed raw block")
+		}
+
+		// {{{{/
+		l.rawBlock = false
+		l.pos += i
+
 		// emit scanned content
 		l.emitContent()
 
-		// scan next token
-		return next
+		return lexOpenMustache
 	}
 
-	// scan next rune
-	if l.next() == eof {
+	// all mustaches start with an open delimiter
+	i := strings.Index(l.input[l.pos:], l.delims.open)
+	if i == -1 {
+		l.pos = len(l.input)
+
 		// emit scanned content
 		l.emitContent()
 
@@ -311,8 +438,64 @@ func lexContent(l *Lexer) lexFunc {
 		return nil
 	}
 
-	// continue content scanning
-	return lexContent
+	// open delimiter may be preceded by escape characters
+	if open := l.pos + i; open-2 > l.pos {
+		l.pos = open - 2
+	}
+
+	for {
+		var next lexFunc
+		var skip int
+
+		if l.isString(l.delims.escapedEscapedOpen) {
+			// \\{{
+
+			// emit content with only one escaped escape
+			l.next()
+			l.emitContent()
+
+			// ignore second escaped escape
+			l.next()
+			l.ignore()
+
+			next = lexContent
+		} else if l.isString(l.delims.escapedOpen) {
+			// \{{
+			next = lexEscapedOpenMustache
+		} else if l.isString(l.delims.openSet) {
+			// {{=
+			next = lexSetDelimiters
+		} else if l.matchOpen("!--") != -1 {
+			// {{!--
+			l.commentDash = true
+			skip = len(l.delims.open)
+
+			next = lexComment
+		} else if l.matchOpen("!") != -1 {
+			// {{!
+			l.commentDash = false
+			skip = len(l.delims.open)
+
+			next = lexComment
+		} else if l.isString(l.delims.open) {
+			// {{
+			next = lexOpenMustache
+		}
+
+		if next != nil {
+			// emit scanned content
+			l.emitContent()
+
+			// skip comment opening, so that it can't be mistaken for its closing
+			l.pos += skip
+
+			// scan next token
+			return next
+		}
+
+		// scan next rune
+		l.next()
+	}
 }
 
 // lexEscapedOpenMustache scans \{{
@@ -322,6 +505,7 @@ func lexEscapedOpenMustache(l *Lexer) lexFunc {
 	l.ignore()
 
 	// scan mustaches
+	l.pos += len(l.delims.open)
 	for l.peek() == '{' {
 		l.next()
 	}
@@ -331,39 +515,56 @@ func lexEscapedOpenMustache(l *Lexer) lexFunc {
 
 // lexOpenMustache scans {{
 func lexOpenMustache(l *Lexer) lexFunc {
-	var str string
+	var length int
 	var tok TokenKind
 
 	nextFunc := lexExpression
 
-	if str = l.findRegexp(rOpenEndRaw); str != "" {
-		tok = TokenOpenEndRawBlock
-	} else if str = l.findRegexp(rOpenRaw); str != "" {
-		tok = TokenOpenRawBlock
+	if l.isString(l.delims.openEndRaw) {
+		// {{{{/
+		length, tok = len(l.delims.openEndRaw), TokenOpenEndRawBlock
+	} else if l.isStringAt(len(l.delims.open), "{{") {
+		// {{{{
+		length, tok = len(l.delims.open)+len("{{"), TokenOpenRawBlock
 		l.rawBlock = true
-	} else if str = l.findRegexp(rOpenUnescaped); str != "" {
+	} else if length = l.matchOpen("{"); length != -1 {
 		tok = TokenOpenUnescaped
-	} else if str = l.findRegexp(rOpenBlock); str != "" {
+	} else if length = l.matchOpen("#>"); length != -1 {
+		tok = TokenOpenPartialBlock
+	} else if length = l.matchOpen("#*"); length != -1 {
+		tok = TokenOpenDecoratorBlock
+	} else if length = l.matchOpen("#"); length != -1 {
 		tok = TokenOpenBlock
-	} else if str = l.findRegexp(rOpenEndBlock); str != "" {
+	} else if length = l.matchOpen("/"); length != -1 {
 		tok = TokenOpenEndBlock
-	} else if str = l.findRegexp(rOpenPartial); str != "" {
-		tok = TokenOpenPartial
-	} else if str = l.findRegexp(rInverse); str != "" {
+	} else if length = l.matchOpen(">"); length != -1 {
+		// {{> or {{>*
+		length, tok = l.matchStar(length), TokenOpenPartial
+	} else if length = l.matchOpen("<"); length != -1 {
+		// {{< or {{<*
+		length, tok = l.matchStar(length), TokenOpenParent
+	} else if length = l.matchOpen("$"); length != -1 {
+		tok = TokenOpenNamedBlock
+	} else if length = l.matchOpen("*"); length != -1 {
+		tok = TokenOpenDecorator
+	} else if length = l.matchInverse(); length != -1 {
+		// {{^}} or {{else}}
 		tok = TokenInverse
 		nextFunc = lexContent
-	} else if str = l.findRegexp(rOpenInverse); str != "" {
+	} else if length = l.matchOpen("^"); length != -1 {
 		tok = TokenOpenInverse
-	} else if str = l.findRegexp(rOpenInverseChain); str != "" {
+	} else if length = l.matchOpenElse(); length != -1 {
 		tok = TokenOpenInverseChain
-	} else if str = l.findRegexp(rOpen); str != "" {
+	} else if length = l.matchOpen("&"); length != -1 {
+		tok = TokenOpen
+	} else if length = l.matchOpen(""); length != -1 {
 		tok = TokenOpen
 	} else {
 		// this is rotten
 		panic("Current pos MUST be an opening mustache")
 	}
 
-	l.pos += len(str)
+	l.pos += length
 	l.emit(tok)
 
 	return nextFunc
@@ -371,16 +572,16 @@ func lexOpenMustache(l *Lexer) lexFunc {
 
 // lexCloseMustache scans }} or ~}}
 func lexCloseMustache(l *Lexer) lexFunc {
-	var str string
+	var length int
 	var tok TokenKind
 
-	if str = l.findRegexp(rCloseRaw); str != "" {
+	if l.isString(l.delims.closeRaw) {
 		// }}}}
-		tok = TokenCloseRawBlock
-	} else if str = l.findRegexp(rCloseUnescaped); str != "" {
+		length, tok = len(l.delims.closeRaw), TokenCloseRawBlock
+	} else if l.isString("}") && (l.matchClose(1) != -1) {
 		// }}}
-		tok = TokenCloseUnescaped
-	} else if str = l.findRegexp(rClose); str != "" {
+		length, tok = l.matchClose(1), TokenCloseUnescaped
+	} else if length = l.matchClose(0); length != -1 {
 		// }}
 		tok = TokenClose
 	} else {
@@ -388,7 +589,7 @@ func lexCloseMustache(l *Lexer) lexFunc {
 		panic("Current pos MUST be a closing mustache")
 	}
 
-	l.pos += len(str)
+	l.pos += length
 	l.emit(tok)
 
 	return lexContent
@@ -397,17 +598,21 @@ func lexCloseMustache(l *Lexer) lexFunc {
 // lexExpression scans inside mustaches
 func lexExpression(l *Lexer) lexFunc {
 	// search close mustache delimiter
-	if l.isString(closeMustache) || l.isString(closeStripMustache) || l.isString(closeUnescapedStripMustache) {
+	if l.isString(l.delims.close) || l.isString(l.delims.closeStrip) ||
+		l.isString(l.delims.closeUnescaped) || l.isString(l.delims.closeUnescapedStrip) ||
+		l.isString(l.delims.closeRaw) {
 		return lexCloseMustache
 	}
 
 	// search some patterns before advancing scanning position
 
 	// "as |"
-	if str := l.findRegexp(rOpenBlockParams); str != "" {
-		l.pos += len(str)
-		l.emit(TokenOpenBlockParams)
-		return lexExpression
+	if l.isString("as") {
+		if i := l.skipSpaces(len("as")); (i > len("as")) && l.isByteAt(i, '|') {
+			l.pos += i + 1
+			l.emit(TokenOpenBlockParams)
+			return lexExpression
+		}
 	}
 
 	// ..
@@ -418,21 +623,21 @@ func lexExpression(l *Lexer) lexFunc {
 	}
 
 	// .
-	if str := l.findRegexp(rDotID); str != "" {
+	if l.isKeyword(".", "=~}/)|") {
 		l.pos += len(".")
 		l.emit(TokenID)
 		return lexExpression
 	}
 
 	// true
-	if str := l.findRegexp(rTrue); str != "" {
+	if l.isKeyword("true", "~})") {
 		l.pos += len("true")
 		l.emit(TokenBoolean)
 		return lexExpression
 	}
 
 	// false
-	if str := l.findRegexp(rFalse); str != "" {
+	if l.isKeyword("false", "~})") {
 		l.pos += len("false")
 		l.emit(TokenBoolean)
 		return lexExpression
@@ -476,18 +681,55 @@ func lexExpression(l *Lexer) lexFunc {
 
 // lexComment scans {{!-- or {{!
 func lexComment(l *Lexer) lexFunc {
-	if str := l.findRegexp(l.closeComment); str != "" {
-		l.pos += len(str)
-		l.emit(TokenComment)
+	for {
+		if length := l.matchCloseComment(); length != -1 {
+			l.pos += length
+			l.emit(TokenComment)
+
+			return lexContent
+		}
+
+		if r := l.next(); r == eof {
+			return l.errorf("Unclosed comment")
+		}
+	}
+}
+
+// matchCloseComment returns the length of current comment closing, or -1 if not found: --}} or }}
+func (l *Lexer) matchCloseComment() int {
+	offset := l.skipSpaces(0)
+
+	if l.commentDash {
+		if !l.isStringAt(offset, "--") {
+			return -1
+		}
+
+		offset += len("--")
+	}
+
+	return l.matchClose(offset)
+}
 
-		return lexContent
+// lexSetDelimiters scans {{=<% %>=}} and switches to new delimiters
+func lexSetDelimiters(l *Lexer) lexFunc {
+	i := strings.Index(l.input[l.pos+len(l.delims.openSet):], l.delims.closeSet)
+	if i == -1 {
+		return l.errorf("Unclosed set delimiters tag")
 	}
 
-	if r := l.next(); r == eof {
-		return l.errorf("Unclosed comment")
+	inner := l.input[l.pos+len(l.delims.openSet) : l.pos+len(l.delims.openSet)+i]
+
+	fields := strings.Fields(inner)
+	if (len(fields) != 2) || !isValidDelimiter(fields[0]) || !isValidDelimiter(fields[1]) {
+		return l.errorf("Invalid set delimiters tag: %q", inner)
 	}
 
-	return lexComment
+	l.pos += len(l.delims.openSet) + i + len(l.delims.closeSet)
+	l.emit(TokenSetDelimiters)
+
+	l.delims = newDelimiters(fields[0], fields[1])
+
+	return lexContent
 }
 
 // lexIgnorable scans all following ignorable characters
@@ -596,7 +838,16 @@ func (l *Lexer) scanNumber() bool {
 
 // lexIdentifier scans an ID
 func lexIdentifier(l *Lexer) lexFunc {
-	str := l.findRegexp(rID)
+	str := l.input[l.pos:]
+	if i := strings.IndexFunc(str, isUnallowedIDChar); i != -1 {
+		str = str[:i]
+	}
+
+	// custom close delimiter may be made of characters allowed in an identifier
+	if i := strings.Index(str, l.delims.close); i > 0 {
+		str = str[:i]
+	}
+
 	if len(str) == 0 {
 		// this is rotten
 		panic("Identifier expected")
@@ -626,11 +877,27 @@ func lexPathLiteral(l *Lexer) lexFunc {
 	return lexExpression
 }
 
+// isUnallowedIDChar returns true if given character is not allowed in an identifier
+func isUnallowedIDChar(r rune) bool {
+	return (r < utf8.RuneSelf) && (strings.IndexByte(unallowedIDChars, byte(r)) >= 0)
+}
+
+// isSpace returns true if given character is a space, as matched by \s in regular expressions
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
 // isIgnorable returns true if given character is ignorable (ie. whitespace of line feed)
 func isIgnorable(r rune) bool {
 	return r == ' ' || r == '\t' || r == '\n'
 }
 
+// isValidDelimiter returns true if given string can be used as a mustache delimiter (ie. it is not empty, and
+// contains neither whitespaces nor equal sign)
+func isValidDelimiter(delim string) bool {
+	return (delim != "") && !strings.ContainsAny(delim, " \t\r\n=")
+}
+
 // isAlphaNumeric reports whether r is an alphabetic, digit, or underscore.
 //
 // NOTE borrowed from https://github.com/golang/go/tree/master/src/text/template/parse/lex.go
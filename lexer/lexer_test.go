@@ -22,6 +22,8 @@ func tokBool(val string) Token    { return Token{TokenBoolean, val, 0, 1} }
 func tokError(val string) Token   { return Token{TokenError, val, 0, 1} }
 func tokComment(val string) Token { return Token{TokenComment, val, 0, 1} }
 
+func tokSetDelimiters(val string) Token { return Token{TokenSetDelimiters, val, 0, 1} }
+
 var tokEOF = Token{TokenEOF, "", 0, 1}
 var tokEquals = Token{TokenEquals, "=", 0, 1}
 var tokData = Token{TokenData, "@", 0, 1}
@@ -37,6 +39,11 @@ var tokOpenUnescapedStrip = Token{TokenOpenUnescaped, "{{~{", 0, 1}
 var tokCloseUnescapedStrip = Token{TokenCloseUnescaped, "}~}}", 0, 1}
 var tokOpenBlock = Token{TokenOpenBlock, "{{#", 0, 1}
 var tokOpenEndBlock = Token{TokenOpenEndBlock, "{{/", 0, 1}
+var tokOpenDecoratorBlock = Token{TokenOpenDecoratorBlock, "{{#*", 0, 1}
+var tokOpenPartialBlock = Token{TokenOpenPartialBlock, "{{#>", 0, 1}
+var tokOpenParent = Token{TokenOpenParent, "{{<", 0, 1}
+var tokOpenNamedBlock = Token{TokenOpenNamedBlock, "{{$", 0, 1}
+var tokOpenDecorator = Token{TokenOpenDecorator, "{{*", 0, 1}
 var tokOpenInverse = Token{TokenOpenInverse, "{{^", 0, 1}
 var tokOpenInverseChain = Token{TokenOpenInverseChain, "{{else", 0, 1}
 var tokOpenSexpr = Token{TokenOpenSexpr, "(", 0, 1}
@@ -257,11 +264,83 @@ var lexTests = []lexTest{
 		"foo {{!-- this is a\n{{comment}}\n--}} bar {{ baz }}",
 		[]Token{tokContent("foo "), tokComment("{{!-- this is a\n{{comment}}\n--}}"), tokContent(" bar "), tokOpen, tokID("baz"), tokClose, tokEOF},
 	},
+	{
+		`tokenizes set delimiters as "SET_DELIMITERS" and switches delimiters`,
+		`{{=<% %>=}}<% foo %>{{bar}}`,
+		[]Token{tokSetDelimiters("{{=<% %>=}}"), Token{TokenOpen, "<%", 0, 1}, tokID("foo"), Token{TokenClose, "%>", 0, 1}, tokContent("{{bar}}"), tokEOF},
+	},
+	{
+		`tokenizes mustaches with set delimiters`,
+		`{{= | | =}}|#foo||{bar}||! baz ||/foo|`,
+		[]Token{
+			tokSetDelimiters("{{= | | =}}"),
+			Token{TokenOpenBlock, "|#", 0, 1}, tokID("foo"), Token{TokenClose, "|", 0, 1},
+			Token{TokenOpenUnescaped, "|{", 0, 1}, tokID("bar"), Token{TokenCloseUnescaped, "}|", 0, 1},
+			tokComment("|! baz |"),
+			Token{TokenOpenEndBlock, "|/", 0, 1}, tokID("foo"), Token{TokenClose, "|", 0, 1},
+			tokEOF,
+		},
+	},
+	{
+		`tokenizes successive set delimiters`,
+		`{{=<% %>=}}<%={{ }}=%>{{foo}}`,
+		[]Token{tokSetDelimiters("{{=<% %>=}}"), tokSetDelimiters("<%={{ }}=%>"), tokOpen, tokID("foo"), tokClose, tokEOF},
+	},
+	{
+		`tokenizes an invalid set delimiters tag as error`,
+		`{{=<%=}}`,
+		[]Token{tokError(`Invalid set delimiters tag: "<%"`)},
+	},
+	{
+		`tokenizes an unclosed set delimiters tag as error`,
+		`{{=<% %>}}`,
+		[]Token{tokError("Unclosed set delimiters tag")},
+	},
 	{
 		`tokenizes open and closing blocks as OPEN_BLOCK, ID, CLOSE ..., OPEN_ENDBLOCK ID CLOSE`,
 		`{{#foo}}content{{/foo}}`,
 		[]Token{tokOpenBlock, tokID("foo"), tokClose, tokContent("content"), tokOpenEndBlock, tokID("foo"), tokClose, tokEOF},
 	},
+	{
+		`tokenizes decorator blocks as OPEN_DECORATOR_BLOCK, ID, STRING, CLOSE ..., OPEN_ENDBLOCK ID CLOSE`,
+		`{{#*inline "foo"}}content{{/inline}}`,
+		[]Token{tokOpenDecoratorBlock, tokID("inline"), tokString("foo"), tokClose, tokContent("content"), tokOpenEndBlock, tokID("inline"), tokClose, tokEOF},
+	},
+	{
+		`tokenizes partial blocks as OPEN_PARTIAL_BLOCK, ID, CLOSE ..., OPEN_ENDBLOCK ID CLOSE`,
+		`{{#> foo}}content{{/foo}}`,
+		[]Token{tokOpenPartialBlock, tokID("foo"), tokClose, tokContent("content"), tokOpenEndBlock, tokID("foo"), tokClose, tokEOF},
+	},
+	{
+		`tokenizes partial block data as OPEN_PARTIAL, DATA, ID, CLOSE`,
+		`{{> @partial-block}}`,
+		[]Token{tokOpenPartial, tokData, tokID("partial-block"), tokClose, tokEOF},
+	},
+	{
+		`tokenizes dynamic partials as OPEN_PARTIAL, ID, CLOSE`,
+		`{{>*foo}}`,
+		[]Token{{TokenOpenPartial, "{{>*", 0, 1}, tokID("foo"), tokClose, tokEOF},
+	},
+	{
+		`tokenizes parents as OPEN_PARENT, ID, CLOSE ..., OPEN_ENDBLOCK ID CLOSE`,
+		`{{<foo}}content{{/foo}}`,
+		[]Token{tokOpenParent, tokID("foo"), tokClose, tokContent("content"), tokOpenEndBlock, tokID("foo"), tokClose, tokEOF},
+	},
+	{
+		`tokenizes dynamic parents as OPEN_PARENT, ID, CLOSE ..., OPEN_ENDBLOCK ID CLOSE`,
+		`{{<*foo}}{{/foo}}`,
+		[]Token{{TokenOpenParent, "{{<*", 0, 1}, tokID("foo"), tokClose, tokOpenEndBlock, tokID("foo"), tokClose, tokEOF},
+	},
+	{
+		`tokenizes named blocks as OPEN_NAMED_BLOCK, ID, CLOSE ..., OPEN_ENDBLOCK ID CLOSE`,
+		`{{$foo}}content{{/foo}}`,
+		[]Token{tokOpenNamedBlock, tokID("foo"), tokClose, tokContent("content"), tokOpenEndBlock, tokID("foo"), tokClose, tokEOF},
+	},
+	{
+		`tokenizes decorators as OPEN_DECORATOR, ID, ID, CLOSE`,
+		`{{* foo bar}}`,
+		[]Token{tokOpenDecorator, tokID("foo"), tokID("bar"), tokClose, tokEOF},
+	},
 	{
 		`tokenizes inverse sections as "INVERSE"`,
 		`{{^}}`,
@@ -514,6 +593,99 @@ func TestLexer(t *testing.T) {
 	}
 }
 
+var lexDelimitersTests = []lexTest{
+	{
+		`tokenizes mustaches with custom delimiters`,
+		`foo [[bar]] {{baz}}`,
+		[]Token{tokContent("foo "), {TokenOpen, "[[", 0, 1}, tokID("bar"), {TokenClose, "]]", 0, 1}, tokContent(" {{baz}}"), tokEOF},
+	},
+	{
+		`tokenizes triple-stash with custom delimiters`,
+		`[[{foo}]][[&bar]]`,
+		[]Token{{TokenOpenUnescaped, "[[{", 0, 1}, tokID("foo"), {TokenCloseUnescaped, "}]]", 0, 1}, {TokenOpen, "[[&", 0, 1}, tokID("bar"), {TokenClose, "]]", 0, 1}, tokEOF},
+	},
+	{
+		`tokenizes blocks with custom delimiters`,
+		`[[#foo]]bar[[else]]baz[[/foo]]`,
+		[]Token{{TokenOpenBlock, "[[#", 0, 1}, tokID("foo"), {TokenClose, "]]", 0, 1}, tokContent("bar"), tokInverse("[[else]]"), tokContent("baz"), {TokenOpenEndBlock, "[[/", 0, 1}, tokID("foo"), {TokenClose, "]]", 0, 1}, tokEOF},
+	},
+	{
+		`tokenizes comments with custom delimiters`,
+		`[[! foo ]][[!-- bar ]] --]]`,
+		[]Token{tokComment("[[! foo ]]"), tokComment("[[!-- bar ]] --]]"), tokEOF},
+	},
+	{
+		`tokenizes strip markers with custom delimiters`,
+		`[[~foo~]][[~{bar}~]]`,
+		[]Token{{TokenOpen, "[[~", 0, 1}, tokID("foo"), {TokenClose, "~]]", 0, 1}, {TokenOpenUnescaped, "[[~{", 0, 1}, tokID("bar"), {TokenCloseUnescaped, "}~]]", 0, 1}, tokEOF},
+	},
+	{
+		`tokenizes raw blocks with custom delimiters`,
+		`[[{{raw}}]] [[foo]] {{bar}} [[{{/raw}}]]`,
+		[]Token{{TokenOpenRawBlock, "[[{{", 0, 1}, tokID("raw"), {TokenCloseRawBlock, "}}]]", 0, 1}, tokContent(" [[foo]] {{bar}} "), {TokenOpenEndRawBlock, "[[{{/", 0, 1}, tokID("raw"), {TokenCloseRawBlock, "}}]]", 0, 1}, tokEOF},
+	},
+	{
+		`tokenizes escaped custom delimiters`,
+		`\[[foo]] \\[[bar]]`,
+		[]Token{tokContent("[[foo]] \\"), {TokenOpen, "[[", 0, 1}, tokID("bar"), {TokenClose, "]]", 0, 1}, tokEOF},
+	},
+	{
+		`tokenizes set delimiters with custom delimiters`,
+		`[[=<% %>=]]<%foo%>`,
+		[]Token{tokSetDelimiters("[[=<% %>=]]"), {TokenOpen, "<%", 0, 1}, tokID("foo"), {TokenClose, "%>", 0, 1}, tokEOF},
+	},
+}
+
+func TestLexerWithDelimiters(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range lexDelimitersTests {
+		var tokens []Token
+
+		l := ScanWithOptions(test.input, Options{OpenDelimiter: "[[", CloseDelimiter: "]]"})
+		for {
+			token := l.NextToken()
+			tokens = append(tokens, token)
+
+			if token.Kind == TokenEOF || token.Kind == TokenError {
+				break
+			}
+		}
+
+		if !equal(tokens, test.tokens, false) {
+			t.Errorf("Test '%s' failed\ninput:\n\t'%s'\nexpected\n\t%v\ngot\n\t%+v\n", test.name, test.input, test.tokens, tokens)
+		}
+	}
+}
+
+func TestLexerInvalidDelimiters(t *testing.T) {
+	t.Parallel()
+
+	for _, delims := range [][2]string{{"<%", "% >"}, {"=", "%>"}, {"[[", "\n"}} {
+		token := ScanWithOptions("foo", Options{OpenDelimiter: delims[0], CloseDelimiter: delims[1]}).NextToken()
+		if token.Kind != TokenError {
+			t.Errorf("Expected error with delimiters %q, got: %s", delims, token)
+		}
+	}
+}
+
+func TestLexerFinalToken(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{"foo {{bar}}", "foo {{bar"} {
+		l := Scan(input)
+
+		var last Token
+		for i := 0; i < 10; i++ {
+			last = l.NextToken()
+		}
+
+		if (last.Kind != TokenEOF) && (last.Kind != TokenError) {
+			t.Errorf("Expected final token to be returned again for input %q, got: %s", input, last)
+		}
+	}
+}
+
 // @todo Test errors:
 //   `{{{{raw foo`
 
@@ -52,9 +52,27 @@ const (
 	// TokenOpenPartial is the OPEN_PARTIAL token
 	TokenOpenPartial
 
+	// TokenOpenPartialBlock is the OPEN_PARTIAL_BLOCK token
+	TokenOpenPartialBlock
+
+	// TokenOpenParent is the OPEN_PARENT token for a mustache parent, ie. {{<
+	TokenOpenParent
+
+	// TokenOpenNamedBlock is the OPEN_NAMED_BLOCK token for a mustache block, ie. {{$
+	TokenOpenNamedBlock
+
+	// TokenOpenDecorator is the OPEN token for a decorator, ie. {{*
+	TokenOpenDecorator
+
+	// TokenOpenDecoratorBlock is the OPEN_BLOCK token for a decorator block, ie. {{#*
+	TokenOpenDecoratorBlock
+
 	// TokenComment is the COMMENT token
 	TokenComment
 
+	// TokenSetDelimiters is the SET_DELIMITERS token, ie. {{=<% %>=}}
+	TokenSetDelimiters
+
 	//
 	// Inside mustaches
 	//
@@ -122,34 +140,40 @@ type Token struct {
 
 // tokenName permits to display token name given token type
 var tokenName = map[TokenKind]string{
-	TokenError:            "Error",
-	TokenEOF:              "EOF",
-	TokenContent:          "Content",
-	TokenComment:          "Comment",
-	TokenOpen:             "Open",
-	TokenClose:            "Close",
-	TokenOpenUnescaped:    "OpenUnescaped",
-	TokenCloseUnescaped:   "CloseUnescaped",
-	TokenOpenBlock:        "OpenBlock",
-	TokenOpenEndBlock:     "OpenEndBlock",
-	TokenOpenRawBlock:     "OpenRawBlock",
-	TokenCloseRawBlock:    "CloseRawBlock",
-	TokenOpenEndRawBlock:  "OpenEndRawBlock",
-	TokenOpenBlockParams:  "OpenBlockParams",
-	TokenCloseBlockParams: "CloseBlockParams",
-	TokenInverse:          "Inverse",
-	TokenOpenInverse:      "OpenInverse",
-	TokenOpenInverseChain: "OpenInverseChain",
-	TokenOpenPartial:      "OpenPartial",
-	TokenOpenSexpr:        "OpenSexpr",
-	TokenCloseSexpr:       "CloseSexpr",
-	TokenID:               "ID",
-	TokenEquals:           "Equals",
-	TokenString:           "String",
-	TokenNumber:           "Number",
-	TokenBoolean:          "Boolean",
-	TokenData:             "Data",
-	TokenSep:              "Sep",
+	TokenError:              "Error",
+	TokenEOF:                "EOF",
+	TokenContent:            "Content",
+	TokenComment:            "Comment",
+	TokenSetDelimiters:      "SetDelimiters",
+	TokenOpen:               "Open",
+	TokenClose:              "Close",
+	TokenOpenUnescaped:      "OpenUnescaped",
+	TokenCloseUnescaped:     "CloseUnescaped",
+	TokenOpenBlock:          "OpenBlock",
+	TokenOpenEndBlock:       "OpenEndBlock",
+	TokenOpenRawBlock:       "OpenRawBlock",
+	TokenCloseRawBlock:      "CloseRawBlock",
+	TokenOpenEndRawBlock:    "OpenEndRawBlock",
+	TokenOpenBlockParams:    "OpenBlockParams",
+	TokenCloseBlockParams:   "CloseBlockParams",
+	TokenInverse:            "Inverse",
+	TokenOpenInverse:        "OpenInverse",
+	TokenOpenInverseChain:   "OpenInverseChain",
+	TokenOpenPartial:        "OpenPartial",
+	TokenOpenPartialBlock:   "OpenPartialBlock",
+	TokenOpenParent:         "OpenParent",
+	TokenOpenNamedBlock:     "OpenNamedBlock",
+	TokenOpenDecorator:      "OpenDecorator",
+	TokenOpenDecoratorBlock: "OpenDecoratorBlock",
+	TokenOpenSexpr:          "OpenSexpr",
+	TokenCloseSexpr:         "CloseSexpr",
+	TokenID:                 "ID",
+	TokenEquals:             "Equals",
+	TokenString:             "String",
+	TokenNumber:             "Number",
+	TokenBoolean:            "Boolean",
+	TokenData:               "Data",
+	TokenSep:                "Sep",
 }
 
 // String returns the token kind string representation for debugging.
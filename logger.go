@@ -0,0 +1,160 @@
+package raymond
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LogLevel represents the level of a message logged by the `log` helper.
+type LogLevel int
+
+// log levels, in increasing order of severity
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// logLevelNames are the names of log levels, as used in templates
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+// String returns the name of log level.
+func (level LogLevel) String() string {
+	if (level >= LogDebug) && (level <= LogError) {
+		return logLevelNames[level]
+	}
+
+	return strconv.Itoa(int(level))
+}
+
+// lookupLogLevel returns the log level corresponding to given level name or number
+func lookupLogLevel(value interface{}) (LogLevel, bool) {
+	var result int
+
+	val := reflect.ValueOf(value)
+
+	switch val.Kind() {
+	case reflect.String:
+		name := strings.ToLower(val.String())
+		for i, levelName := range logLevelNames {
+			if name == levelName {
+				return LogLevel(i), true
+			}
+		}
+
+		var err error
+		if result, err = strconv.Atoi(name); err != nil {
+			return LogInfo, false
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result = int(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		result = int(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		result = int(val.Float())
+	default:
+		return LogInfo, false
+	}
+
+	// out of range levels are clamped
+	switch {
+	case result < int(LogDebug):
+		return LogDebug, true
+	case result > int(LogError):
+		return LogError, true
+	}
+
+	return LogLevel(result), true
+}
+
+// Logger is the interface used by the `log` helper to output messages.
+//
+// The execution context is the one provided to Template.ExecContext(), or context.Background() if template was not
+// executed with that function.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, args ...interface{})
+}
+
+// LoggerFunc is an adapter to use an ordinary function as a Logger.
+type LoggerFunc func(ctx context.Context, level LogLevel, args ...interface{})
+
+// Log calls f(ctx, level, args...).
+func (f LoggerFunc) Log(ctx context.Context, level LogLevel, args ...interface{}) {
+	f(ctx, level, args...)
+}
+
+// stdLogger outputs messages with a standard logger
+type stdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+// NewStdLogger returns a Logger that prints messages with given level or above with given standard logger. If logger
+// is nil, the standard logger of the log package is used.
+//
+// Arguments are printed with their string representation, separated by spaces.
+func NewStdLogger(logger *log.Logger, level LogLevel) Logger {
+	return &stdLogger{
+		logger: logger,
+		level:  level,
+	}
+}
+
+// Log implements the Logger interface
+func (l *stdLogger) Log(ctx context.Context, level LogLevel, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	if l.logger == nil {
+		log.Print(logMessage(args))
+	} else {
+		l.logger.Print(logMessage(args))
+	}
+}
+
+// logMessage returns the string representations of given arguments, separated by spaces
+func logMessage(args []interface{}) string {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = Str(arg)
+	}
+
+	return strings.Join(strs, " ")
+}
+
+// defaultLogger is the logger used when no logger is set, it prints info messages and above with the standard logger
+var defaultLogger = NewStdLogger(nil, LogInfo)
+
+// logger is the global logger
+var logger = defaultLogger
+
+// protects global logger
+var loggerMutex sync.RWMutex
+
+// SetLogger sets the global logger used by the `log` helper, for templates that do not have their own logger. If
+// logger is nil, the default logger is restored: it prints messages with info level or above with the standard
+// logger of the log package.
+func SetLogger(l Logger) {
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
+
+	if l == nil {
+		l = defaultLogger
+	}
+
+	logger = l
+}
+
+// getLogger returns the global logger
+func getLogger() Logger {
+	loggerMutex.RLock()
+	defer loggerMutex.RUnlock()
+
+	return logger
+}
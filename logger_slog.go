@@ -0,0 +1,44 @@
+package raymond
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogLogger outputs messages with a structured logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger that outputs messages with given structured logger. If logger is nil, slog.Default() is used.
+//
+// The message is made of the string representations of arguments, separated by spaces.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return &slogLogger{
+		logger: logger,
+	}
+}
+
+// Log implements the Logger interface
+func (l *slogLogger) Log(ctx context.Context, level LogLevel, args ...interface{}) {
+	logger := l.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	logger.Log(ctx, slogLevel(level), logMessage(args))
+}
+
+// slogLevel converts given log level to a slog level
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogDebug:
+		return slog.LevelDebug
+	case LogWarn:
+		return slog.LevelWarn
+	case LogError:
+		return slog.LevelError
+	}
+
+	return slog.LevelInfo
+}
@@ -0,0 +1,35 @@
+package raymond
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	tpl := MustParse(`{{log "foo" bar}}{{log "baz" level="debug"}}{{log "qux" level="error"}}`)
+	tpl.SetLogger(NewSlogLogger(slog.New(handler)))
+
+	if _, err := tpl.ExecContext(context.Background(), map[string]int{"bar": 1}, nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := "level=INFO msg=\"foo 1\"\nlevel=DEBUG msg=baz\nlevel=ERROR msg=qux\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,93 @@
+package raymond
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+)
+
+type logEntry struct {
+	level LogLevel
+	args  []interface{}
+}
+
+// captureLogger returns a logger that records log entries
+func captureLogger(entries *[]logEntry) Logger {
+	return LoggerFunc(func(ctx context.Context, level LogLevel, args ...interface{}) {
+		*entries = append(*entries, logEntry{level, args})
+	})
+}
+
+func TestLookupLogLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value interface{}
+		level LogLevel
+		ok    bool
+	}{
+		{"debug", LogDebug, true},
+		{"WARN", LogWarn, true},
+		{"03", LogError, true},
+		{2, LogWarn, true},
+		{12, LogError, true},
+		{-1, LogDebug, true},
+		{1.0, LogInfo, true},
+		{"unknown", LogInfo, false},
+		{true, LogInfo, false},
+	}
+
+	for _, test := range tests {
+		level, ok := lookupLogLevel(test.value)
+		if level != test.level || ok != test.ok {
+			t.Errorf("Expected level %s (%t) for %v but got %s (%t)", test.level, test.ok, test.value, level, ok)
+		}
+	}
+}
+
+func TestTemplateLogger(t *testing.T) {
+	t.Parallel()
+
+	var entries []logEntry
+
+	tpl := MustParse(`{{log "foo"}}`)
+	tpl.SetLogger(captureLogger(&entries))
+
+	tpl.MustExec(nil)
+
+	// template logger is cloned
+	tpl.Clone().MustExec(nil)
+
+	if len(entries) != 2 || entries[0].level != LogInfo || entries[1].args[0] != "foo" {
+		t.Errorf("Unexpected log entries: %v", entries)
+	}
+}
+
+func TestGlobalLogger(t *testing.T) {
+	var entries []logEntry
+
+	SetLogger(captureLogger(&entries))
+	defer SetLogger(nil)
+
+	MustRender(`{{log "foo" level="warn"}}`, nil)
+
+	if len(entries) != 1 || entries[0].level != LogWarn || entries[0].args[0] != "foo" {
+		t.Errorf("Unexpected log entries: %v", entries)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+
+	tpl := MustParse(`{{log "foo" 1 true}}{{log "bar" level="debug"}}{{log "baz" level="error"}}`)
+	tpl.SetLogger(NewStdLogger(log.New(buf, "", 0), LogInfo))
+
+	tpl.MustExec(nil)
+
+	if expected := "foo 1 true\nbaz\n"; buf.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,126 @@
+package raymond
+
+import (
+	"reflect"
+	"sync"
+)
+
+// member is the resolution of a template name in a type
+type member struct {
+	// index of the method of the type, or -1
+	method int
+
+	// index of the method of the pointer to the type, used with addressable values, or -1
+	ptrMethod int
+
+	// index of the exported struct field, or nil
+	field []int
+
+	// index of the struct field with that name as handlebars tag, or -1
+	tag int
+
+	// the tagged field is not exported, or has an interface type
+	tagCopy bool
+}
+
+// typeMembers holds the resolved members of a type, by template name
+type typeMembers struct {
+	typ reflect.Type
+
+	mutex   sync.RWMutex
+	members map[string]*member
+}
+
+// membersCache holds the resolved members of all types, by reflect.Type
+var membersCache sync.Map
+
+// mapType is the type of the most common map context, that is accessed without reflection
+var mapType = reflect.TypeOf(map[string]interface{}(nil))
+
+// typeMembersOf returns the resolved members of given type
+func typeMembersOf(typ reflect.Type) *typeMembers {
+	if result, ok := membersCache.Load(typ); ok {
+		return result.(*typeMembers)
+	}
+
+	result, _ := membersCache.LoadOrStore(typ, &typeMembers{
+		typ:     typ,
+		members: make(map[string]*member),
+	})
+
+	return result.(*typeMembers)
+}
+
+// get returns the resolution of given path part
+func (t *typeMembers) get(part pathPart) *member {
+	t.mutex.RLock()
+	result := t.members[part.name]
+	t.mutex.RUnlock()
+
+	if result != nil {
+		return result
+	}
+
+	result = t.resolve(part)
+
+	t.mutex.Lock()
+	t.members[part.name] = result
+	t.mutex.Unlock()
+
+	return result
+}
+
+// resolve computes the resolution of given path part
+func (t *typeMembers) resolve(part pathPart) *member {
+	result := &member{
+		method:    methodIndex(t.typ, part),
+		ptrMethod: -1,
+		tag:       -1,
+	}
+
+	if t.typ.Kind() != reflect.Interface {
+		result.ptrMethod = methodIndex(reflect.PtrTo(t.typ), part)
+	}
+
+	if t.typ.Kind() != reflect.Struct {
+		return result
+	}
+
+	// example: firstName => FirstName
+	if field, ok := t.typ.FieldByName(part.title()); ok && (field.PkgPath == "") {
+		result.field = field.Index
+		return result
+	}
+
+	// attempts to find template variable name as a struct tag
+	for i := 0; i < t.typ.NumField(); i++ {
+		field := t.typ.Field(i)
+
+		if field.Tag.Get("handlebars") == part.name {
+			result.tag = i
+			result.tagCopy = (field.PkgPath != "") || (field.Type.Kind() == reflect.Interface)
+			break
+		}
+	}
+
+	return result
+}
+
+// methodIndex returns the index of the method of given type for given path part, or -1
+func methodIndex(typ reflect.Type, part pathPart) int {
+	if typ.NumMethod() == 0 {
+		return -1
+	}
+
+	method, ok := typ.MethodByName(part.name)
+	if !ok {
+		// example: subject() => Subject()
+		method, ok = typ.MethodByName(part.title())
+	}
+
+	if !ok {
+		return -1
+	}
+
+	return method.Index
+}
@@ -0,0 +1,111 @@
+package raymond
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type testMembers struct {
+	Name    string
+	Other   string      `handlebars:"alias"`
+	Dynamic interface{} `handlebars:"any"`
+	private string      `handlebars:"private"`
+}
+
+func (t testMembers) Value() string { return "value" }
+
+func (t *testMembers) Pointer() string { return "pointer" }
+
+func TestTypeMembers(t *testing.T) {
+	t.Parallel()
+
+	members := typeMembersOf(reflect.TypeOf(testMembers{}))
+	if members != typeMembersOf(reflect.TypeOf(testMembers{})) {
+		t.Errorf("Type members are not cached")
+	}
+
+	tests := []struct {
+		name      string
+		method    bool
+		ptrMethod bool
+		field     bool
+		tag       int
+		tagCopy   bool
+	}{
+		{"value", true, true, false, -1, false},
+		{"pointer", false, true, false, -1, false},
+		{"name", false, false, true, -1, false},
+		{"alias", false, false, false, 1, false},
+		{"any", false, false, false, 2, true},
+		{"private", false, false, false, 3, true},
+		{"missing", false, false, false, -1, false},
+	}
+
+	for _, test := range tests {
+		m := members.get(pathPart{name: test.name})
+
+		if (m.method >= 0) != test.method || (m.ptrMethod >= 0) != test.ptrMethod || (m.field != nil) != test.field ||
+			(m.tag != test.tag) || (m.tagCopy != test.tagCopy) {
+			t.Errorf("Unexpected resolution of %q: %+v", test.name, m)
+		}
+
+		if members.get(pathPart{name: test.name}) != m {
+			t.Errorf("Resolution of %q is not cached", test.name)
+		}
+	}
+}
+
+func TestEvalMembers(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{name}} {{alias}} {{any}} {{value}} {{pointer}}`)
+
+	ctx := testMembers{Name: "foo", Other: "bar", Dynamic: "baz"}
+
+	if output := tpl.MustExec(ctx); output != "foo bar baz value " {
+		t.Errorf("Unexpected output with struct value: %q", output)
+	}
+
+	if output := tpl.MustExec(&ctx); output != "foo bar baz value pointer" {
+		t.Errorf("Unexpected output with struct pointer: %q", output)
+	}
+}
+
+func TestEvalMembersConcurrently(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{#each items}}{{name}}{{alias}}{{pointer}}{{/each}}`)
+
+	ctx := map[string][]*testMembers{"items": {{Name: "a", Other: "b"}, {Name: "c", Other: "d"}}}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if output := tpl.MustExec(ctx); output != "abpointercdpointer" {
+				t.Errorf("Unexpected output: %q", output)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestEvalMapNilValue(t *testing.T) {
+	t.Parallel()
+
+	ctx := map[string]interface{}{
+		"foo": "parent",
+		"a":   map[string]interface{}{"foo": nil},
+	}
+
+	// a nil value is found, so the parent context is not checked
+	if output := MustRender(`{{#a}}[{{foo}}]{{/a}}`, ctx); output != "[]" {
+		t.Errorf("Unexpected output with nil map value: %q", output)
+	}
+}
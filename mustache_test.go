@@ -2,8 +2,8 @@ package raymond
 
 import (
 	"io/ioutil"
+	"os"
 	"path"
-	"regexp"
 	"strings"
 	"testing"
 
@@ -11,9 +11,11 @@ import (
 )
 
 //
-// Note, as the JS implementation, the divergences from mustache spec:
-//   - we don't support alternative delimeters
-//   - the mustache lambda spec differs
+// Note, as the JS implementation, the mustache lambda spec differs, so ~lambdas.yml tests are run
+// in mustache lambdas mode, with the lambdas defined in mustacheSpecLambdas().
+//
+// Note, ~inheritance.yml and ~dynamic-names.yml tests are also copied below, so that they run with
+// older versions of the mustache specs submodule. They are skipped when the submodule has those spec files.
 //
 
 type mustacheTest struct {
@@ -30,23 +32,19 @@ type mustacheTestFile struct {
 	Tests    []mustacheTest
 }
 
-var (
-	rAltDelim = regexp.MustCompile(regexp.QuoteMeta("{{="))
-)
-
 var (
 	musTestLambdaInterMult = 0
 )
 
-func TestMustache(t *testing.T) {
-	skipFiles := map[string]bool{
-		// mustache lambdas differ from handlebars lambdas
-		"~lambdas.yml": true,
-	}
+const mustacheLambdasFile = "~lambdas.yml"
 
+func TestMustache(t *testing.T) {
 	for _, fileName := range mustacheTestFiles() {
-		if skipFiles[fileName] {
-			// fmt.Printf("Skipped file: %s\n", fileName)
+		if fileName == mustacheLambdasFile {
+			// mustache lambdas differ from handlebars lambdas
+			launchTestsWithSetup(t, testsFromMustacheFile(fileName), func(tpl *Template) {
+				tpl.SetMustacheLambdas(true)
+			})
 			continue
 		}
 
@@ -54,6 +52,32 @@ func TestMustache(t *testing.T) {
 	}
 }
 
+// mustacheSpecLambdas returns the go implementations of lambdas in ~lambdas.yml tests
+func mustacheSpecLambdas() map[string]interface{} {
+	calls := 0
+
+	return map[string]interface{}{
+		"Interpolation":                        func() string { return "world" },
+		"Interpolation - Expansion":            func() string { return "{{planet}}" },
+		"Interpolation - Alternate Delimiters": func() string { return "|planet| => {{planet}}" },
+		"Interpolation - Multiple Calls": func() int {
+			calls++
+			return calls
+		},
+		"Escaping": func() string { return ">" },
+		"Section": func(text string) string {
+			if text == "{{x}}" {
+				return "yes"
+			}
+			return "no"
+		},
+		"Section - Expansion":            func(text string) string { return text + "{{planet}}" + text },
+		"Section - Alternate Delimiters": func(text string) string { return text + "{{planet}} => |planet|" + text },
+		"Section - Multiple Calls":       func(text string) string { return "__" + text + "__" },
+		"Inverted Section":               func(text string) bool { return false },
+	}
+}
+
 func testsFromMustacheFile(fileName string) []Test {
 	result := []Test{}
 
@@ -67,12 +91,19 @@ func testsFromMustacheFile(fileName string) []Test {
 		panic(err)
 	}
 
+	lambdas := mustacheSpecLambdas()
+
 	for _, mustacheTest := range testFile.Tests {
 		if mustBeSkipped(mustacheTest, fileName) {
 			// fmt.Printf("Skipped test: %s\n", mustacheTest.Name)
 			continue
 		}
 
+		if data, ok := mustacheTest.Data.(map[interface{}]interface{}); ok && (fileName == mustacheLambdasFile) {
+			// replace lambda code with go implementation
+			data["lambda"] = lambdas[mustacheTest.Name]
+		}
+
 		test := Test{
 			name:     mustacheTest.Name,
 			input:    mustacheTest.Template,
@@ -89,24 +120,11 @@ func testsFromMustacheFile(fileName string) []Test {
 
 // returns true if test must be skipped
 func mustBeSkipped(test mustacheTest, fileName string) bool {
-	// handlebars does not support alternative delimiters
-	return haveAltDelimiter(test) ||
-		// the JS implementation skips those tests
-		fileName == "partials.yml" && (test.Name == "Failed Lookup" || test.Name == "Standalone Indentation")
-}
-
-// returns true if test have alternative delimeter in template or in partials
-func haveAltDelimiter(test mustacheTest) bool {
-	// check template
-	if rAltDelim.MatchString(test.Template) {
-		return true
-	}
-
-	// check partials
-	for _, partial := range test.Partials {
-		if rAltDelim.MatchString(partial) {
-			return true
-		}
+	// the JS implementation skips those tests
+	// NOTE: "Standalone Indentation" expects interpolated data not to be indented, but the whole partial output is indented
+	switch fileName {
+	case "partials.yml", "~dynamic-names.yml":
+		return test.Name == "Failed Lookup" || test.Name == "Standalone Indentation"
 	}
 
 	return false
@@ -131,6 +149,13 @@ func mustacheTestFiles() []string {
 	return result
 }
 
+// skipIfMustacheSpecFile skips test if the mustache specs submodule has given spec file, as it is run by TestMustache()
+func skipIfMustacheSpecFile(t *testing.T, fileName string) {
+	if _, err := os.Stat(path.Join("mustache", "specs", fileName)); err == nil {
+		t.Skipf("%s is run from mustache specs", fileName)
+	}
+}
+
 //
 // Following tests come fron ~lambdas.yml
 //
@@ -232,3 +257,367 @@ func TestMustacheLambdas(t *testing.T) {
 
 	launchTests(t, mustacheLambdasTests)
 }
+
+func TestMustacheLambdasMode(t *testing.T) {
+	t.Parallel()
+
+	source := `{{lambda}}|{{#list}}{{#wrap}}{{name}}{{/wrap}}{{/list}}`
+	data := map[string]interface{}{
+		"planet": "world",
+		"lambda": func() string { return "{{planet}}" },
+		"wrap":   func(text string) string { return "(" + text + ")" },
+		"list":   []map[string]string{{"name": "foo"}, {"name": "bar"}},
+	}
+
+	tpl := MustParse(source)
+	tpl.SetMustacheLambdas(true)
+
+	if output := tpl.MustExec(data); output != "world|(foo)(bar)" {
+		t.Errorf("Unexpected output in mustache lambdas mode: %q", output)
+	}
+
+	// lambdas results are not rendered by default
+	tpl = MustParse(`{{lambda}}`)
+
+	if output := tpl.MustExec(data); output != "{{planet}}" {
+		t.Errorf("Unexpected output without mustache lambdas mode: %q", output)
+	}
+}
+
+//
+// Following tests come fron ~inheritance.yml
+//
+
+var mustacheInheritanceTests = []Test{
+	{"Default", "{{$title}}Default title{{/title}}\n", map[string]interface{}{}, nil, nil, nil, "Default title\n"},
+	{"Variable", "{{$foo}}default {{bar}} content{{/foo}}\n", map[string]interface{}{"bar": "baz"}, nil, nil, nil, "default baz content\n"},
+	{"Triple Mustache", "{{$foo}}default {{{bar}}} content{{/foo}}\n", map[string]interface{}{"bar": "<baz>"}, nil, nil, nil, "default <baz> content\n"},
+	{"Sections", "{{$foo}}default {{#bar}}{{baz}}{{/bar}} content{{/foo}}\n", map[string]interface{}{"bar": map[string]string{"baz": "qux"}}, nil, nil, nil, "default qux content\n"},
+	{"Negative Sections", "{{$foo}}default {{^bar}}{{baz}}{{/bar}} content{{/foo}}\n", map[string]interface{}{"baz": "three"}, nil, nil, nil, "default three content\n"},
+	{"Mustache Injection", "{{$foo}}default {{#bar}}{{baz}}{{/bar}} content{{/foo}}\n", map[string]interface{}{"bar": map[string]string{"baz": "{{qux}}"}}, nil, nil, nil, "default {{qux}} content\n"},
+	{
+		"Inherit",
+		"{{<include}}{{/include}}\n",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"include": "{{$foo}}default content{{/foo}}"},
+		"default content",
+	},
+	{
+		"Overridden content",
+		"{{<super}}{{$title}}sub template title{{/title}}{{/super}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"super": "...{{$title}}Default title{{/title}}..."},
+		"...sub template title...",
+	},
+	{
+		"Data does not override block",
+		"{{<include}}{{$var}}var in template{{/var}}{{/include}}",
+		map[string]interface{}{"var": "var in data"},
+		nil, nil,
+		map[string]string{"include": "{{$var}}var in include{{/var}}"},
+		"var in template",
+	},
+	{
+		"Data does not override block default",
+		"{{<include}}{{/include}}",
+		map[string]interface{}{"var": "var in data"},
+		nil, nil,
+		map[string]string{"include": "{{$var}}var in include{{/var}}"},
+		"var in include",
+	},
+	{
+		"Overridden parent",
+		"test {{<parent}}{{$stuff}}override{{/stuff}}{{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "{{$stuff}}...{{/stuff}}"},
+		"test override",
+	},
+	{
+		"Two overridden parents",
+		"test {{<parent}}{{$stuff}}override1{{/stuff}}{{/parent}} {{<parent}}{{$stuff}}override2{{/stuff}}{{/parent}}\n",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "|{{$stuff}}...{{/stuff}}{{$default}} default{{/default}}|"},
+		"test |override1 default| |override2 default|\n",
+	},
+	{
+		"Inherit indentation",
+		"{{<parent}}{{$nineties}}hammer time{{/nineties}}{{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "stop:\n  {{$nineties}}collaborate and listen{{/nineties}}\n"},
+		"stop:\n  hammer time\n",
+	},
+	{
+		"Only one override",
+		"{{<parent}}{{$stuff2}}override two{{/stuff2}}{{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "{{$stuff}}new default one{{/stuff}}, {{$stuff2}}new default two{{/stuff2}}"},
+		"new default one, override two",
+	},
+	{
+		"Parent template",
+		"{{>parent}}|{{<parent}}{{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "{{$foo}}default content{{/foo}}"},
+		"default content|default content",
+	},
+	{
+		"Recursion",
+		"{{<parent}}{{$foo}}override{{/foo}}{{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{
+			"parent":  "{{$foo}}default content{{/foo}} {{$bar}}{{<parent2}}{{/parent2}}{{/bar}}",
+			"parent2": "{{$foo}}parent2 default content{{/foo}} {{<parent}}{{$bar}}don't recurse{{/bar}}{{/parent}}",
+		},
+		"override override override don't recurse",
+	},
+	{
+		"Multi-level inheritance",
+		"{{<parent}}{{$a}}c{{/a}}{{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{
+			"parent":      "{{<older}}{{$a}}p{{/a}}{{/older}}",
+			"older":       "{{<grandParent}}{{$a}}o{{/a}}{{/grandParent}}",
+			"grandParent": "{{$a}}g{{/a}}",
+		},
+		"c",
+	},
+	{
+		"Multi-level inheritance, no sub child",
+		"{{<parent}}{{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{
+			"parent":      "{{<older}}{{$a}}p{{/a}}{{/older}}",
+			"older":       "{{<grandParent}}{{$a}}o{{/a}}{{/grandParent}}",
+			"grandParent": "{{$a}}g{{/a}}",
+		},
+		"p",
+	},
+	{
+		"Text inside parent",
+		"{{<parent}} asdfasd {{$foo}}hmm{{/foo}} asdfasdfasdf {{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "{{$foo}}default content{{/foo}}"},
+		"hmm",
+	},
+	{
+		"Text inside parent",
+		"{{<parent}} asdfasd asdfasdfasdf {{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "{{$foo}}default content{{/foo}}"},
+		"default content",
+	},
+	{
+		"Block scope",
+		"{{<parent}}{{$block}}I say {{fruit}}.{{/block}}{{/parent}}",
+		map[string]interface{}{"fruit": "apples", "nested": map[string]string{"fruit": "bananas"}},
+		nil, nil,
+		map[string]string{"parent": "{{#nested}}{{$block}}You say {{fruit}}.{{/block}}{{/nested}}"},
+		"I say bananas.",
+	},
+	{
+		"Standalone parent",
+		"Hi,\n  {{<parent}}{{/parent}}\n",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "one\ntwo\n"},
+		"Hi,\n  one\n  two\n",
+	},
+	{
+		"Standalone block",
+		"{{<parent}}{{$block}}\none\ntwo\n{{/block}}\n{{/parent}}\n",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "Hi,\n  {{$block}}{{/block}}\n"},
+		"Hi,\n  one\n  two\n",
+	},
+	{
+		"Block reindentation",
+		"{{<parent}}{{$block}}\n    one\n    two\n{{/block}}{{/parent}}\n",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "Hi,\n  {{$block}}\n  {{/block}}\n"},
+		"Hi,\n  one\n  two\n",
+	},
+	{
+		"Intrinsic indentation",
+		"{{<parent}}{{$block}}\none\ntwo\n{{/block}}{{/parent}}\n",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "Hi,\n{{$block}}\n    default\n{{/block}}\n"},
+		"Hi,\n    one\n    two\n",
+	},
+	{
+		"Nested block reindentation",
+		"{{<parent}}{{$nested}}\nthree\n{{/nested}}{{/parent}}\n",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{
+			"parent":      "{{<grandparent}}{{$block}}\none\n  {{$nested}}\n  two\n  {{/nested}}\n{{/block}}{{/grandparent}}\n",
+			"grandparent": "{{$block}}default{{/block}}",
+		},
+		"one\n  three\n",
+	},
+	{
+		"Override parent with newlines",
+		"{{<parent}}{{$ballmer}}\npeaked\n\n:(\n{{/ballmer}}{{/parent}}",
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"parent": "{{$ballmer}}peaking{{/ballmer}}"},
+		"peaked\n\n:(\n",
+	},
+}
+
+func TestMustacheInheritance(t *testing.T) {
+	t.Parallel()
+
+	skipIfMustacheSpecFile(t, "~inheritance.yml")
+
+	launchTests(t, mustacheInheritanceTests)
+}
+
+//
+// Following tests come fron ~dynamic-names.yml
+//
+
+var mustacheDynamicNamesTests = []Test{
+	{
+		"Basic Behavior - Partial",
+		`"{{>*dynamic}}"`,
+		map[string]interface{}{"dynamic": "content"},
+		nil, nil,
+		map[string]string{"content": "Hello, world!"},
+		`"Hello, world!"`,
+	},
+	{
+		"Basic Behavior - Name Resolution",
+		`"{{>*dynamic}}"`,
+		map[string]interface{}{"dynamic": "content"},
+		nil, nil,
+		map[string]string{"content": "Hello, world!", "dynamic": "Error"},
+		`"Hello, world!"`,
+	},
+	{
+		"Context Misses",
+		`"{{>*missing}}"`,
+		map[string]interface{}{},
+		nil, nil,
+		map[string]string{"missing": "Hello, world!"},
+		`""`,
+	},
+	{
+		"Context",
+		`"{{>*example}}"`,
+		map[string]interface{}{"text": "Hello, world!", "example": "partial"},
+		nil, nil,
+		map[string]string{"partial": "*{{text}}*"},
+		`"*Hello, world!*"`,
+	},
+	{
+		"Dotted Names",
+		`"{{>*foo.bar.baz}}"`,
+		map[string]interface{}{"text": "Hello, world!", "foo": map[string]interface{}{"bar": map[string]string{"baz": "partial"}}},
+		nil, nil,
+		map[string]string{"partial": "*{{text}}*"},
+		`"*Hello, world!*"`,
+	},
+	{
+		"Dotted Names - Operator Precedence",
+		`"{{>*foo.bar.baz}}"`,
+		map[string]interface{}{"text": "Hello, world!", "foo": "test", "test": map[string]interface{}{"bar": map[string]string{"baz": "partial"}}},
+		nil, nil,
+		map[string]string{"partial": "*{{text}}*", "test": "Error"},
+		`""`,
+	},
+	{
+		"Dotted Names - Failed Lookup",
+		`"{{>*foo.bar.baz}}"`,
+		map[string]interface{}{"foo": map[string]interface{}{"text": "Hello, world!", "bar": map[string]interface{}{}}},
+		nil, nil,
+		map[string]string{"partial": "*{{text}}*"},
+		`""`,
+	},
+	{
+		"Dotted names - Context Stacking",
+		"{{#section1}}{{>*section2.dynamic}}{{/section1}}",
+		map[string]interface{}{"section1": map[string]string{"dynamic": "content1"}, "section2": map[string]string{"dynamic": "content2"}},
+		nil, nil,
+		map[string]string{"content1": "doesnt", "content2": "works"},
+		"works",
+	},
+	{
+		"Recursion",
+		"{{>*template}}",
+		map[string]interface{}{"template": "node", "content": "X", "nodes": []map[string]interface{}{{"content": "Y", "nodes": []interface{}{}}}},
+		nil, nil,
+		map[string]string{"node": "{{content}}<{{#nodes}}{{>*template}}{{/nodes}}>"},
+		"X<Y<>>",
+	},
+	{
+		"Surrounding Whitespace",
+		"| {{>*partial}} |",
+		map[string]interface{}{"partial": "foobar"},
+		nil, nil,
+		map[string]string{"foobar": "\t|\t"},
+		"| \t|\t |",
+	},
+	{
+		"Inline Indentation",
+		"  {{data}}  {{>*dynamic}}\n",
+		map[string]interface{}{"dynamic": "partial", "data": "|"},
+		nil, nil,
+		map[string]string{"partial": ">\n>"},
+		"  |  >\n>\n",
+	},
+	{
+		"Standalone Line Endings",
+		"|\r\n{{>*dynamic}}\r\n|",
+		map[string]interface{}{"dynamic": "partial"},
+		nil, nil,
+		map[string]string{"partial": ">"},
+		"|\r\n>|",
+	},
+	{
+		"Standalone Without Previous Line",
+		"  {{>*dynamic}}\n>",
+		map[string]interface{}{"dynamic": "partial"},
+		nil, nil,
+		map[string]string{"partial": ">\n>"},
+		"  >\n  >>",
+	},
+	{
+		"Standalone Without Newline",
+		">\n  {{>*dynamic}}",
+		map[string]interface{}{"dynamic": "partial"},
+		nil, nil,
+		map[string]string{"partial": ">\n>"},
+		">\n  >\n  >",
+	},
+	{
+		"Padding Whitespace",
+		"|{{>* dynamic }}|",
+		map[string]interface{}{"dynamic": "partial", "boolean": true},
+		nil, nil,
+		map[string]string{"partial": "[]"},
+		"|[]|",
+	},
+}
+
+func TestMustacheDynamicNames(t *testing.T) {
+	t.Parallel()
+
+	skipIfMustacheSpecFile(t, "~dynamic-names.yml")
+
+	launchTests(t, mustacheDynamicNamesTests)
+}
@@ -3,9 +3,9 @@ package parser
 
 import (
 	"fmt"
-	"regexp"
 	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/aymerick/raymond/ast"
 	"github.com/aymerick/raymond/lexer"
@@ -17,6 +17,9 @@ import (
 
 // parser is a syntax analyzer.
 type parser struct {
+	// Input string
+	input string
+
 	// Lexer
 	lex *lexer.Lexer
 
@@ -28,27 +31,62 @@ type parser struct {
 
 	// All tokens have been retreieved from lexer
 	lexOver bool
-}
 
-var (
-	rOpenComment  = regexp.MustCompile(`^\{\{~?!-?-?`)
-	rCloseComment = regexp.MustCompile(`-?-?~?\}\}$`)
-	rOpenAmp      = regexp.MustCompile(`^\{\{~?&`)
-)
+	// Current mustaches delimiters
+	openDelim  string
+	closeDelim string
+}
 
 // new instanciates a new parser
-func new(input string) *parser {
-	return &parser{
-		lex: lexer.Scan(input),
+func new(input string, options Options) *parser {
+	result := &parser{
+		input: input,
+		lex: lexer.ScanWithOptions(input, lexer.Options{
+			OpenDelimiter:  options.OpenDelimiter,
+			CloseDelimiter: options.CloseDelimiter,
+		}),
+		openDelim:  "{{",
+		closeDelim: "}}",
+	}
+
+	if options.OpenDelimiter != "" {
+		result.openDelim = options.OpenDelimiter
+	}
+
+	if options.CloseDelimiter != "" {
+		result.closeDelim = options.CloseDelimiter
 	}
+
+	return result
+}
+
+// Options represents parsing options.
+type Options struct {
+	// IgnoreStandalone disables the removal of whitespaces and line breaks around standalone statements, like the
+	// handlebars.js `ignoreStandalone` option.
+	IgnoreStandalone bool
+
+	// PreventIndent keeps the indentation of standalone partials as content, instead of indenting every line of the
+	// partial output, like the handlebars.js `preventIndent` option.
+	PreventIndent bool
+
+	// OpenDelimiter and CloseDelimiter are the mustaches delimiters used at the start of input. Default delimiters
+	// are "{{" and "}}".
+	OpenDelimiter  string
+	CloseDelimiter string
 }
 
 // Parse analyzes given input and returns the AST root node.
-func Parse(input string) (result *ast.Program, err error) {
+func Parse(input string) (*ast.Program, error) {
+	return ParseWithOptions(input, Options{})
+}
+
+// ParseWithOptions analyzes given input with given options and returns the AST root node.
+func ParseWithOptions(input string, options Options) (result *ast.Program, err error) {
 	// recover error
 	defer errRecover(&err)
 
-	parser := new(input)
+	parser := new(input, options)
 
 	// parse
 	result = parser.parseProgram()
@@ -61,7 +99,7 @@ func Parse(input string) (result *ast.Program, err error) {
 	}
 
 	// fix whitespaces
-	processWhitespaces(result)
+	processWhitespaces(result, options)
 
 	// named returned values
 	return
@@ -113,7 +151,7 @@ func (p *parser) parseProgram() *ast.Program {
 	return result
 }
 
-// statement : mustache | block | rawBlock | partial | content | COMMENT
+// statement : mustache | block | rawBlock | partial | decoratorBlock | content | COMMENT
 func (p *parser) parseStatement() ast.Node {
 	var result ast.Node
 
@@ -135,12 +173,30 @@ func (p *parser) parseStatement() ast.Node {
 	case lexer.TokenOpenPartial:
 		// partial
 		result = p.parsePartial()
+	case lexer.TokenOpenPartialBlock:
+		// partialBlock
+		result = p.parsePartialBlock()
+	case lexer.TokenOpenParent:
+		// parent
+		result = p.parseParent()
+	case lexer.TokenOpenNamedBlock:
+		// namedBlock
+		result = p.parseNamedBlock()
+	case lexer.TokenOpenDecorator:
+		// decorator
+		result = p.parseDecorator()
+	case lexer.TokenOpenDecoratorBlock:
+		// decoratorBlock
+		result = p.parseDecoratorBlock()
 	case lexer.TokenContent:
 		// content
 		result = p.parseContent()
 	case lexer.TokenComment:
 		// COMMENT
 		result = p.parseComment()
+	case lexer.TokenSetDelimiters:
+		// SET_DELIMITERS
+		result = p.parseSetDelimiters()
 	}
 
 	return result
@@ -154,8 +210,10 @@ func (p *parser) isStatement() bool {
 
 	switch p.next().Kind {
 	case lexer.TokenOpen, lexer.TokenOpenUnescaped, lexer.TokenOpenBlock,
-		lexer.TokenOpenInverse, lexer.TokenOpenRawBlock, lexer.TokenOpenPartial,
-		lexer.TokenContent, lexer.TokenComment:
+		lexer.TokenOpenInverse, lexer.TokenOpenRawBlock, lexer.TokenOpenPartial, lexer.TokenOpenPartialBlock,
+		lexer.TokenOpenParent, lexer.TokenOpenNamedBlock,
+		lexer.TokenOpenDecorator, lexer.TokenOpenDecoratorBlock, lexer.TokenContent, lexer.TokenComment,
+		lexer.TokenSetDelimiters:
 		return true
 	}
 
@@ -179,15 +237,55 @@ func (p *parser) parseComment() *ast.CommentStatement {
 	// COMMENT
 	tok := p.shift()
 
-	value := rOpenComment.ReplaceAllString(tok.Val, "")
-	value = rCloseComment.ReplaceAllString(value, "")
+	// strip {{~!-- and --~}}
+	value := strings.TrimPrefix(tok.Val, p.openDelim)
+	for _, str := range []string{"~", "!", "-", "-"} {
+		value = strings.TrimPrefix(value, str)
+	}
+
+	value = strings.TrimSuffix(value, p.closeDelim)
+	for _, str := range []string{"~", "-", "-"} {
+		value = strings.TrimSuffix(value, str)
+	}
 
 	result := ast.NewCommentStatement(tok.Pos, tok.Line, value)
-	result.Strip = ast.NewStripForStr(tok.Val)
+	result.Strip = p.newStrip(tok.Val, tok.Val)
 
 	return result
 }
 
+// setDelimiters : SET_DELIMITERS
+//
+// The set delimiters tag is kept in AST as a comment, so that standalone tags are correctly stripped.
+func (p *parser) parseSetDelimiters() *ast.CommentStatement {
+	// SET_DELIMITERS
+	tok := p.shift()
+
+	// {{=<% %>=}}
+	inner := tok.Val[len(p.openDelim)+1 : len(tok.Val)-len(p.closeDelim)-1]
+
+	fields := strings.Fields(inner)
+	if len(fields) != 2 {
+		errToken(tok, "Invalid set delimiters tag")
+	}
+
+	result := ast.NewCommentStatement(tok.Pos, tok.Line, inner)
+	result.Strip = &ast.Strip{}
+
+	// following tokens are scanned with new delimiters
+	p.openDelim, p.closeDelim = fields[0], fields[1]
+
+	return result
+}
+
+// newStrip instanciates a Strip for given open and close mustaches, scanned with current delimiters
+func (p *parser) newStrip(openStr, closeStr string) *ast.Strip {
+	return &ast.Strip{
+		Open:  strings.HasPrefix(strings.TrimPrefix(openStr, p.openDelim), "~"),
+		Close: strings.HasSuffix(strings.TrimSuffix(closeStr, p.closeDelim), "~"),
+	}
+}
+
 // param* hash?
 func (p *parser) parseExpressionParamsHash() ([]ast.Node, *ast.Hash) {
 	var params []ast.Node
@@ -227,6 +325,7 @@ func (p *parser) parseRawBlock() *ast.BlockStatement {
 	tok := p.shift()
 
 	result := ast.NewBlockStatement(tok.Pos, tok.Line)
+	result.Raw = true
 
 	// helperName param* hash?
 	result.Expression = p.parseExpression(tok)
@@ -282,10 +381,16 @@ func (p *parser) parseBlock() *ast.BlockStatement {
 	result, blockParams := p.parseOpenBlock()
 
 	// program
+	start := p.next().Pos
+
 	program := p.parseProgram()
 	program.BlockParams = blockParams
 	result.Program = program
 
+	// keep program source, needed by mustache lambdas
+	result.Source = p.input[start:p.next().Pos]
+	result.OpenDelimiter, result.CloseDelimiter = p.openDelim, p.closeDelim
+
 	// inverseChain?
 	if p.isInverseChain() {
 		result.Inverse = p.parseInverseChain()
@@ -404,7 +509,7 @@ func (p *parser) parseInverseAndProgram() *ast.Program {
 
 	// program
 	result := p.parseProgram()
-	result.Strip = ast.NewStripForStr(tok.Val)
+	result.Strip = p.newStrip(tok.Val, tok.Val)
 
 	return result
 }
@@ -425,7 +530,7 @@ func (p *parser) parseOpenBlock() (*ast.BlockStatement, []string) {
 		errExpected(lexer.TokenClose, tokClose)
 	}
 
-	result.OpenStrip = ast.NewStrip(tok.Val, tokClose.Val)
+	result.OpenStrip = p.newStrip(tok.Val, tokClose.Val)
 
 	// named returned values
 	return result, blockParams
@@ -433,6 +538,13 @@ func (p *parser) parseOpenBlock() (*ast.BlockStatement, []string) {
 
 // closeBlock : OPEN_ENDBLOCK helperName CLOSE
 func (p *parser) parseCloseBlock(block *ast.BlockStatement) {
+	block.CloseStrip = p.parseCloseBlockName(block.Expression.Canonical())
+}
+
+// closeBlock : OPEN_ENDBLOCK helperName CLOSE
+//
+// It checks that helperName matches given opening name, and returns the close strip.
+func (p *parser) parseCloseBlockName(openName string) *ast.Strip {
 	// OPEN_ENDBLOCK
 	tok := p.shift()
 	if tok.Kind != lexer.TokenOpenEndBlock {
@@ -447,7 +559,6 @@ func (p *parser) parseCloseBlock(block *ast.BlockStatement) {
 		errNode(endID, "Erroneous closing expression")
 	}
 
-	openName := block.Expression.Canonical()
 	if openName != closeName {
 		errNode(endID, fmt.Sprintf("%s doesn't match %s", openName, closeName))
 	}
@@ -458,7 +569,46 @@ func (p *parser) parseCloseBlock(block *ast.BlockStatement) {
 		errExpected(lexer.TokenClose, tokClose)
 	}
 
-	block.CloseStrip = ast.NewStrip(tok.Val, tokClose.Val)
+	return p.newStrip(tok.Val, tokClose.Val)
+}
+
+// decoratorBlock : OPEN_DECORATOR_BLOCK helperName param* hash? blockParams? CLOSE program closeBlock
+func (p *parser) parseDecoratorBlock() *ast.DecoratorBlock {
+	// OPEN_DECORATOR_BLOCK
+	tok := p.shift()
+
+	result := ast.NewDecoratorBlock(tok.Pos, tok.Line)
+
+	// helperName param* hash?
+	result.Expression = p.parseExpression(tok)
+
+	// blockParams?
+	var blockParams []string
+	if p.isBlockParams() {
+		blockParams = p.parseBlockParams()
+	}
+
+	// CLOSE
+	tokClose := p.shift()
+	if tokClose.Kind != lexer.TokenClose {
+		errExpected(lexer.TokenClose, tokClose)
+	}
+
+	result.OpenStrip = p.newStrip(tok.Val, tokClose.Val)
+
+	// program
+	program := p.parseProgram()
+	program.BlockParams = blockParams
+	result.Program = program
+
+	if p.isInverseChain() {
+		errToken(p.next(), "Unexpected inverse in decorator block")
+	}
+
+	// closeBlock
+	result.CloseStrip = p.parseCloseBlockName(result.Expression.Canonical())
+
+	return result
 }
 
 // mustache : OPEN helperName param* hash? CLOSE
@@ -473,7 +623,8 @@ func (p *parser) parseMustache() *ast.MustacheStatement {
 	}
 
 	unescaped := false
-	if (tok.Kind == lexer.TokenOpenUnescaped) || (rOpenAmp.MatchString(tok.Val)) {
+	// {{& or {{~&
+	if (tok.Kind == lexer.TokenOpenUnescaped) || strings.HasPrefix(strings.TrimLeft(strings.TrimPrefix(tok.Val, p.openDelim), "~"), "&") {
 		unescaped = true
 	}
 
@@ -488,7 +639,7 @@ func (p *parser) parseMustache() *ast.MustacheStatement {
 		errExpected(closeToken, tokClose)
 	}
 
-	result.Strip = ast.NewStrip(tok.Val, tokClose.Val)
+	result.Strip = p.newStrip(tok.Val, tokClose.Val)
 
 	return result
 }
@@ -500,6 +651,33 @@ func (p *parser) parsePartial() *ast.PartialStatement {
 
 	result := ast.NewPartialStatement(tok.Pos, tok.Line)
 
+	// {{>*
+	result.Dynamic = isDynamicName(tok)
+
+	// partialName
+	result.Name = p.parsePartialName()
+
+	// param* hash?
+	result.Params, result.Hash = p.parseExpressionParamsHash()
+
+	// CLOSE
+	tokClose := p.shift()
+	if tokClose.Kind != lexer.TokenClose {
+		errExpected(lexer.TokenClose, tokClose)
+	}
+
+	result.Strip = p.newStrip(tok.Val, tokClose.Val)
+
+	return result
+}
+
+// partialBlock : OPEN_PARTIAL_BLOCK partialName param* hash? CLOSE program closeBlock
+func (p *parser) parsePartialBlock() *ast.PartialBlockStatement {
+	// OPEN_PARTIAL_BLOCK
+	tok := p.shift()
+
+	result := ast.NewPartialBlockStatement(tok.Pos, tok.Line)
+
 	// partialName
 	result.Name = p.parsePartialName()
 
@@ -512,7 +690,107 @@ func (p *parser) parsePartial() *ast.PartialStatement {
 		errExpected(lexer.TokenClose, tokClose)
 	}
 
-	result.Strip = ast.NewStrip(tok.Val, tokClose.Val)
+	result.OpenStrip = p.newStrip(tok.Val, tokClose.Val)
+
+	// program
+	result.Program = p.parseProgram()
+
+	if p.isInverseChain() {
+		errToken(p.next(), "Unexpected inverse in partial block")
+	}
+
+	// closeBlock
+	openName, _ := ast.HelperNameStr(result.Name)
+	result.CloseStrip = p.parseCloseBlockName(openName)
+
+	return result
+}
+
+// parent : OPEN_PARENT path CLOSE program closeBlock
+func (p *parser) parseParent() *ast.ParentStatement {
+	// OPEN_PARENT
+	tok := p.shift()
+
+	result := ast.NewParentStatement(tok.Pos, tok.Line)
+
+	// {{<*
+	result.Dynamic = isDynamicName(tok)
+
+	// path
+	result.Name = p.parsePath(false)
+
+	// CLOSE
+	tokClose := p.shift()
+	if tokClose.Kind != lexer.TokenClose {
+		errExpected(lexer.TokenClose, tokClose)
+	}
+
+	result.OpenStrip = p.newStrip(tok.Val, tokClose.Val)
+
+	// program
+	result.Program = p.parseProgram()
+
+	// closeBlock
+	openName, _ := ast.HelperNameStr(result.Name)
+	result.CloseStrip = p.parseCloseBlockName(openName)
+
+	return result
+}
+
+// namedBlock : OPEN_NAMED_BLOCK ID CLOSE program closeBlock
+func (p *parser) parseNamedBlock() *ast.NamedBlockStatement {
+	// OPEN_NAMED_BLOCK
+	tok := p.shift()
+
+	result := ast.NewNamedBlockStatement(tok.Pos, tok.Line)
+
+	// ID
+	tokID := p.shift()
+	if tokID.Kind != lexer.TokenID {
+		errExpected(lexer.TokenID, tokID)
+	}
+
+	result.Name = tokID.Val
+
+	// CLOSE
+	tokClose := p.shift()
+	if tokClose.Kind != lexer.TokenClose {
+		errExpected(lexer.TokenClose, tokClose)
+	}
+
+	result.OpenStrip = p.newStrip(tok.Val, tokClose.Val)
+
+	// program
+	result.Program = p.parseProgram()
+
+	// closeBlock
+	result.CloseStrip = p.parseCloseBlockName(result.Name)
+
+	return result
+}
+
+// isDynamicName returns true if given partial or parent opening token is followed by a star, ie. {{>* or {{<*
+func isDynamicName(tok *lexer.Token) bool {
+	return strings.HasSuffix(tok.Val, "*")
+}
+
+// decorator : OPEN_DECORATOR helperName param* hash? CLOSE
+func (p *parser) parseDecorator() *ast.Decorator {
+	// OPEN_DECORATOR
+	tok := p.shift()
+
+	result := ast.NewDecorator(tok.Pos, tok.Line)
+
+	// helperName param* hash?
+	result.Expression = p.parseExpression(tok)
+
+	// CLOSE
+	tokClose := p.shift()
+	if tokClose.Kind != lexer.TokenClose {
+		errExpected(lexer.TokenClose, tokClose)
+	}
+
+	result.Strip = p.newStrip(tok.Val, tokClose.Val)
 
 	return result
 }
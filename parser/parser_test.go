@@ -67,6 +67,7 @@ var parserTests = []parserTest{
 
 	{"parses a comment", `{{! this is a comment }}`, "{{! ' this is a comment ' }}\n"},
 	{"parses a multi-line comment", "{{!\nthis is a multi-line comment\n}}", "{{! '\nthis is a multi-line comment\n' }}\n"},
+	{"parses set delimiters", `{{=<% %>=}}<%~! comment ~%><%& foo %>`, "{{! '<% %>' }}\n{{! ' comment ' }}\n{{ PATH:foo [] }}\n"},
 
 	{"parses an inverse section", `{{#foo}} bar {{^}} baz {{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n    CONTENT[ ' bar ' ]\n  {{^}}\n    CONTENT[ ' baz ' ]\n"},
 	{"parses an inverse (else-style) section", `{{#foo}} bar {{else}} baz {{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n    CONTENT[ ' bar ' ]\n  {{^}}\n    CONTENT[ ' baz ' ]\n"},
@@ -82,6 +83,13 @@ var parserTests = []parserTest{
 	{"parses block with block params", `{{#foo as |bar baz|}}content{{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n    BLOCK PARAMS: [ bar baz ]\n    CONTENT[ 'content' ]\n"},
 	{"parses inverse block with block params", `{{^foo as |bar baz|}}content{{/foo}}`, "BLOCK:\n  PATH:foo []\n  {{^}}\n    BLOCK PARAMS: [ bar baz ]\n    CONTENT[ 'content' ]\n"},
 	{"parses chained inverse block with block params", `{{#foo}}{{else foo as |bar baz|}}content{{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n  {{^}}\n    BLOCK:\n      PATH:foo []\n      PROGRAM:\n        BLOCK PARAMS: [ bar baz ]\n        CONTENT[ 'content' ]\n"},
+	{"parses partial blocks", `{{#> foo}}bar{{/foo}}`, "{{> PARTIAL BLOCK:foo }}\n  PROGRAM:\n    CONTENT[ 'bar' ]\n"},
+	{"parses partial blocks with arguments", `{{#> foo context hash=value}}bar{{/foo}}`, "{{> PARTIAL BLOCK:foo PATH:context HASH{hash=PATH:value} }}\n  PROGRAM:\n    CONTENT[ 'bar' ]\n"},
+	{"parses decorators", `{{* foo bar}}`, "{{ DIRECTIVE PATH:foo [PATH:bar] }}\n"},
+	{"parses dynamic partials", `{{>*foo}}`, "{{> PARTIAL:*foo }}\n"},
+	{"parses parents", `{{<foo}}{{$bar}}baz{{/bar}}{{/foo}}`, "{{< PARENT:foo }}\n  PROGRAM:\n    {{$ BLOCK:bar }}\n      PROGRAM:\n        CONTENT[ 'baz' ]\n"},
+	{"parses dynamic parents", `{{<*foo}}{{/foo}}`, "{{< PARENT:*foo }}\n  PROGRAM:\n"},
+	{"parses inline partials", `{{#*inline "foo"}}bar{{/inline}}`, "DIRECTIVE BLOCK:\n  PATH:inline [\"foo\"]\n  PROGRAM:\n    CONTENT[ 'bar' ]\n"},
 }
 
 func TestParser(t *testing.T) {
@@ -101,6 +109,20 @@ func TestParser(t *testing.T) {
 	}
 }
 
+func TestParserWithDelimiters(t *testing.T) {
+	t.Parallel()
+
+	node, err := ParseWithOptions(`{{foo}}<%{baz}%><%# bar %>qux<%/bar%>`, Options{OpenDelimiter: "<%", CloseDelimiter: "%>"})
+	if err != nil {
+		t.Fatalf("Failed to parse with delimiters: %s", err)
+	}
+
+	expected := "CONTENT[ '{{foo}}' ]\n{{ PATH:baz [] }}\nBLOCK:\n  PATH:bar []\n  PROGRAM:\n    CONTENT[ 'qux' ]\n"
+	if output := ast.Print(node); output != expected {
+		t.Errorf("Unexpected AST with delimiters:\n%s", output)
+	}
+}
+
 var parserErrorTests = []parserTest{
 	{"lexer error", `{{! unclosed comment`, "Lexer error"},
 	{"syntax error", `foo{{^}}`, "Syntax error"},
@@ -135,6 +157,11 @@ var parserErrorTests = []parserTest{
 	{"a path must start with an ID", `{{#/}}content{{/foo}}`, "Expecting ID"},
 	{"a path must end with an ID", `{{foo/bar/}}`, "Expecting ID"},
 
+	{"parent names must match", `{{<foo}}bar{{/baz}}`, "foo doesn't match baz"},
+	{"named block names must match", `{{$foo}}bar{{/baz}}`, "foo doesn't match baz"},
+	{"partial block names must match", `{{#> foo}}bar{{/baz}}`, "foo doesn't match baz"},
+	{"decorator block can't have an inverse section", `{{#*inline "foo"}}bar{{else}}baz{{/inline}}`, "Unexpected inverse in decorator block"},
+
 	//
 	// Next tests come from:
 	//   https://github.com/wycats/handlebars.js/blob/master/spec/parser.js
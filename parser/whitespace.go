@@ -2,6 +2,7 @@ package parser
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/aymerick/raymond/ast"
 )
@@ -12,6 +13,7 @@ import (
 //   https://github.com/wycats/handlebars.js/blob/master/lib/handlebars/compiler/whitespace-control.js
 type whitespaceVisitor struct {
 	isRootSeen bool
+	options    Options
 }
 
 var (
@@ -31,15 +33,17 @@ var (
 )
 
 // newWhitespaceVisitor instanciates a new whitespaceVisitor
-func newWhitespaceVisitor() *whitespaceVisitor {
-	return &whitespaceVisitor{}
+func newWhitespaceVisitor(options Options) *whitespaceVisitor {
+	return &whitespaceVisitor{
+		options: options,
+	}
 }
 
-// processWhitespaces performs whitespace control on given AST
+// processWhitespaces performs whitespace control on given AST with given options
 //
 // WARNING: It must be called only once on AST.
-func processWhitespaces(node ast.Node) {
-	node.Accept(newWhitespaceVisitor())
+func processWhitespaces(node ast.Node, options Options) {
+	node.Accept(newWhitespaceVisitor(options))
 }
 
 func omitRightFirst(body []ast.Node, multiple bool) {
@@ -154,7 +158,7 @@ func isNextWhitespaceProgram(body []ast.Node, i int, isRoot bool) bool {
 		}
 
 		r := rNextWhitespaceEnd
-		if (i+2 > len(body)) || !isRoot {
+		if (i+2 < len(body)) || !isRoot {
 			r = rNextWhitespace
 		}
 
@@ -179,8 +183,9 @@ func (v *whitespaceVisitor) VisitProgram(program *ast.Program) interface{} {
 			continue
 		}
 
-		_isPrevWhitespace := isPrevWhitespaceProgram(body, i, isRoot)
-		_isNextWhitespace := isNextWhitespaceProgram(body, i, isRoot)
+		// standalone statements are not handled in ignoreStandalone mode
+		_isPrevWhitespace := !v.options.IgnoreStandalone && isPrevWhitespaceProgram(body, i, isRoot)
+		_isNextWhitespace := !v.options.IgnoreStandalone && isNextWhitespaceProgram(body, i, isRoot)
 
 		openStandalone := strip.OpenStandalone && _isPrevWhitespace
 		closeStandalone := strip.CloseStandalone && _isNextWhitespace
@@ -198,23 +203,56 @@ func (v *whitespaceVisitor) VisitProgram(program *ast.Program) interface{} {
 			omitRight(body, i, false)
 
 			if omitLeft(body, i, false) {
-				// If we are on a standalone node, save the indent info for partials
-				if partial, ok := current.(*ast.PartialStatement); ok {
+				// If we are on a standalone node, save the indent info for partials and parents
+				var partialIndent *string
+
+				switch node := current.(type) {
+				case *ast.PartialStatement:
+					partialIndent = &node.Indent
+				case *ast.ParentStatement:
+					partialIndent = &node.Indent
+				case *ast.NamedBlockStatement:
+					partialIndent = &node.Indent
+				}
+
+				if partialIndent != nil {
 					// Pull out the whitespace from the final line
 					if i > 0 {
 						if prevContent, ok := body[i-1].(*ast.ContentStatement); ok {
-							partial.Indent = rPartialIndent.FindString(prevContent.Original)
+							indent := rPartialIndent.FindString(prevContent.Original)
+
+							if v.options.PreventIndent {
+								// keep indent as content
+								prevContent.Value += indent
+							} else {
+								*partialIndent = indent
+							}
 						}
 					}
 				}
 			}
 		}
 
-		if b, ok := current.(*ast.BlockStatement); ok {
+		var blockProgram, blockInverse *ast.Program
+
+		switch b := current.(type) {
+		case *ast.BlockStatement:
+			blockProgram, blockInverse = b.Program, b.Inverse
+		case *ast.DecoratorBlock:
+			blockProgram = b.Program
+		case *ast.PartialBlockStatement:
+			blockProgram = b.Program
+		case *ast.ParentStatement:
+			blockProgram = b.Program
+		case *ast.NamedBlockStatement:
+			blockProgram = b.Program
+		}
+
+		if (blockProgram != nil) || (blockInverse != nil) {
 			if openStandalone {
-				prog := b.Program
+				prog := blockProgram
 				if prog == nil {
-					prog = b.Inverse
+					prog = blockInverse
 				}
 
 				omitRightFirst(prog.Body, false)
@@ -224,9 +262,9 @@ func (v *whitespaceVisitor) VisitProgram(program *ast.Program) interface{} {
 			}
 
 			if closeStandalone {
-				prog := b.Inverse
+				prog := blockInverse
 				if prog == nil {
-					prog = b.Program
+					prog = blockProgram
 				}
 
 				// Always strip the next node
@@ -236,11 +274,39 @@ func (v *whitespaceVisitor) VisitProgram(program *ast.Program) interface{} {
 			}
 
 		}
+
+		if named, ok := current.(*ast.NamedBlockStatement); ok && openStandalone && !inlineStandalone && !v.options.PreventIndent {
+			named.Indent = blockIndent(named, body, i)
+		}
 	}
 
 	return nil
 }
 
+// blockIndent returns the indentation of the content of given standalone named block, found at index i of body: the
+// indentation of its first line, or the indentation of the block tag if it is empty
+func blockIndent(node *ast.NamedBlockStatement, body []ast.Node, i int) string {
+	for _, n := range node.Program.Body {
+		content, ok := n.(*ast.ContentStatement)
+		if !ok {
+			// first line starts with a statement
+			return ""
+		}
+
+		if content.Value != "" {
+			return content.Value[:len(content.Value)-len(strings.TrimLeft(content.Value, " \t"))]
+		}
+	}
+
+	if i > 0 {
+		if prevContent, ok := body[i-1].(*ast.ContentStatement); ok {
+			return rPartialIndent.FindString(prevContent.Original)
+		}
+	}
+
+	return ""
+}
+
 func (v *whitespaceVisitor) VisitBlock(block *ast.BlockStatement) interface{} {
 	if block.Program != nil {
 		block.Program.Accept(v)
@@ -306,7 +372,7 @@ func (v *whitespaceVisitor) VisitBlock(block *ast.BlockStatement) interface{} {
 		}
 
 		// Find standalone else statements
-		if isPrevWhitespace(program.Body) && isNextWhitespace(firstInverse.Body) {
+		if !v.options.IgnoreStandalone && isPrevWhitespace(program.Body) && isNextWhitespace(firstInverse.Body) {
 			omitLeftLast(program.Body, false)
 
 			omitRightFirst(firstInverse.Body, false)
@@ -318,10 +384,73 @@ func (v *whitespaceVisitor) VisitBlock(block *ast.BlockStatement) interface{} {
 	return strip
 }
 
+func (v *whitespaceVisitor) VisitDecoratorBlock(block *ast.DecoratorBlock) interface{} {
+	return v.visitProgramBlock(block.Program, block.OpenStrip, block.CloseStrip)
+}
+
+func (v *whitespaceVisitor) VisitPartialBlock(block *ast.PartialBlockStatement) interface{} {
+	return v.visitProgramBlock(block.Program, block.OpenStrip, block.CloseStrip)
+}
+
+func (v *whitespaceVisitor) VisitParent(node *ast.ParentStatement) interface{} {
+	// content of parent body is ignored, so its statements are handled as if they were at template root
+	v.isRootSeen = false
+
+	strip := v.visitProgramBlock(node.Program, node.OpenStrip, node.CloseStrip)
+
+	if len(node.Program.Body) == 0 {
+		// {{<parent}}{{/parent}} is standalone as a partial
+		strip.InlineStandalone = true
+	} else if block, ok := node.Program.Body[len(node.Program.Body)-1].(*ast.NamedBlockStatement); ok && isPrevWhitespace(block.Program.Body) {
+		// {{/block}}{{/parent}} closing tags are standalone
+		strip.CloseStandalone = true
+	}
+
+	return strip
+}
+
+func (v *whitespaceVisitor) VisitNamedBlock(node *ast.NamedBlockStatement) interface{} {
+	strip := v.visitProgramBlock(node.Program, node.OpenStrip, node.CloseStrip)
+
+	if len(node.Program.Body) == 0 {
+		// {{$block}}{{/block}} is standalone as a partial
+		strip.InlineStandalone = true
+	}
+
+	return strip
+}
+
+// visitProgramBlock handles whitespaces of a block that has a program but no inverse
+func (v *whitespaceVisitor) visitProgramBlock(program *ast.Program, openStrip *ast.Strip, closeStrip *ast.Strip) *ast.Strip {
+	program.Accept(v)
+
+	strip := &ast.Strip{
+		Open:  (openStrip != nil) && openStrip.Open,
+		Close: (closeStrip != nil) && closeStrip.Close,
+
+		OpenStandalone:  isNextWhitespace(program.Body),
+		CloseStandalone: isPrevWhitespace(program.Body),
+	}
+
+	if (openStrip != nil) && openStrip.Close {
+		omitRightFirst(program.Body, true)
+	}
+
+	if (closeStrip != nil) && closeStrip.Open {
+		omitLeftLast(program.Body, true)
+	}
+
+	return strip
+}
+
 func (v *whitespaceVisitor) VisitMustache(mustache *ast.MustacheStatement) interface{} {
 	return mustache.Strip
 }
 
+func (v *whitespaceVisitor) VisitDecorator(node *ast.Decorator) interface{} {
+	return node.Strip
+}
+
 func _inlineStandalone(strip *ast.Strip) interface{} {
 	return &ast.Strip{
 		Open:             strip.Open,
@@ -3,15 +3,34 @@ package raymond
 import (
 	"fmt"
 	"sync"
+
+	"github.com/aymerick/raymond/parser"
 )
 
 // partial represents a partial template
 type partial struct {
 	name   string
 	source string
-	tpl    *Template
+
+	// registered parsed template, nil if partial is registered as source
+	tpl *Template
+
+	// templates parsed from source, by parse options of calling templates
+	mutex     sync.Mutex
+	templates map[partialOptions]*Template
+}
+
+// partialOptions represents the parse options of a template that are inherited by the partials it calls
+type partialOptions struct {
+	parser parser.Options
+
+	// known helpers names in knownHelpersOnly mode, separated by commas
+	knownHelpers string
 }
 
+// name of the partial that renders current partial block content
+const partialBlockName = "@partial-block"
+
 // partials stores all global partials
 var partials map[string]*partial
 
@@ -86,16 +105,36 @@ func findPartial(name string) *partial {
 	return partials[name]
 }
 
-// template returns parsed partial template
-func (p *partial) template() (*Template, error) {
-	if p.tpl == nil {
-		var err error
+// template returns partial template, parsed with the options of given calling template
+//
+// Contextual escaping is not enabled in returned template: the escaping contexts of a partial depend on where it is called.
+func (p *partial) template(caller *Template) (*Template, error) {
+	if p.tpl != nil {
+		return p.tpl, nil
+	}
+
+	key := caller.partialOptions
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if result := p.templates[key]; result != nil {
+		return result, nil
+	}
+
+	options := caller.options
+	options.ContextualEscaping = false
+
+	result, err := ParseWithOptions(p.source, options)
+	if err != nil {
+		return nil, err
+	}
 
-		p.tpl, err = Parse(p.source)
-		if err != nil {
-			return nil, err
-		}
+	if p.templates == nil {
+		p.templates = make(map[partialOptions]*Template)
 	}
 
-	return p.tpl, nil
+	p.templates[key] = result
+
+	return result, nil
 }
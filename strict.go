@@ -0,0 +1,60 @@
+package raymond
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StrictMode defines how evaluation handles fields that can't be resolved.
+type StrictMode int
+
+const (
+	// StrictOff renders missing fields as empty strings. This is the default mode.
+	StrictOff StrictMode = iota
+
+	// StrictOn aborts evaluation with a *MissingFieldError as soon as a field can't be resolved, like the handlebars.js `strict` option.
+	//
+	// Helper parameters are allowed to be missing, so that `{{#if foo}}` still works, but not the objects traversed by their path.
+	StrictOn
+
+	// StrictAssumeObjects aborts evaluation with a *MissingFieldError only when an object traversed by a path can't be resolved, like the handlebars.js `assumeObjects` option.
+	//
+	// For example `{{foo.bar}}` fails if `foo` is missing, but renders an empty string if `foo` is present and `bar` is missing.
+	StrictAssumeObjects
+
+	// StrictWarn detects missing fields like StrictOn, but renders them as empty strings. Once evaluation is over, they are all returned as Warnings.
+	StrictWarn
+)
+
+// MissingFieldError is the error returned when a field can't be resolved in strict mode.
+type MissingFieldError struct {
+	Path string // path expression, as written in template
+	Line int    // line number in template
+	Pos  int    // byte position in template
+}
+
+// Error implements the error interface.
+func (err *MissingFieldError) Error() string {
+	return fmt.Sprintf("Missing field %q on line %d", err.Path, err.Line)
+}
+
+// Warnings is the error returned when fields could not be resolved in StrictWarn mode.
+//
+// When that error is returned, template has been entirely rendered anyway.
+type Warnings []*MissingFieldError
+
+// Error implements the error interface.
+func (w Warnings) Error() string {
+	msgs := make([]string, len(w))
+	for i, err := range w {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// isWarnings returns true if given error only reports warnings
+func isWarnings(err error) bool {
+	_, ok := err.(Warnings)
+	return ok
+}
@@ -0,0 +1,113 @@
+package raymond
+
+import "testing"
+
+type strictTest struct {
+	name   string
+	mode   StrictMode
+	input  string
+	data   interface{}
+	output string
+	err    string // expected missing field path, if any
+}
+
+var strictTests = []strictTest{
+	{"off - missing field", StrictOff, "{{hello}}", nil, "", ""},
+	{"off - missing child", StrictOff, "{{hello.bar}}", map[string]interface{}{}, "", ""},
+
+	{"strict - present field", StrictOn, "{{hello}}", map[string]string{"hello": "world"}, "world", ""},
+	{"strict - explicit nil", StrictOn, "{{hello}}", map[string]interface{}{"hello": nil}, "", ""},
+	{"strict - missing field", StrictOn, "{{hello}}", map[string]string{}, "", "hello"},
+	{"strict - missing child", StrictOn, "{{hello.bar}}", map[string]interface{}{"hello": map[string]string{}}, "", "hello.bar"},
+	{"strict - missing data", StrictOn, "{{@hello}}", nil, "", "@hello"},
+	{"strict - missing field in parent", StrictOn, "{{#foo}}{{../hello}}{{/foo}}", map[string]interface{}{"foo": true}, "", "../hello"},
+	{"strict - missing struct field", StrictOn, "{{Missing}}", struct{ Present string }{"foo"}, "", "Missing"},
+	{"strict - ambiguous block", StrictOn, "{{#hello}}foo{{/hello}}", nil, "", "hello"},
+	{"strict - unknown helper is handled by helperMissing", StrictOn, "{{hello foo=bar}}", map[string]string{"bar": "baz"}, "", ""},
+	{"strict - missing helper parameter", StrictOn, "{{#if hello}}yes{{else}}no{{/if}}", nil, "no", ""},
+	{"strict - missing helper hash value", StrictOn, "{{#each list key=hello}}{{.}}{{/each}}", map[string][]int{"list": {1, 2}}, "12", ""},
+	{"strict - missing object in helper parameter", StrictOn, "{{#if hello.bar}}yes{{/if}}", nil, "", "hello.bar"},
+
+	{"assumeObjects - missing field", StrictAssumeObjects, "{{hello}}", nil, "", ""},
+	{"assumeObjects - missing child", StrictAssumeObjects, "{{hello.bar}}", map[string]interface{}{"hello": map[string]string{}}, "", ""},
+	{"assumeObjects - missing object", StrictAssumeObjects, "{{hello.bar}}", map[string]interface{}{}, "", "hello.bar"},
+	{"assumeObjects - missing object in helper parameter", StrictAssumeObjects, "{{#if hello.bar.baz}}yes{{/if}}", map[string]interface{}{"hello": map[string]string{}}, "", "hello.bar.baz"},
+}
+
+func TestStrict(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range strictTests {
+		tpl := MustParse(test.input)
+		tpl.SetStrict(test.mode)
+
+		output, err := tpl.Exec(test.data)
+
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("Test '%s' failed - Unexpected error: %s", test.name, err)
+			} else if output != test.output {
+				t.Errorf("Test '%s' failed - Expected %q but got %q", test.name, test.output, output)
+			}
+
+			continue
+		}
+
+		missingErr, ok := err.(*MissingFieldError)
+		if !ok {
+			t.Errorf("Test '%s' failed - Expected a MissingFieldError but got: %v", test.name, err)
+		} else if missingErr.Path != test.err {
+			t.Errorf("Test '%s' failed - Expected missing field %q but got %q", test.name, test.err, missingErr.Path)
+		}
+	}
+}
+
+func TestStrictErrorLocation(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("Hello\n  {{user.name}}!")
+	tpl.SetStrict(StrictOn)
+
+	_, err := tpl.Exec(map[string]interface{}{"user": map[string]string{}})
+	if err == nil {
+		t.Fatalf("Missing field error expected")
+	}
+
+	if expected := `Missing field "user.name" on line 2`; err.Error() != expected {
+		t.Errorf("Expected error %q but got %q", expected, err)
+	}
+}
+
+func TestStrictWarn(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("{{title}} {{#each items}}{{name}}:{{price}} {{/each}}{{#if missing}}no{{/if}}")
+	tpl.SetStrict(StrictWarn)
+
+	ctx := map[string]interface{}{
+		"items": []map[string]string{{"name": "foo", "price": "1"}, {"name": "bar"}},
+	}
+
+	output, err := tpl.Exec(ctx)
+	if output != " foo:1 bar: " {
+		t.Errorf("Template must be rendered anyway, got: %q", output)
+	}
+
+	warnings, ok := err.(Warnings)
+	if !ok {
+		t.Fatalf("Warnings expected, got: %v", err)
+	}
+
+	if len(warnings) != 2 || warnings[0].Path != "title" || warnings[1].Path != "price" {
+		t.Errorf("Unexpected warnings: %s", warnings)
+	}
+
+	if output := tpl.MustExec(ctx); output != " foo:1 bar: " {
+		t.Errorf("MustExec() must not panic on warnings, got: %q", output)
+	}
+
+	// setting is cloned
+	if output, err := tpl.Clone().Exec(ctx); err == nil || output != " foo:1 bar: " {
+		t.Errorf("Strict mode must be cloned")
+	}
+}
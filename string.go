@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 // SafeString represents a string that must not be escaped.
@@ -21,6 +22,13 @@ func isSafeString(value interface{}) bool {
 
 // Str returns string representation of any basic type value.
 func Str(value interface{}) string {
+	switch str := value.(type) {
+	case string:
+		return str
+	case SafeString:
+		return string(str)
+	}
+
 	return strValue(reflect.ValueOf(value))
 }
 
@@ -33,20 +41,28 @@ func strValue(value reflect.Value) string {
 		panic(fmt.Errorf("Can't print value: %q", value))
 	}
 
+	if str, ok := ival.(string); ok {
+		return str
+	}
+
 	val := reflect.ValueOf(ival)
 
 	switch val.Kind() {
 	case reflect.Array, reflect.Slice:
+		var buf strings.Builder
 		for i := 0; i < val.Len(); i++ {
-			result += strValue(val.Index(i))
+			buf.WriteString(strValue(val.Index(i)))
 		}
+		result = buf.String()
 	case reflect.Bool:
 		result = "false"
 		if val.Bool() {
 			result = "true"
 		}
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		result = fmt.Sprintf("%d", ival)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result = strconv.FormatInt(val.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		result = strconv.FormatUint(val.Uint(), 10)
 	case reflect.Float32, reflect.Float64:
 		result = strconv.FormatFloat(val.Float(), 'f', -1, 64)
 	case reflect.Invalid:
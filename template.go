@@ -1,37 +1,117 @@
 package raymond
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/aymerick/raymond/ast"
 	"github.com/aymerick/raymond/parser"
 )
 
+// ParseOptions represents template parsing options.
+//
+// Partials registered as source are parsed with the options of the template being evaluated, so that they share its
+// delimiters, whitespace control and known helpers. Partials registered as parsed templates keep their own options.
+type ParseOptions struct {
+	// KnownHelpers lists helpers that are known to exist at execution time, in knownHelpersOnly mode.
+	// Builtin helpers are known by default, and can be disabled by setting them to false.
+	KnownHelpers map[string]bool
+
+	// KnownHelpersOnly enables the knownHelpersOnly mode: parsing fails if an unknown helper is called, and
+	// helpers are never looked up for other expressions.
+	KnownHelpersOnly bool
+
+	// ContextualEscaping enables contextual escaping: the HTML context of each mustache is computed at parse time, and its
+	// value is escaped accordingly, in HTML text, HTML attribute, URL, JavaScript or CSS.
+	ContextualEscaping bool
+
+	// IgnoreStandalone disables the removal of whitespaces and line breaks around standalone statements.
+	IgnoreStandalone bool
+
+	// PreventIndent disables the indentation of every line of standalone partials output. The partial indentation
+	// is output once instead.
+	PreventIndent bool
+
+	// OpenDelimiter and CloseDelimiter replace the default "{{" and "}}" mustaches delimiters, in every tags.
+	// Delimiters can't contain whitespaces nor equal sign.
+	OpenDelimiter  string
+	CloseDelimiter string
+}
+
+// ExecOptions represents the options of a template evaluation, accepted by Template.ExecWithOptions().
+type ExecOptions struct {
+	// Escaper escapes the result of mustache expressions for that evaluation, instead of the template escaper.
+	// Evaluation fails if it is set on a template parsed with the ContextualEscaping option.
+	Escaper Escaper
+}
+
+// errContextualEscaper is returned when a custom escaper is used with contextual escaping
+var errContextualEscaper = errors.New("A custom escaper can't be used with contextual escaping")
+
 // Template represents a handlebars template.
 type Template struct {
-	source   string
-	program  *ast.Program
-	helpers  map[string]reflect.Value
-	partials map[string]*partial
-	mutex    sync.RWMutex // protects helpers and partials
+	source       string
+	options      ParseOptions
+	program      *ast.Program
+	helpers      map[string]reflect.Value
+	partials     map[string]*partial
+	decorators   map[string]DecoratorFunc
+	strict       StrictMode
+	compat       bool
+	trackIds     bool
+	stringParams bool
+	lambdas      bool
+	escaper      Escaper
+	logger       Logger
+	mutex        sync.RWMutex // protects helpers, partials, decorators, settings and partial escaping contexts
+
+	// known helpers in knownHelpersOnly mode, nil otherwise
+	knownHelpers map[string]bool
+
+	// parse options inherited by partials
+	partialOptions partialOptions
+
+	// mustaches and partials escaping contexts in contextual escaping mode, nil otherwise
+	escapeContexts map[ast.Node]escapeContext
+
+	// escaping contexts of the template used as a partial, by the context it is called in
+	partialContexts map[escapeContext]map[ast.Node]escapeContext
+
+	// compiled programs
+	compiled compiledPrograms
+
+	// incremented each time a helper is registered, to invalidate memoized helper lookups
+	helpersGen uint64
 }
 
 // newTemplate instanciate a new template without parsing it
 func newTemplate(source string) *Template {
 	return &Template{
-		source:   source,
-		helpers:  make(map[string]reflect.Value),
-		partials: make(map[string]*partial),
+		source:     source,
+		helpers:    make(map[string]reflect.Value),
+		partials:   make(map[string]*partial),
+		decorators: make(map[string]DecoratorFunc),
 	}
 }
 
 // Parse instanciates a template by parsing given source.
 func Parse(source string) (*Template, error) {
+	return ParseWithOptions(source, ParseOptions{})
+}
+
+// ParseWithOptions instanciates a template by parsing given source with given options.
+func ParseWithOptions(source string, options ParseOptions) (*Template, error) {
 	tpl := newTemplate(source)
+	tpl.setOptions(options)
 
 	// parse template
 	if err := tpl.parse(); err != nil {
@@ -60,31 +140,114 @@ func ParseFile(filePath string) (*Template, error) {
 	return Parse(string(b))
 }
 
+// setOptions sets parsing options
+func (tpl *Template) setOptions(options ParseOptions) {
+	tpl.options = options
+	tpl.knownHelpers = knownHelpersSet(options)
+
+	tpl.partialOptions = partialOptions{parser: tpl.parserOptions()}
+
+	if tpl.knownHelpers != nil {
+		names := make([]string, 0, len(tpl.knownHelpers))
+		for name := range tpl.knownHelpers {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		tpl.partialOptions.knownHelpers = strings.Join(names, ",")
+	}
+}
+
+// parserOptions returns the options provided to parser
+func (tpl *Template) parserOptions() parser.Options {
+	return parser.Options{
+		IgnoreStandalone: tpl.options.IgnoreStandalone,
+		PreventIndent:    tpl.options.PreventIndent,
+		OpenDelimiter:    tpl.options.OpenDelimiter,
+		CloseDelimiter:   tpl.options.CloseDelimiter,
+	}
+}
+
 // parse parses the template
 //
 // It can be called several times, the parsing will be done only once.
 func (tpl *Template) parse() error {
 	if tpl.program == nil {
-		var err error
-
-		tpl.program, err = parser.Parse(tpl.source)
+		program, err := parser.ParseWithOptions(tpl.source, tpl.parserOptions())
 		if err != nil {
 			return err
 		}
+
+		if tpl.knownHelpers != nil {
+			if err := checkKnownHelpers(program, tpl.knownHelpers); err != nil {
+				return err
+			}
+		}
+
+		if tpl.options.ContextualEscaping {
+			contexts, err := computeEscapeContexts(program)
+			if err != nil {
+				return err
+			}
+
+			tpl.escapeContexts = contexts
+		}
+
+		tpl.compiled = compile(program, tpl.knownHelpers)
+		tpl.program = program
 	}
 
 	return nil
 }
 
+// partialEscapeContexts returns the escaping contexts of template, used as a partial called in given context
+func (tpl *Template) partialEscapeContexts(start escapeContext) (map[ast.Node]escapeContext, error) {
+	tpl.mutex.RLock()
+	result := tpl.partialContexts[start]
+	tpl.mutex.RUnlock()
+
+	if result != nil {
+		return result, nil
+	}
+
+	result, err := computePartialEscapeContexts(tpl.program, start)
+	if err != nil {
+		return nil, err
+	}
+
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	if tpl.partialContexts == nil {
+		tpl.partialContexts = make(map[escapeContext]map[ast.Node]escapeContext)
+	}
+
+	tpl.partialContexts[start] = result
+
+	return result, nil
+}
+
 // Clone returns a copy of that template.
 func (tpl *Template) Clone() *Template {
 	result := newTemplate(tpl.source)
 
+	result.setOptions(tpl.options)
 	result.program = tpl.program
+	result.escapeContexts = tpl.escapeContexts
+	result.compiled = tpl.compiled
 
 	tpl.mutex.RLock()
 	defer tpl.mutex.RUnlock()
 
+	result.strict = tpl.strict
+	result.compat = tpl.compat
+	result.trackIds = tpl.trackIds
+	result.stringParams = tpl.stringParams
+	result.lambdas = tpl.lambdas
+	result.escaper = tpl.escaper
+	result.logger = tpl.logger
+
 	for name, helper := range tpl.helpers {
 		result.RegisterHelper(name, helper.Interface())
 	}
@@ -93,9 +256,142 @@ func (tpl *Template) Clone() *Template {
 		result.addPartial(name, partial.source, partial.tpl)
 	}
 
+	for name, decorator := range tpl.decorators {
+		result.RegisterDecorator(name, decorator)
+	}
+
 	return result
 }
 
+// SetStrict sets the way missing fields are handled when evaluating that template. Default is StrictOff.
+func (tpl *Template) SetStrict(mode StrictMode) {
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	tpl.strict = mode
+}
+
+func (tpl *Template) strictMode() StrictMode {
+	tpl.mutex.RLock()
+	defer tpl.mutex.RUnlock()
+
+	return tpl.strict
+}
+
+// SetCompat enables or disables compat mode. Default is disabled.
+//
+// In compat mode, a path that can not be entirely resolved in current context is looked up recursively in parent contexts, like mustache does.
+func (tpl *Template) SetCompat(compat bool) {
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	tpl.compat = compat
+}
+
+func (tpl *Template) compatMode() bool {
+	tpl.mutex.RLock()
+	defer tpl.mutex.RUnlock()
+
+	return tpl.compat
+}
+
+// SetTrackIds enables or disables trackIds mode. Default is disabled.
+//
+// In trackIds mode, helpers can get the paths used to resolve their parameters with Options.ParamID() and Options.HashID(), and
+// the `each` and `with` helpers set the `@contextPath` private variable to the path of current context.
+func (tpl *Template) SetTrackIds(trackIds bool) {
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	tpl.trackIds = trackIds
+}
+
+func (tpl *Template) trackIdsMode() bool {
+	tpl.mutex.RLock()
+	defer tpl.mutex.RUnlock()
+
+	return tpl.trackIds
+}
+
+// SetStringParams enables or disables stringParams mode. Default is disabled.
+//
+// In stringParams mode, helpers parameters and hash values that are paths are not evaluated: helpers get the paths themselves
+// as strings. Helpers get the parameters types with Options.ParamType() and Options.HashType(), and the contexts the paths
+// would have been resolved in with Options.ParamContext() and Options.HashContext().
+func (tpl *Template) SetStringParams(stringParams bool) {
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	tpl.stringParams = stringParams
+}
+
+func (tpl *Template) stringParamsMode() bool {
+	tpl.mutex.RLock()
+	defer tpl.mutex.RUnlock()
+
+	return tpl.stringParams
+}
+
+// SetMustacheLambdas enables or disables mustache lambdas mode. Default is disabled.
+//
+// In mustache lambdas mode, context functions behave as the mustache spec requires:
+//   - a function without argument used in a mustache, ie. {{lambda}}, returns a template that is rendered in current context
+//     with default delimiters, then interpolated;
+//   - a function with a single string argument used as a section, ie. {{#lambda}}...{{/lambda}}, gets the unprocessed
+//     section content and returns a template that is rendered in current context with current delimiters.
+//
+// Helpers, and functions with other signatures, are not affected.
+func (tpl *Template) SetMustacheLambdas(lambdas bool) {
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	tpl.lambdas = lambdas
+}
+
+func (tpl *Template) mustacheLambdasMode() bool {
+	tpl.mutex.RLock()
+	defer tpl.mutex.RUnlock()
+
+	return tpl.lambdas
+}
+
+// SetEscaper sets the function used to escape the result of mustache expressions when evaluating that template. Default is nil, that escapes special HTML characters.
+//
+// Use NoEscape to disable escaping, like the handlebars.js `noEscape` option. Evaluation fails if an escaper is set on a
+// template parsed with the ContextualEscaping option.
+func (tpl *Template) SetEscaper(escaper Escaper) {
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	tpl.escaper = escaper
+}
+
+func (tpl *Template) getEscaper() Escaper {
+	tpl.mutex.RLock()
+	defer tpl.mutex.RUnlock()
+
+	return tpl.escaper
+}
+
+// SetLogger sets the logger used by the `log` helper when evaluating that template. Default is nil, that uses the global logger set with SetLogger().
+func (tpl *Template) SetLogger(logger Logger) {
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	tpl.logger = logger
+}
+
+func (tpl *Template) getLogger() Logger {
+	tpl.mutex.RLock()
+	defer tpl.mutex.RUnlock()
+
+	if tpl.logger == nil {
+		return getLogger()
+	}
+
+	return tpl.logger
+}
+
 func (tpl *Template) findHelper(name string) reflect.Value {
 	tpl.mutex.RLock()
 	defer tpl.mutex.RUnlock()
@@ -116,6 +412,7 @@ func (tpl *Template) RegisterHelper(name string, helper interface{}) {
 	ensureValidHelper(name, val)
 
 	tpl.helpers[name] = val
+	atomic.AddUint64(&tpl.helpersGen, 1)
 }
 
 // RegisterHelpers registers several helpers for that template.
@@ -189,22 +486,91 @@ func (tpl *Template) RegisterPartialTemplate(name string, template *Template) {
 	tpl.addPartial(name, "", template)
 }
 
+func (tpl *Template) findDecorator(name string) DecoratorFunc {
+	tpl.mutex.RLock()
+	defer tpl.mutex.RUnlock()
+
+	return tpl.decorators[name]
+}
+
+// RegisterDecorator registers a decorator for that template.
+func (tpl *Template) RegisterDecorator(name string, decorator DecoratorFunc) {
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	if tpl.decorators[name] != nil {
+		panic(fmt.Sprintf("Decorator %s already registered", name))
+	}
+
+	if decorator == nil {
+		panic(fmt.Sprintf("Decorator must be a function: %s", name))
+	}
+
+	tpl.decorators[name] = decorator
+}
+
 // Exec evaluates template with given context.
 func (tpl *Template) Exec(ctx interface{}) (result string, err error) {
 	return tpl.ExecWith(ctx, nil)
 }
 
-// MustExec evaluates template with given context. It panics on error.
+// MustExec evaluates template with given context. It panics on error, but not on Warnings.
 func (tpl *Template) MustExec(ctx interface{}) string {
 	result, err := tpl.Exec(ctx)
-	if err != nil {
+	if (err != nil) && !isWarnings(err) {
 		panic(err)
 	}
 	return result
 }
 
 // ExecWith evaluates template with given context and private data frame.
+//
+// In StrictWarn mode, the rendered template is returned alongside Warnings.
 func (tpl *Template) ExecWith(ctx interface{}, privData *DataFrame) (result string, err error) {
+	return tpl.execString(context.Background(), ctx, privData, ExecOptions{})
+}
+
+// ExecWithOptions evaluates template with given context and evaluation options.
+func (tpl *Template) ExecWithOptions(ctx interface{}, options ExecOptions) (result string, err error) {
+	return tpl.execString(context.Background(), ctx, nil, options)
+}
+
+// ExecTo evaluates template with given context and writes result to given writer.
+func (tpl *Template) ExecTo(w io.Writer, ctx interface{}) error {
+	return tpl.ExecWithTo(w, ctx, nil)
+}
+
+// ExecWithTo evaluates template with given context and private data frame, and writes result to given writer.
+//
+// Output is written as evaluation proceeds, so on error some output may already have been written. Evaluation is aborted as soon as a write fails, and that write error is returned.
+func (tpl *Template) ExecWithTo(w io.Writer, ctx interface{}, privData *DataFrame) error {
+	return tpl.exec(context.Background(), w, ctx, privData, ExecOptions{})
+}
+
+// ExecContext evaluates template with given execution context, context and private data frame.
+//
+// Evaluation is aborted as soon as execCtx is done, and execCtx.Err() is returned. Helpers can access execCtx with Options.Context().
+func (tpl *Template) ExecContext(execCtx context.Context, ctx interface{}, privData *DataFrame) (result string, err error) {
+	return tpl.execString(execCtx, ctx, privData, ExecOptions{})
+}
+
+// execString evaluates template with given execution context, context, private data frame and options, and returns result as a string
+func (tpl *Template) execString(execCtx context.Context, ctx interface{}, privData *DataFrame, options ExecOptions) (result string, err error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err = tpl.exec(execCtx, buf, ctx, privData, options); (err != nil) && !isWarnings(err) {
+		return
+	}
+
+	result = buf.String()
+
+	// named return values
+	return
+}
+
+// exec evaluates template with given execution context, context, private data frame and options, and writes result to given writer
+func (tpl *Template) exec(execCtx context.Context, w io.Writer, ctx interface{}, privData *DataFrame, options ExecOptions) (err error) {
 	defer errRecover(&err)
 
 	// parses template if necessary
@@ -213,11 +579,25 @@ func (tpl *Template) ExecWith(ctx interface{}, privData *DataFrame) (result stri
 		return
 	}
 
+	escaper := tpl.getEscaper()
+	if options.Escaper != nil {
+		escaper = options.Escaper
+	}
+
+	if (escaper != nil) && (tpl.escapeContexts != nil) {
+		return errContextualEscaper
+	}
+
 	// setup visitor
-	v := newEvalVisitor(tpl, ctx, privData)
+	v := newEvalVisitor(execCtx, tpl, ctx, privData, escaper, w)
+	defer v.release()
 
 	// visit AST
-	result, _ = tpl.program.Accept(v).(string)
+	tpl.program.Accept(v)
+
+	if len(v.warnings) > 0 {
+		err = v.warnings
+	}
 
 	// named return values
 	return
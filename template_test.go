@@ -1,7 +1,11 @@
 package raymond
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"testing"
 )
 
@@ -46,6 +50,55 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseWithDelimiters(t *testing.T) {
+	t.Parallel()
+
+	source := `<div title="{{title}}">[[! comment ]]  [[~#if show~]]
+  [[{raw}]] [[title]]
+[[~else~]]
+  [[{{verbatim}}]][[foo]][[{{/verbatim}}]]
+[[~/if~]]</div>`
+
+	tpl, err := ParseWithOptions(source, ParseOptions{OpenDelimiter: "[[", CloseDelimiter: "]]"})
+	if err != nil {
+		t.Fatalf("Failed to parse template with delimiters: %s", err)
+	}
+
+	tpl.RegisterHelper("verbatim", func(options *Options) string {
+		return options.Fn()
+	})
+
+	ctx := map[string]interface{}{"show": true, "title": "<b>", "raw": "<i>"}
+	if output := tpl.MustExec(ctx); output != `<div title="{{title}}"><i> &lt;b&gt;</div>` {
+		t.Errorf("Unexpected output with delimiters: %q", output)
+	}
+
+	ctx["show"] = false
+	if output := tpl.MustExec(ctx); output != `<div title="{{title}}">[[foo]]</div>` {
+		t.Errorf("Unexpected output with delimiters: %q", output)
+	}
+
+	// partials inherit delimiters
+	tpl, err = ParseWithOptions(`[[> p]]`, ParseOptions{OpenDelimiter: "[[", CloseDelimiter: "]]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl.RegisterPartial("p", `[[name]] {{name}}`)
+
+	if output := tpl.MustExec(map[string]string{"name": "N"}); output != `N {{name}}` {
+		t.Errorf("Unexpected partial output with delimiters: %q", output)
+	}
+}
+
+func TestParseWithInvalidDelimiters(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseWithOptions("foo", ParseOptions{OpenDelimiter: "<%=", CloseDelimiter: "%>"}); err == nil {
+		t.Errorf("Expected an error with invalid delimiters")
+	}
+}
+
 func TestClone(t *testing.T) {
 	t.Parallel()
 
@@ -76,6 +129,128 @@ func TestClone(t *testing.T) {
 	}
 }
 
+func TestExecTo(t *testing.T) {
+	t.Parallel()
+
+	source := `{{title}} - {{#each items}}{{#bold}}{{.}}{{/bold}} {{/each}}{{{raw}}}`
+
+	ctx := map[string]interface{}{
+		"title": "<list>",
+		"items": []string{"foo", "bar"},
+		"raw":   "<br>",
+	}
+
+	tpl := MustParse(source)
+	tpl.RegisterHelper("bold", func(options *Options) SafeString {
+		return SafeString("<b>" + options.Fn() + "</b>")
+	})
+
+	buf := new(bytes.Buffer)
+	if err := tpl.ExecTo(buf, ctx); err != nil {
+		t.Fatalf("Failed to execute template: %s", err)
+	}
+
+	expected := tpl.MustExec(ctx)
+	if buf.String() != expected {
+		t.Errorf("Streamed output differs, expected %q but got %q", expected, buf.String())
+	}
+}
+
+// failingWriter fails on the write that would exceed given limit
+type failingWriter struct {
+	limit int
+	buf   bytes.Buffer
+}
+
+var errFailingWriter = errors.New("write failed")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, errFailingWriter
+	}
+
+	return w.buf.Write(p)
+}
+
+func TestExecToWriteError(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{#items}}{{.}}{{/items}}`)
+
+	w := &failingWriter{limit: 3}
+	err := tpl.ExecTo(w, map[string]interface{}{"items": []string{"foo", "bar", "baz"}})
+	if err != errFailingWriter {
+		t.Errorf("Write error should abort evaluation and be returned as is, got: %v", err)
+	}
+
+	if w.buf.String() != "foo" {
+		t.Errorf("Output should have been written before write error, got: %q", w.buf.String())
+	}
+}
+
+type testCtxKey string
+
+func TestExecContext(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`Hello {{user}}!`)
+	tpl.RegisterHelper("user", func(options *Options) string {
+		return Str(options.Context().Value(testCtxKey("user")))
+	})
+
+	execCtx := context.WithValue(context.Background(), testCtxKey("user"), "Marcel")
+
+	output, err := tpl.ExecContext(execCtx, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute template: %s", err)
+	}
+
+	if output != "Hello Marcel!" {
+		t.Errorf("Helper failed to access execution context, got: %q", output)
+	}
+
+	tpl = MustParse(`{{hasContext}}`)
+	tpl.RegisterHelper("hasContext", func(options *Options) bool {
+		return options.Context() != nil
+	})
+
+	if output := tpl.MustExec(nil); output != "true" {
+		t.Errorf("A default execution context must be provided to helpers")
+	}
+}
+
+func TestExecContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	execCtx, cancel := context.WithCancel(context.Background())
+
+	tpl := MustParse(`{{#each items}}{{stop .}}{{/each}}`)
+	tpl.RegisterHelper("stop", func(item string) string {
+		if item == "bar" {
+			cancel()
+		}
+		return item
+	})
+
+	iterations := []string{"foo", "bar", "baz"}
+
+	output, err := tpl.ExecContext(execCtx, map[string]interface{}{"items": iterations}, nil)
+	if err != context.Canceled {
+		t.Errorf("Evaluation must be aborted with context error, got: %v", err)
+	}
+
+	if output != "" {
+		t.Errorf("No output expected when evaluation is aborted, got: %q", output)
+	}
+
+	tpl = MustParse(`{{> foo}}`)
+	tpl.RegisterPartial("foo", "partial")
+
+	if _, err := tpl.ExecContext(execCtx, nil, nil); err != context.Canceled {
+		t.Errorf("Partial evaluation must be aborted with context error, got: %v", err)
+	}
+}
+
 func ExampleTemplate_Exec() {
 	source := "<h1>{{title}}</h1><p>{{body.content}}</p>"
 
@@ -140,6 +315,25 @@ func ExampleTemplate_ExecWith() {
 	// Output: <h1>foo</h1><p>bar and unicorns</p>
 }
 
+func ExampleTemplate_ExecTo() {
+	source := "<h1>{{title}}</h1><p>{{body.content}}</p>"
+
+	ctx := map[string]interface{}{
+		"title": "foo",
+		"body":  map[string]string{"content": "bar"},
+	}
+
+	// parse template
+	tpl := MustParse(source)
+
+	// evaluate template with context, and write result to standard output
+	if err := tpl.ExecTo(os.Stdout, ctx); err != nil {
+		panic(err)
+	}
+
+	// Output: <h1>foo</h1><p>bar</p>
+}
+
 func ExampleTemplate_PrintAST() {
 	source := "<h1>{{title}}</h1><p>{{#body}}{{content}} and {{@baz.bat}}{{/body}}</p>"
 
@@ -164,3 +358,28 @@ func ExampleTemplate_PrintAST() {
 	//   CONTENT[ '</p>' ]
 	//
 }
+
+func TestPartialParseOptions(t *testing.T) {
+	t.Parallel()
+
+	RegisterPartial("parseOptionsPartial", "{{#if a}}\nX\n{{/if}}\n")
+	defer RemovePartial("parseOptionsPartial")
+
+	ctx := map[string]bool{"a": true}
+
+	// the same partial is parsed once for each calling template options
+	for i := 0; i < 2; i++ {
+		if output := MustParse(`{{> parseOptionsPartial}}`).MustExec(ctx); output != "X\n" {
+			t.Errorf("Unexpected partial output with default options: %q", output)
+		}
+
+		tpl, err := ParseWithOptions(`{{> parseOptionsPartial}}`, ParseOptions{IgnoreStandalone: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if output := tpl.MustExec(ctx); output != "\nX\n\n" {
+			t.Errorf("Unexpected partial output with IgnoreStandalone option: %q", output)
+		}
+	}
+}